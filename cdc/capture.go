@@ -15,15 +15,23 @@ package cdc
 
 import (
 	"context"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
+	pd "github.com/pingcap/pd/client"
+	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/puller"
 	"github.com/pingcap/ticdc/cdc/roles"
+	"github.com/pingcap/ticdc/pkg/security"
+	tidbkv "github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/store/tikv"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/clientv3/concurrency"
 	"go.uber.org/zap"
@@ -40,10 +48,22 @@ const (
 // Capture represents a Capture server, it monitors the changefeed information in etcd and schedules Task on it.
 type Capture struct {
 	pdEndpoints  []string
+	credential   *security.Credential
 	etcdClient   kv.CDCEtcdClient
 	ownerManager roles.Manager
 	ownerWorker  *ownerImpl
 
+	// pullerRegistry shares the table pullers among the processors on this capture
+	pullerRegistry *puller.Registry
+	// kvClient is shared by the table pullers, so the subscriptions to a store share the streams
+	kvClient *kv.CDCClient
+	// kvStore is used to resolve the locks of the stalled regions, it's nil if the resolve-lock action is disabled
+	kvStore tidbkv.Storage
+	// mounterPool decodes the rows of the table pullers, it's nil if the rows are decoded by the mounters of the tables
+	mounterPool *entry.MounterWorkerPool
+	// sharedSchemas is the schema store shared by the processors on this capture
+	sharedSchemas *sharedSchemaStore
+
 	processors map[string]*processor
 	procLock   sync.Mutex
 
@@ -53,10 +73,20 @@ type Capture struct {
 	session *concurrency.Session
 }
 
-// NewCapture returns a new Capture instance
-func NewCapture(pdEndpoints []string) (c *Capture, err error) {
+// NewCapture returns a new Capture instance, kvCfg is the config of the kv client used by the table pullers,
+// mounterWorkerNum is the number of the workers decoding the rows of the tables, advertiseAddr is the address
+// of the status server recorded in the capture info
+func NewCapture(
+	pdEndpoints []string, credential *security.Credential, labels map[string]string, kvCfg *kv.ClientConfig, mounterWorkerNum int,
+	advertiseAddr string,
+) (c *Capture, err error) {
+	tlsConfig, err := credential.ToTLSConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	etcdCli, err := clientv3.New(clientv3.Config{
 		Endpoints:   pdEndpoints,
+		TLS:         tlsConfig,
 		DialTimeout: 5 * time.Second,
 		DialOptions: []grpc.DialOption{
 			grpc.WithConnectParams(grpc.ConnectParams{
@@ -79,28 +109,69 @@ func NewCapture(pdEndpoints []string) (c *Capture, err error) {
 		return nil, errors.Annotate(err, "create capture session")
 	}
 	cli := kv.NewCDCEtcdClient(etcdCli)
+	pdCli, err := pd.NewClient(pdEndpoints, credential.PDSecurityOption())
+	if err != nil {
+		return nil, errors.Annotatef(err, "create pd client failed, addr: %v", pdEndpoints)
+	}
+	var kvStore tidbkv.Storage
+	if kvCfg != nil && kvCfg.RegionStallAction == kv.RegionStallActionResolveLock && kvCfg.LockResolver == nil {
+		kvStore, err = kv.CreateTiStore(strings.Join(pdEndpoints, ","), credential)
+		if err != nil {
+			return nil, errors.Annotate(err, "create tikv store failed")
+		}
+		tikvStore, ok := kvStore.(tikv.Storage)
+		if !ok {
+			return nil, errors.Errorf("unexpected tikv store type %T", kvStore)
+		}
+		cfg := *kvCfg
+		cfg.LockResolver = kv.NewLockResolver(tikvStore, cfg.ResolveLockThreshold, cfg.ResolveLockRate)
+		kvCfg = &cfg
+	}
+	kvClient, err := kv.NewCDCClient(pdCli, credential, kvCfg)
+	if err != nil {
+		return nil, errors.Annotate(err, "create cdc client failed")
+	}
 	id := uuid.New().String()
+	captureLabels := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		captureLabels[k] = v
+	}
+	if _, ok := captureLabels[model.CaptureLabelHost]; !ok {
+		if hostname, err := os.Hostname(); err == nil {
+			captureLabels[model.CaptureLabelHost] = hostname
+		}
+	}
 	info := &model.CaptureInfo{
-		ID: id,
+		ID:            id,
+		Labels:        captureLabels,
+		AdvertiseAddr: advertiseAddr,
 	}
 
-	log.Info("creating capture", zap.String("capture-id", id))
+	log.Info("creating capture", zap.String("capture-id", id), zap.Reflect("labels", captureLabels))
 
 	manager := roles.NewOwnerManager(cli, id, kv.CaptureOwnerKey)
 
-	worker, err := NewOwner(pdEndpoints, cli, manager)
+	worker, err := NewOwner(pdEndpoints, credential, cli, manager)
 	if err != nil {
 		return nil, errors.Annotate(err, "new owner failed")
 	}
 
 	c = &Capture{
-		processors:   make(map[string]*processor),
-		pdEndpoints:  pdEndpoints,
-		etcdClient:   cli,
-		session:      sess,
-		ownerManager: manager,
-		ownerWorker:  worker,
-		info:         info,
+		processors:     make(map[string]*processor),
+		pdEndpoints:    pdEndpoints,
+		credential:     credential,
+		etcdClient:     cli,
+		session:        sess,
+		ownerManager:   manager,
+		ownerWorker:    worker,
+		pullerRegistry: puller.NewRegistry(pdCli, credential, puller.NewBlurResourceLimmter(defaultMemBufferCapacity), kvClient),
+		kvClient:       kvClient,
+		kvStore:        kvStore,
+		info:           info,
+		sharedSchemas:  newSharedSchemaStore(pdCli, pdEndpoints, credential, id, kvClient),
+	}
+	if mounterWorkerNum > 0 {
+		c.mounterPool = entry.NewMounterWorkerPool(mounterWorkerNum)
 	}
 
 	return
@@ -124,6 +195,11 @@ func (c *Capture) Start(ctx context.Context) (err error) {
 	errg.Go(func() error {
 		return c.ownerWorker.Run(cctx, ownerRunInterval)
 	})
+	if c.mounterPool != nil {
+		errg.Go(func() error {
+			return c.mounterPool.Run(cctx)
+		})
+	}
 
 	taskWatcher := NewTaskWatcher(c, &TaskWatcherConfig{
 		Prefix:      kv.TaskStatusKeyPrefix + "/" + c.info.ID,
@@ -147,7 +223,7 @@ func (c *Capture) Start(ctx context.Context) (err error) {
 			log.Info("run processor", zap.String("captureid", c.info.ID),
 				zap.String("changefeedid", task.ChangeFeedID))
 			if _, ok := c.processors[task.ChangeFeedID]; !ok {
-				p, err := runProcessor(ctx, c.pdEndpoints, *cf, task.ChangeFeedID,
+				p, err := runProcessor(ctx, c.pdEndpoints, c.credential, c.pullerRegistry, c.mounterPool, c.sharedSchemas, *cf, task.ChangeFeedID,
 					c.info.ID, task.CheckpointTS)
 				if err != nil {
 					log.Error("run processor failed",
@@ -183,6 +259,19 @@ func (c *Capture) Cleanup() {
 
 // Close closes the capture by unregistering it from etcd
 func (c *Capture) Close(ctx context.Context) error {
+	if c.sharedSchemas != nil {
+		c.sharedSchemas.close()
+	}
+	if c.kvClient != nil {
+		if err := c.kvClient.Close(); err != nil {
+			log.Warn("close cdc client failed", zap.Error(err))
+		}
+	}
+	if c.kvStore != nil {
+		if err := c.kvStore.Close(); err != nil {
+			log.Warn("close tikv store failed", zap.Error(err))
+		}
+	}
 	return errors.Trace(c.etcdClient.DeleteCaptureInfo(ctx, c.info.ID))
 }
 
@@ -0,0 +1,175 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	tidbkv "github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/kvcache"
+	"go.uber.org/zap"
+)
+
+// defaultKeylessRowsCacheSize is the max number of the rows cached by the mounter of a table
+const defaultKeylessRowsCacheSize = 10240
+
+// OldValueReader reads the value of a key before ts, which is the old value of the key changed at ts.
+// A nil value is returned if the key doesn't exist before ts.
+type OldValueReader interface {
+	ReadOldValue(ctx context.Context, key []byte, ts uint64) ([]byte, error)
+}
+
+type storeOldValueReader struct {
+	store tidbkv.Storage
+}
+
+// NewOldValueReader creates an OldValueReader reading the snapshots of the store. The old values are kept
+// by TiKV because the GC safepoint of the changefeed is not greater than the ts of the unreplicated changes.
+func NewOldValueReader(store tidbkv.Storage) OldValueReader {
+	return &storeOldValueReader{store: store}
+}
+
+// ReadOldValue implements the OldValueReader interface
+func (r *storeOldValueReader) ReadOldValue(ctx context.Context, key []byte, ts uint64) ([]byte, error) {
+	snapshot, err := r.store.GetSnapshot(tidbkv.NewVersion(ts - 1))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	value, err := snapshot.Get(ctx, key)
+	if tidbkv.IsErrNotFound(err) {
+		return nil, nil
+	}
+	return value, errors.Trace(err)
+}
+
+type keylessRowKey struct {
+	tableID  int64
+	recordID int64
+}
+
+// Hash implements the kvcache.Key interface
+func (k keylessRowKey) Hash() []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b, uint64(k.tableID))
+	binary.BigEndian.PutUint64(b[8:], uint64(k.recordID))
+	return b
+}
+
+type keylessRowColumns map[string]*model.Column
+
+// keylessRows finds the old columns of the rows of the tables without a primary key or unique index,
+// which are replicated by all the columns. The old values are not in the row changed events of TiKV,
+// so they're read from the snapshot of TiKV before the row is changed. The columns of the recently
+// changed rows are cached to save the reads, the least recently changed ones are evicted.
+type keylessRows struct {
+	rows *kvcache.SimpleLRUCache
+	// oldValues reads the old values of the rows not cached, if it's nil, the rows not cached are
+	// treated as not existing, which is only correct if all the changes of the table are mounted.
+	oldValues OldValueReader
+}
+
+func newKeylessRows(oldValues OldValueReader) *keylessRows {
+	return &keylessRows{
+		rows:      kvcache.NewSimpleLRUCache(defaultKeylessRowsCacheSize, 0, 0),
+		oldValues: oldValues,
+	}
+}
+
+// oldColumns returns the columns of the row before ts, decode mounts the old value read from TiKV
+func (r *keylessRows) oldColumns(
+	ctx context.Context, key keylessRowKey, ts uint64, decode func(value []byte) (map[string]*model.Column, error),
+) (map[string]*model.Column, bool, error) {
+	if columns, ok := r.rows.Get(key); ok {
+		return columns.(keylessRowColumns), true, nil
+	}
+	if r.oldValues == nil {
+		return nil, false, nil
+	}
+	value, err := r.oldValues.ReadOldValue(ctx, tablecodec.EncodeRowKeyWithHandle(key.tableID, key.recordID), ts)
+	if err != nil {
+		return nil, false, errors.Annotatef(err, "read the old value of the row, tableID: %d, recordID: %d, ts: %d",
+			key.tableID, key.recordID, ts)
+	}
+	if value == nil {
+		return nil, false, nil
+	}
+	columns, err := decode(value)
+	if err != nil {
+		return nil, false, errors.Trace(err)
+	}
+	return columns, true, nil
+}
+
+// apply returns the events replicating the row changed event, an update is replicated by
+// deleting the old row and inserting the new one. The events must be applied in order.
+func (r *keylessRows) apply(
+	ctx context.Context, tableID, recordID int64, tableInfo *TableInfo, event *model.RowChangedEvent,
+	decode func(value []byte) (map[string]*model.Column, error),
+) ([]*model.RowChangedEvent, error) {
+	key := keylessRowKey{tableID: tableID, recordID: recordID}
+	oldColumns, exist, err := r.oldColumns(ctx, key, event.Ts, decode)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if exist {
+		oldColumns = filterDroppedColumns(oldColumns, tableInfo)
+	}
+	if event.Delete {
+		if !exist {
+			log.Warn("skip the delete of the row without a primary key or unique index, "+
+				"because its old value isn't found",
+				zap.String("schema", event.Schema), zap.String("table", event.Table),
+				zap.Int64("tableID", tableID), zap.Int64("recordID", recordID), zap.Uint64("ts", event.Ts))
+			return nil, nil
+		}
+		r.rows.Delete(key)
+		event.Columns = oldColumns
+		return []*model.RowChangedEvent{event}, nil
+	}
+
+	// the value of an existing key isn't replaced by Put
+	r.rows.Delete(key)
+	r.rows.Put(key, keylessRowColumns(event.Columns))
+	if !exist {
+		return []*model.RowChangedEvent{event}, nil
+	}
+	deleteEvent := &model.RowChangedEvent{
+		Ts:            event.Ts,
+		Schema:        event.Schema,
+		Table:         event.Table,
+		SchemaVersion: event.SchemaVersion,
+		Delete:        true,
+		Columns:       oldColumns,
+	}
+	return []*model.RowChangedEvent{deleteEvent, event}, nil
+}
+
+// filterDroppedColumns removes the columns which are dropped after the row is changed
+func filterDroppedColumns(columns map[string]*model.Column, tableInfo *TableInfo) map[string]*model.Column {
+	filtered := make(map[string]*model.Column, len(columns))
+	for _, col := range tableInfo.Columns {
+		if !tableInfo.IsColWritable(col) {
+			continue
+		}
+		if value, ok := columns[col.Name.O]; ok {
+			filtered[col.Name.O] = value
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+	"sync/atomic"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/types"
+)
+
+type keylessRowsSuite struct{}
+
+var _ = Suite(&keylessRowsSuite{})
+
+// mockOldValueReader returns the old values of the rows changed before the mounter starts
+type mockOldValueReader struct {
+	values map[string][]byte
+	reads  int
+}
+
+func (r *mockOldValueReader) ReadOldValue(ctx context.Context, key []byte, ts uint64) ([]byte, error) {
+	r.reads++
+	return r.values[string(key)], nil
+}
+
+func (s *keylessRowsSuite) TestMountKeylessRows(c *C) {
+	newColumn := func(id int64, name string) *timodel.ColumnInfo {
+		col := &timodel.ColumnInfo{ID: id, Name: timodel.NewCIStr(name), Offset: int(id - 1), State: timodel.StatePublic}
+		col.Tp = mysql.TypeLonglong
+		return col
+	}
+	historyJobs := []*timodel.Job{{
+		ID:       1,
+		State:    timodel.JobStateSynced,
+		SchemaID: 1,
+		Type:     timodel.ActionCreateSchema,
+		BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 1,
+			DBInfo:     &timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test"), State: timodel.StatePublic},
+			FinishedTS: 10},
+		Query: "create database test",
+	}, {
+		ID:       2,
+		State:    timodel.JobStateSynced,
+		SchemaID: 1,
+		TableID:  50,
+		Type:     timodel.ActionCreateTable,
+		BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 2, FinishedTS: 20, TableInfo: &timodel.TableInfo{
+			ID:      50,
+			Name:    timodel.NewCIStr("t1"),
+			State:   timodel.StatePublic,
+			Columns: []*timodel.ColumnInfo{newColumn(1, "a"), newColumn(2, "b")},
+		}},
+		Query: "create table test.t1(a bigint, b bigint)",
+	}}
+	put := func(handle, a, b int64, ts uint64) *model.RawKVEntry {
+		value, err := tablecodec.EncodeOldRow(&stmtctx.StatementContext{},
+			[]types.Datum{types.NewIntDatum(a), types.NewIntDatum(b)}, []int64{1, 2}, nil, nil)
+		c.Assert(err, IsNil)
+		return &model.RawKVEntry{OpType: model.OpTypePut, Key: tablecodec.EncodeRowKeyWithHandle(50, handle), Value: value, Ts: ts}
+	}
+	del := func(handle int64, ts uint64) *model.RawKVEntry {
+		return &model.RawKVEntry{OpType: model.OpTypeDelete, Key: tablecodec.EncodeRowKeyWithHandle(50, handle), Ts: ts}
+	}
+	rawRows := []*model.RawKVEntry{
+		put(1, 1, 10, 100),
+		put(1, 1, 11, 110),
+		// the rows 2 and 3 are inserted before the table is replicated
+		del(2, 115),
+		put(3, 3, 31, 118),
+		del(1, 120),
+		// the row 4 is deleted before it's inserted again
+		del(4, 125),
+		{OpType: model.OpTypeResolved, Ts: 130},
+	}
+	oldValues := &mockOldValueReader{values: map[string][]byte{
+		string(put(2, 2, 20, 0).Key): put(2, 2, 20, 0).Value,
+		string(put(3, 3, 30, 0).Key): put(3, 3, 30, 0).Value,
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	builder := NewStorageBuilder(historyJobs, nil)
+	atomic.StoreUint64(&builder.resolvedTs, 130)
+	storage, err := builder.Build(50)
+	c.Assert(err, IsNil)
+	c.Assert(storage.IsIneligibleTableID(50), IsTrue)
+
+	rawCh := make(chan *model.RawKVEntry, len(rawRows))
+	for _, raw := range rawRows {
+		rawCh <- raw
+	}
+	mounter := NewMounter(rawCh, storage, nil, true, oldValues)
+	go func() {
+		err := mounter.Run(ctx)
+		c.Assert(errors.Cause(err), Equals, context.Canceled)
+	}()
+
+	expected := []struct {
+		ts      uint64
+		delete  bool
+		a, b    int64
+		resolve bool
+	}{
+		{ts: 100, a: 1, b: 10},
+		{ts: 110, delete: true, a: 1, b: 10},
+		{ts: 110, a: 1, b: 11},
+		{ts: 115, delete: true, a: 2, b: 20},
+		{ts: 118, delete: true, a: 3, b: 30},
+		{ts: 118, a: 3, b: 31},
+		{ts: 120, delete: true, a: 1, b: 11},
+		{ts: 130, resolve: true},
+	}
+	for i, e := range expected {
+		row := <-mounter.Output()
+		comment := Commentf("row %d", i)
+		c.Assert(row.Ts, Equals, e.ts, comment)
+		c.Assert(row.Resolved, Equals, e.resolve, comment)
+		if e.resolve {
+			continue
+		}
+		c.Assert(row.Delete, Equals, e.delete, comment)
+		c.Assert(row.Columns, HasLen, 2, comment)
+		c.Assert(row.Columns["a"].Value, Equals, e.a, comment)
+		c.Assert(row.Columns["b"].Value, Equals, e.b, comment)
+		c.Assert(row.Columns["a"].WhereHandle, IsTrue, comment)
+		c.Assert(row.Columns["b"].WhereHandle, IsTrue, comment)
+	}
+	// only the rows not cached are read from TiKV
+	c.Assert(oldValues.reads, Equals, 4)
+}
+
+func (s *keylessRowsSuite) TestEvictKeylessRows(c *C) {
+	rows := newKeylessRows(nil)
+	tableInfo := WrapTableInfo(&timodel.TableInfo{ID: 50})
+	for i := 0; i < defaultKeylessRowsCacheSize+1; i++ {
+		events, err := rows.apply(context.Background(), 50, int64(i), tableInfo,
+			&model.RowChangedEvent{Ts: uint64(i + 1), Columns: map[string]*model.Column{}}, nil)
+		c.Assert(err, IsNil)
+		c.Assert(events, HasLen, 1)
+	}
+	c.Assert(rows.rows.Size(), Equals, defaultKeylessRowsCacheSize)
+	_, ok := rows.rows.Get(keylessRowKey{tableID: 50, recordID: 0})
+	c.Assert(ok, IsFalse)
+	_, ok = rows.rows.Get(keylessRowKey{tableID: 50, recordID: defaultKeylessRowsCacheSize})
+	c.Assert(ok, IsTrue)
+}
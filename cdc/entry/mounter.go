@@ -29,6 +29,7 @@ import (
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/types"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
 type baseKVEntry struct {
@@ -50,7 +51,7 @@ type indexKVEntry struct {
 }
 
 func (idx *indexKVEntry) unflatten(tableInfo *TableInfo) error {
-	if tableInfo.ID != idx.TableID {
+	if !tableInfo.IsPhysicalTableID(idx.TableID) {
 		return errors.New("wrong table info in unflatten")
 	}
 	index, exist := tableInfo.GetIndexInfo(idx.IndexID)
@@ -94,21 +95,50 @@ type Mounter interface {
 }
 
 type mounterImpl struct {
-	schemaStorage   *Storage
+	schemas         SchemaGetter
 	rawRowChangedCh <-chan *model.RawKVEntry
 	output          chan *model.RowChangedEvent
+	workerPool      *MounterWorkerPool
+
+	forceReplicate bool
+	keylessRows    *keylessRows
 }
 
-// NewMounter creates a mounter
-func NewMounter(rawRowChangedCh <-chan *model.RawKVEntry, schemaStorage *Storage) Mounter {
+// NewMounter creates a mounter, the rows are decoded by workerPool concurrently
+// and output in the order of the raw rows. If workerPool is nil, the rows are decoded
+// by the mounter itself. If forceReplicate is true, the rows of the tables without
+// a primary key or unique index are identified by all the columns, and their old
+// values are read by oldValues.
+func NewMounter(
+	rawRowChangedCh <-chan *model.RawKVEntry, schemas SchemaGetter, workerPool *MounterWorkerPool,
+	forceReplicate bool, oldValues OldValueReader,
+) Mounter {
 	return &mounterImpl{
-		schemaStorage:   schemaStorage,
+		schemas:         schemas,
 		rawRowChangedCh: rawRowChangedCh,
 		output:          make(chan *model.RowChangedEvent),
+		workerPool:      workerPool,
+		forceReplicate:  forceReplicate,
+		keylessRows:     newKeylessRows(oldValues),
 	}
 }
 
 func (m *mounterImpl) Run(ctx context.Context) error {
+	errg, ctx := errgroup.WithContext(ctx)
+	tasks := make(chan *mountTask, defaultMountTaskChanSize)
+	errg.Go(func() error {
+		return m.dispatch(ctx, tasks)
+	})
+	errg.Go(func() error {
+		return m.collect(ctx, tasks)
+	})
+	return errg.Wait()
+}
+
+// dispatch applies the DDL jobs before the raw rows to the schema storage, and sends
+// the raw rows to the worker pool with their schema. The tasks are sent to tasks in
+// the order of the raw rows.
+func (m *mounterImpl) dispatch(ctx context.Context, tasks chan<- *mountTask) error {
 	var lastRowChangedEvent *model.RawKVEntry
 	for {
 		var rawRow *model.RawKVEntry
@@ -126,30 +156,81 @@ func (m *mounterImpl) Run(ctx context.Context) error {
 			return errors.Trace(ctx.Err())
 		}
 
+		var task *mountTask
 		if rawRow.OpType == model.OpTypeResolved {
-			m.output <- &model.RowChangedEvent{Resolved: true, Ts: rawRow.Ts}
-			continue
+			task = newMountedTask(&model.RowChangedEvent{Resolved: true, Ts: rawRow.Ts})
+		} else {
+			storage, err := m.schemas.GetSnapshot(rawRow.Ts)
+			switch errors.Cause(err) {
+			case nil:
+			case model.ErrUnresolved:
+				lastRowChangedEvent = rawRow
+				time.Sleep(50 * time.Millisecond)
+				continue
+			default:
+				return errors.Cause(err)
+			}
+
+			schema, err := m.fetchRowSchema(storage, rawRow)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if schema == nil {
+				continue
+			}
+			task = newMountTask(m, rawRow, schema)
+			if m.workerPool == nil {
+				task.run()
+			} else if err := m.workerPool.submit(ctx, task); err != nil {
+				return errors.Trace(err)
+			}
 		}
 
-		err := m.schemaStorage.HandlePreviousDDLJobIfNeed(rawRow.Ts)
-		switch errors.Cause(err) {
-		case nil:
-		case model.ErrUnresolved:
-			lastRowChangedEvent = rawRow
-			time.Sleep(50 * time.Millisecond)
-			continue
-		default:
-			return errors.Cause(err)
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case tasks <- task:
 		}
+	}
+}
 
-		event, err := m.unmarshalAndMountRowChanged(rawRow)
+// collect outputs the results of the tasks in order
+func (m *mounterImpl) collect(ctx context.Context, tasks <-chan *mountTask) error {
+	for {
+		var task *mountTask
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case task = <-tasks:
+		}
+		event, err := task.wait(ctx)
 		if err != nil {
 			return errors.Trace(err)
 		}
 		if event == nil {
 			continue
 		}
-		m.output <- event
+		events := []*model.RowChangedEvent{event}
+		if schema := task.schema; schema != nil && schema.keyless && bytes.HasPrefix(schema.key, recordPrefix) {
+			_, recordID, err := decodeRecordID(schema.key)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			events, err = m.keylessRows.apply(ctx, schema.tableID, recordID, schema.tableInfo, event,
+				func(value []byte) (map[string]*model.Column, error) {
+					return m.mountOldRow(value, event.Ts, recordID, schema)
+				})
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+		for _, event := range events {
+			select {
+			case <-ctx.Done():
+				return errors.Trace(ctx.Err())
+			case m.output <- event:
+			}
+		}
 	}
 }
 
@@ -157,7 +238,24 @@ func (m *mounterImpl) Output() <-chan *model.RowChangedEvent {
 	return m.output
 }
 
-func (m *mounterImpl) unmarshalAndMountRowChanged(raw *model.RawKVEntry) (*model.RowChangedEvent, error) {
+// rowSchema is the schema of the table which a raw row belongs to, the TableInfo is
+// replaced rather than modified by the schema storage, so it can be used concurrently.
+type rowSchema struct {
+	// key is the key of the raw row without the table prefix
+	key       []byte
+	tableID   int64
+	tableInfo *TableInfo
+	tableName TableName
+	// exist is true if both the TableInfo and the name of the table exist
+	exist     bool
+	truncated bool
+	// keyless is true if the table has no primary key or unique index and it's force replicated
+	keyless bool
+}
+
+// fetchRowSchema fetches the schema of the raw row from the schema storage,
+// it returns nil if the raw row doesn't belong to a table.
+func (m *mounterImpl) fetchRowSchema(storage *Storage, raw *model.RawKVEntry) (*rowSchema, error) {
 	if !bytes.HasPrefix(raw.Key, tablePrefix) {
 		return nil, nil
 	}
@@ -165,21 +263,35 @@ func (m *mounterImpl) unmarshalAndMountRowChanged(raw *model.RawKVEntry) (*model
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	schema := &rowSchema{
+		key:       key,
+		tableID:   tableID,
+		truncated: storage.IsTruncateTableID(tableID),
+	}
+	schema.tableInfo, _ = storage.TableByID(tableID)
+	schema.tableName, schema.exist = storage.GetTableNameByID(tableID)
+	schema.exist = schema.exist && schema.tableInfo != nil
+	schema.keyless = m.forceReplicate && schema.tableInfo != nil && !schema.tableInfo.ExistTableUniqueColumn()
+	return schema, nil
+}
+
+func (m *mounterImpl) unmarshalAndMountRowChanged(raw *model.RawKVEntry, schema *rowSchema) (*model.RowChangedEvent, error) {
+	key := schema.key
 	baseInfo := baseKVEntry{
 		Ts:      raw.Ts,
-		TableID: tableID,
+		TableID: schema.tableID,
 		Delete:  raw.OpType == model.OpTypeDelete,
 	}
 	switch {
 	case bytes.HasPrefix(key, recordPrefix):
-		rowKV, err := m.unmarshalRowKVEntry(key, raw.Value, baseInfo)
+		rowKV, err := m.unmarshalRowKVEntry(key, raw.Value, baseInfo, schema)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
 		if rowKV == nil {
 			return nil, nil
 		}
-		return m.mountRowKVEntry(rowKV)
+		return m.mountRowKVEntry(rowKV, schema)
 	case bytes.HasPrefix(key, indexPrefix):
 		indexKV, err := m.unmarshalIndexKVEntry(key, raw.Value, baseInfo)
 		if err != nil {
@@ -188,16 +300,16 @@ func (m *mounterImpl) unmarshalAndMountRowChanged(raw *model.RawKVEntry) (*model
 		if indexKV == nil {
 			return nil, nil
 		}
-		return m.mountIndexKVEntry(indexKV)
+		return m.mountIndexKVEntry(indexKV, schema)
 	}
 	return nil, nil
 }
 
-func (m *mounterImpl) unmarshalRowKVEntry(restKey []byte, rawValue []byte, base baseKVEntry) (*rowKVEntry, error) {
+func (m *mounterImpl) unmarshalRowKVEntry(restKey []byte, rawValue []byte, base baseKVEntry, schema *rowSchema) (*rowKVEntry, error) {
 	tableID := base.TableID
-	tableInfo, exist := m.schemaStorage.TableByID(tableID)
-	if !exist {
-		if m.schemaStorage.IsTruncateTableID(tableID) {
+	tableInfo := schema.tableInfo
+	if tableInfo == nil {
+		if schema.truncated {
 			log.Debug("skip the DML of truncated table", zap.Uint64("ts", base.Ts), zap.Int64("tableID", tableID))
 			return nil, nil
 		}
@@ -278,13 +390,14 @@ func UnmarshalDDL(raw *model.RawKVEntry) (*timodel.Job, error) {
 	return job, nil
 }
 
-func (m *mounterImpl) mountRowKVEntry(row *rowKVEntry) (*model.RowChangedEvent, error) {
-	tableInfo, tableName, exist := m.fetchTableInfo(row.TableID)
-	if !exist {
+func (m *mounterImpl) mountRowKVEntry(row *rowKVEntry, schema *rowSchema) (*model.RowChangedEvent, error) {
+	tableInfo, tableName := schema.tableInfo, schema.tableName
+	if !schema.exist {
 		return nil, errors.NotFoundf("table in schema storage, id: %d", row.TableID)
 	}
 
-	if row.Delete && !tableInfo.PKIsHandle {
+	// the deleted rows are mounted from the unique indexes, except the rows of the keyless tables
+	if row.Delete && !tableInfo.PKIsHandle && !schema.keyless {
 		return nil, nil
 	}
 
@@ -308,17 +421,18 @@ func (m *mounterImpl) mountRowKVEntry(row *rowKVEntry) (*model.RowChangedEvent,
 		}
 		values[colName] = &model.Column{
 			Type:        colInfo.Tp,
-			WhereHandle: tableInfo.IsColumnUnique(colInfo.ID),
+			WhereHandle: tableInfo.IsColumnUnique(colInfo.ID) || schema.keyless,
 			Value:       value,
 		}
 	}
 
 	event := &model.RowChangedEvent{
-		Ts:           row.Ts,
-		Resolved:     false,
-		Schema:       tableName.Schema,
-		Table:        tableName.Table,
-		IndieMarkCol: tableInfo.IndieMarkCol,
+		Ts:            row.Ts,
+		Resolved:      false,
+		Schema:        tableName.Schema,
+		Table:         tableName.Table,
+		SchemaVersion: tableInfo.UpdateTS,
+		IndieMarkCol:  tableInfo.IndieMarkCol,
 	}
 
 	if !row.Delete {
@@ -327,7 +441,7 @@ func (m *mounterImpl) mountRowKVEntry(row *rowKVEntry) (*model.RowChangedEvent,
 			if !ok && tableInfo.IsColWritable(col) {
 				values[col.Name.O] = &model.Column{
 					Type:        col.Tp,
-					WhereHandle: tableInfo.IsColumnUnique(col.ID),
+					WhereHandle: tableInfo.IsColumnUnique(col.ID) || schema.keyless,
 					Value:       getDefaultOrZeroValue(col),
 				}
 			}
@@ -338,14 +452,30 @@ func (m *mounterImpl) mountRowKVEntry(row *rowKVEntry) (*model.RowChangedEvent,
 	return event, nil
 }
 
-func (m *mounterImpl) mountIndexKVEntry(idx *indexKVEntry) (*model.RowChangedEvent, error) {
+// mountOldRow mounts the old value of the row changed at ts
+func (m *mounterImpl) mountOldRow(value []byte, ts uint64, recordID int64, schema *rowSchema) (map[string]*model.Column, error) {
+	row, err := decodeRow(value, recordID, schema.tableInfo)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	event, err := m.mountRowKVEntry(&rowKVEntry{
+		baseKVEntry: baseKVEntry{Ts: ts, TableID: schema.tableID, RecordID: recordID},
+		Row:         row,
+	}, schema)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return event.Columns, nil
+}
+
+func (m *mounterImpl) mountIndexKVEntry(idx *indexKVEntry, schema *rowSchema) (*model.RowChangedEvent, error) {
 	// skip set index KV
 	if !idx.Delete {
 		return nil, nil
 	}
-	tableInfo, tableName, exist := m.fetchTableInfo(idx.TableID)
-	if !exist {
-		if m.schemaStorage.IsTruncateTableID(idx.TableID) {
+	tableInfo, tableName := schema.tableInfo, schema.tableName
+	if !schema.exist {
+		if schema.truncated {
 			log.Debug("skip the DML of truncated table", zap.Uint64("ts", idx.Ts), zap.Int64("tableID", idx.TableID))
 			return nil, nil
 		}
@@ -378,13 +508,14 @@ func (m *mounterImpl) mountIndexKVEntry(idx *indexKVEntry) (*model.RowChangedEve
 		}
 	}
 	return &model.RowChangedEvent{
-		Ts:           idx.Ts,
-		Resolved:     false,
-		Schema:       tableName.Schema,
-		Table:        tableName.Table,
-		IndieMarkCol: tableInfo.IndieMarkCol,
-		Delete:       true,
-		Columns:      values,
+		Ts:            idx.Ts,
+		Resolved:      false,
+		Schema:        tableName.Schema,
+		Table:         tableName.Table,
+		SchemaVersion: tableInfo.UpdateTS,
+		IndieMarkCol:  tableInfo.IndieMarkCol,
+		Delete:        true,
+		Columns:       values,
 	}, nil
 }
 
@@ -436,15 +567,6 @@ func getDefaultOrZeroValue(col *timodel.ColumnInfo) interface{} {
 	return d.GetValue()
 }
 
-func (m *mounterImpl) fetchTableInfo(tableID int64) (tableInfo *TableInfo, tableName TableName, exist bool) {
-	tableInfo, exist = m.schemaStorage.TableByID(tableID)
-	if !exist {
-		return
-	}
-	tableName, exist = m.schemaStorage.GetTableNameByID(tableID)
-	return
-}
-
 func fetchHandleValue(tableInfo *TableInfo, recordID int64) (pkCoID int64, pkValue *types.Datum, err error) {
 	handleColOffset := -1
 	for i, col := range tableInfo.Columns {
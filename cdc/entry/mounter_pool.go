@@ -0,0 +1,109 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	"golang.org/x/sync/errgroup"
+)
+
+const defaultMountTaskChanSize = 1024
+
+// MounterWorkerPool decodes the rows of the mounters concurrently, it's shared by the mounters
+// of a capture, so the number of goroutines decoding rows is limited by the worker number.
+type MounterWorkerPool struct {
+	workerNum int
+	tasks     chan *mountTask
+}
+
+// NewMounterWorkerPool creates a MounterWorkerPool with workerNum workers
+func NewMounterWorkerPool(workerNum int) *MounterWorkerPool {
+	return &MounterWorkerPool{
+		workerNum: workerNum,
+		tasks:     make(chan *mountTask, defaultMountTaskChanSize),
+	}
+}
+
+// Run runs the workers until the context is done
+func (p *MounterWorkerPool) Run(ctx context.Context) error {
+	errg, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < p.workerNum; i++ {
+		errg.Go(func() error {
+			for {
+				select {
+				case <-ctx.Done():
+					return errors.Trace(ctx.Err())
+				case task := <-p.tasks:
+					task.run()
+				}
+			}
+		})
+	}
+	return errg.Wait()
+}
+
+func (p *MounterWorkerPool) submit(ctx context.Context, task *mountTask) error {
+	select {
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	case p.tasks <- task:
+	}
+	return nil
+}
+
+// mountTask mounts a row with the schema fetched when the task is created, so the task
+// doesn't access the schema storage which is modified by the mounter.
+type mountTask struct {
+	mounter *mounterImpl
+	raw     *model.RawKVEntry
+	schema  *rowSchema
+
+	event *model.RowChangedEvent
+	err   error
+	done  chan struct{}
+}
+
+func newMountTask(m *mounterImpl, raw *model.RawKVEntry, schema *rowSchema) *mountTask {
+	return &mountTask{
+		mounter: m,
+		raw:     raw,
+		schema:  schema,
+		done:    make(chan struct{}),
+	}
+}
+
+// newMountedTask returns a task whose result is event
+func newMountedTask(event *model.RowChangedEvent) *mountTask {
+	task := &mountTask{event: event, done: make(chan struct{})}
+	close(task.done)
+	return task
+}
+
+func (t *mountTask) run() {
+	t.event, t.err = t.mounter.unmarshalAndMountRowChanged(t.raw, t.schema)
+	close(t.done)
+}
+
+// wait waits for the task to be done and returns its result
+func (t *mountTask) wait(ctx context.Context) (*model.RowChangedEvent, error) {
+	select {
+	case <-ctx.Done():
+		return nil, errors.Trace(ctx.Err())
+	case <-t.done:
+	}
+	return t.event, errors.Trace(t.err)
+}
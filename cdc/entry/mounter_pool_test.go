@@ -0,0 +1,162 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/rowcodec"
+)
+
+type mounterPoolSuite struct{}
+
+var _ = Suite(&mounterPoolSuite{})
+
+func buildMountTableInfo(withColumnW bool) *timodel.TableInfo {
+	newColumn := func(id int64, name string) *timodel.ColumnInfo {
+		col := &timodel.ColumnInfo{ID: id, Name: timodel.NewCIStr(name), Offset: int(id - 1), State: timodel.StatePublic}
+		col.Tp = mysql.TypeLonglong
+		return col
+	}
+	columns := []*timodel.ColumnInfo{newColumn(1, "id"), newColumn(2, "v")}
+	columns[0].Flag = mysql.PriKeyFlag | mysql.NotNullFlag
+	if withColumnW {
+		columns = append(columns, newColumn(3, "w"))
+	}
+	return &timodel.TableInfo{
+		ID:         50,
+		Name:       timodel.NewCIStr("t1"),
+		PKIsHandle: true,
+		State:      timodel.StatePublic,
+		Columns:    columns,
+	}
+}
+
+func (s *mounterPoolSuite) TestMountInOrder(c *C) {
+	historyJobs := []*timodel.Job{{
+		ID:       1,
+		State:    timodel.JobStateSynced,
+		SchemaID: 1,
+		Type:     timodel.ActionCreateSchema,
+		BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 1,
+			DBInfo:     &timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test"), State: timodel.StatePublic},
+			FinishedTS: 10},
+		Query: "create database test",
+	}, {
+		ID:         2,
+		State:      timodel.JobStateSynced,
+		SchemaID:   1,
+		TableID:    50,
+		Type:       timodel.ActionCreateTable,
+		BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 2, TableInfo: buildMountTableInfo(false), FinishedTS: 20},
+		Query:      "create table test.t1(id bigint primary key, v bigint)",
+	}, {
+		ID:         3,
+		State:      timodel.JobStateSynced,
+		SchemaID:   1,
+		TableID:    50,
+		Type:       timodel.ActionAddColumn,
+		BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 3, TableInfo: buildMountTableInfo(true), FinishedTS: 1000},
+		Query:      "alter table test.t1 add column w bigint",
+	}}
+
+	const rowNum = 2000
+	// the rows after the column w is added are encoded in the new row format
+	sc := &stmtctx.StatementContext{TimeZone: time.UTC}
+	rawRows := make([]*model.RawKVEntry, 0, rowNum+2)
+	for i := 0; i < rowNum; i++ {
+		ts := uint64(500 + i)
+		var value []byte
+		var err error
+		if ts < 1000 {
+			value, err = tablecodec.EncodeOldRow(sc, []types.Datum{types.NewIntDatum(int64(i))}, []int64{2}, nil, nil)
+		} else {
+			value, err = tablecodec.EncodeRow(sc, []types.Datum{types.NewIntDatum(int64(i)), types.NewIntDatum(int64(-i))},
+				[]int64{2, 3}, nil, nil, &rowcodec.Encoder{Enable: true})
+		}
+		c.Assert(err, IsNil)
+		rawRows = append(rawRows, &model.RawKVEntry{
+			OpType: model.OpTypePut,
+			Key:    tablecodec.EncodeRowKeyWithHandle(50, int64(i)),
+			Value:  value,
+			Ts:     ts,
+		})
+		if i == rowNum/2 {
+			rawRows = append(rawRows, &model.RawKVEntry{OpType: model.OpTypeResolved, Ts: ts})
+		}
+	}
+	rawRows = append(rawRows, &model.RawKVEntry{OpType: model.OpTypeResolved, Ts: 500 + rowNum})
+
+	for _, workerNum := range []int{0, 1, 4} {
+		ctx, cancel := context.WithCancel(context.Background())
+		builder := NewStorageBuilder(historyJobs, nil)
+		atomic.StoreUint64(&builder.resolvedTs, 500+rowNum)
+		storage, err := builder.Build(100)
+		c.Assert(err, IsNil)
+
+		var pool *MounterWorkerPool
+		if workerNum > 0 {
+			pool = NewMounterWorkerPool(workerNum)
+			go func() {
+				err := pool.Run(ctx)
+				c.Assert(errors.Cause(err), Equals, context.Canceled)
+			}()
+		}
+		rawCh := make(chan *model.RawKVEntry)
+		mounter := NewMounter(rawCh, storage, pool, false, nil)
+		go func() {
+			err := mounter.Run(ctx)
+			c.Assert(errors.Cause(err), Equals, context.Canceled)
+		}()
+		go func() {
+			for _, raw := range rawRows {
+				select {
+				case <-ctx.Done():
+					return
+				case rawCh <- raw:
+				}
+			}
+		}()
+
+		for i, raw := range rawRows {
+			row := <-mounter.Output()
+			c.Assert(row.Ts, Equals, raw.Ts)
+			if raw.OpType == model.OpTypeResolved {
+				c.Assert(row.Resolved, IsTrue)
+				continue
+			}
+			c.Assert(row.Schema, Equals, "test")
+			c.Assert(row.Table, Equals, "t1")
+			id := row.Columns["id"].Value.(int64)
+			c.Assert(row.Columns["v"].Value, Equals, id, Commentf("row %d, worker num %d", i, workerNum))
+			if raw.Ts < 1000 {
+				c.Assert(row.Columns, HasLen, 2)
+			} else {
+				c.Assert(row.Columns, HasLen, 3)
+				c.Assert(row.Columns["w"].Value, Equals, -id)
+			}
+		}
+		cancel()
+	}
+}
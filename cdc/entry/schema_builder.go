@@ -124,6 +124,24 @@ func NewStorageBuilder(historyDDL []*timodel.Job, ddlEventCh <-chan *model.RawKV
 	return builder
 }
 
+// NewStorageBuilderFromSnapshot creates a new StorageBuilder from the schema snapshot,
+// only the jobs finished after the snapshot are replayed.
+func NewStorageBuilderFromSnapshot(snap *model.SchemaSnapshot, jobs []*timodel.Job, ddlEventCh <-chan *model.RawKVEntry) *StorageBuilder {
+	return NewStorageBuilder(snapshotHistoryJobs(snap, jobs), ddlEventCh)
+}
+
+// snapshotHistoryJobs returns the jobs of the snapshot and the jobs finished after it
+func snapshotHistoryJobs(snap *model.SchemaSnapshot, jobs []*timodel.Job) []*timodel.Job {
+	historyDDL := make([]*timodel.Job, 0, len(snap.Jobs)+len(jobs))
+	historyDDL = append(historyDDL, snap.Jobs...)
+	for _, job := range jobs {
+		if job.BinlogInfo.FinishedTS > snap.Ts {
+			historyDDL = append(historyDDL, job)
+		}
+	}
+	return historyDDL
+}
+
 // Run runs the StorageBuilder
 func (b *StorageBuilder) Run(ctx context.Context) error {
 	for {
@@ -182,6 +200,36 @@ func (b *StorageBuilder) Build(ts uint64) (*Storage, error) {
 	return c, nil
 }
 
+// SnapshotJobs returns the jobs which create the schemas and tables at ts, the last one is finished at ts.
+// The history jobs are loaded after ts, so it doesn't wait for the DDL puller to resolve ts.
+func (b *StorageBuilder) SnapshotJobs(ts uint64) ([]*timodel.Job, error) {
+	snap, err := b.Snapshot(ts)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return snap.Jobs, nil
+}
+
+// Snapshot returns the schema snapshot at ts, it's persisted and loaded by NewStorageBuilderFromSnapshot.
+func (b *StorageBuilder) Snapshot(ts uint64) (*model.SchemaSnapshot, error) {
+	if ts < b.gcTs {
+		return nil, errors.Errorf("the snapshot ts %d is less than gcTs %d", ts, b.gcTs)
+	}
+
+	b.baseStorageMu.Lock()
+	c := b.baseStorage.Clone()
+	b.baseStorageMu.Unlock()
+
+	if err := c.handleJobs(ts); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &model.SchemaSnapshot{
+		Ts:            ts,
+		SchemaVersion: c.currentVersion,
+		Jobs:          c.snapshotJobs(ts),
+	}, nil
+}
+
 // GetResolvedTs return the resolvedTs of DDL puller in this StorageBuilder
 func (b *StorageBuilder) GetResolvedTs() uint64 {
 	return atomic.LoadUint64(&b.resolvedTs)
@@ -69,6 +69,47 @@ func (s *schemaBuilderSuite) TestStorageBuilder(c *C) {
 
 }
 
+func (s *schemaBuilderSuite) TestSnapshotJobs(c *C) {
+	historyJobs := []*timodel.Job{{
+		ID:       1,
+		State:    timodel.JobStateSynced,
+		SchemaID: 1,
+		Type:     timodel.ActionCreateSchema,
+		BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 1,
+			DBInfo: &timodel.DBInfo{
+				ID:    1,
+				Name:  timodel.NewCIStr("testDB"),
+				State: timodel.StatePublic,
+			}, FinishedTS: 10},
+		Query: "create database testDB",
+	},
+		buildCreateTableJob(2, 1, 3, "testTBL2", 20),
+		buildCreateTableJob(3, 1, 2, "testTBL1", 30),
+	}
+	b := NewStorageBuilder(historyJobs, nil)
+	jobs, err := b.SnapshotJobs(25)
+	c.Assert(err, IsNil)
+	c.Assert(jobs, HasLen, 2)
+
+	// the snapshot at 35 is rebuilt from the jobs
+	jobs, err = b.SnapshotJobs(35)
+	c.Assert(err, IsNil)
+	c.Assert(jobs, HasLen, 3)
+	c.Assert(jobs[0].Type, Equals, timodel.ActionCreateSchema)
+	c.Assert(jobs[1].TableID, Equals, int64(2))
+	c.Assert(jobs[2].TableID, Equals, int64(3))
+	c.Assert(jobs[0].BinlogInfo.FinishedTS, Equals, uint64(33))
+	c.Assert(jobs[2].BinlogInfo.FinishedTS, Equals, uint64(35))
+	storage, err := NewStorageBuilder(jobs, nil).Build(35)
+	c.Assert(err, IsNil)
+	table, ok := storage.TableByID(2)
+	c.Assert(ok, IsTrue)
+	c.Assert(table.Name.O, Equals, "testTBL1")
+	name, ok := storage.GetTableNameByID(3)
+	c.Assert(ok, IsTrue)
+	c.Assert(name, DeepEquals, TableName{Schema: "testDB", Table: "testTBL2"})
+}
+
 func buildCreateTableJob(jobID int64, schemaID int64, tableID int64, tableName string, finishedTs uint64) *timodel.Job {
 	return &timodel.Job{
 		ID:       jobID,
@@ -0,0 +1,209 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+	"go.uber.org/zap"
+)
+
+// SchemaSnapshotStorage persists the schema snapshots of a changefeed
+type SchemaSnapshotStorage interface {
+	// Put persists the snapshot
+	Put(ctx context.Context, snap *model.SchemaSnapshot) error
+	// Get returns the latest snapshot whose ts is not greater than ts,
+	// model.ErrSchemaSnapshotNotExists is returned if there is no such snapshot.
+	Get(ctx context.Context, ts uint64) (*model.SchemaSnapshot, error)
+	// GC removes the snapshots older than the latest one whose ts is not greater than ts,
+	// so the schemas at ts can still be loaded after GC.
+	GC(ctx context.Context, ts uint64) error
+}
+
+// NewSchemaSnapshotStorage creates the SchemaSnapshotStorage of the changefeed by the config
+func NewSchemaSnapshotStorage(
+	cfg *util.SchemaSnapshotConfig, etcdCli kv.CDCEtcdClient, changefeedID string,
+) (SchemaSnapshotStorage, error) {
+	switch cfg.Storage {
+	case util.SchemaSnapshotStorageFile:
+		if len(cfg.Dir) == 0 {
+			return nil, errors.New("the dir of the schema snapshots stored in files is not set")
+		}
+		return newFileSchemaSnapshotStorage(filepath.Join(cfg.Dir, changefeedID))
+	case util.SchemaSnapshotStorageEtcd:
+		return &etcdSchemaSnapshotStorage{cli: etcdCli, changefeedID: changefeedID}, nil
+	default:
+		return nil, errors.Errorf("unknown schema snapshot storage %s", cfg.Storage)
+	}
+}
+
+const schemaSnapshotFileSuffix = ".json"
+
+// fileSchemaSnapshotStorage stores every snapshot in a file named by its ts
+type fileSchemaSnapshotStorage struct {
+	dir string
+}
+
+func newFileSchemaSnapshotStorage(dir string) (*fileSchemaSnapshotStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Annotatef(err, "create the schema snapshot dir %s", dir)
+	}
+	return &fileSchemaSnapshotStorage{dir: dir}, nil
+}
+
+func (s *fileSchemaSnapshotStorage) path(ts uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", ts, schemaSnapshotFileSuffix))
+}
+
+// Put implements the SchemaSnapshotStorage interface, the snapshot is written to a temporary
+// file and renamed, so a snapshot file is never partially written.
+func (s *fileSchemaSnapshotStorage) Put(ctx context.Context, snap *model.SchemaSnapshot) error {
+	data, err := snap.Marshal()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tmp, err := ioutil.TempFile(s.dir, "snapshot-*.tmp")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if removeErr := os.Remove(tmp.Name()); removeErr != nil {
+			log.Warn("failed to remove the temporary schema snapshot file", zap.String("file", tmp.Name()), zap.Error(removeErr))
+		}
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.Rename(tmp.Name(), s.path(snap.Ts)))
+}
+
+// snapshotTs returns the ts of the snapshots in the dir in ascending order
+func (s *fileSchemaSnapshotStorage) snapshotTs() ([]uint64, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var tss []uint64
+	// the files are sorted by name, which are the padded ts
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, schemaSnapshotFileSuffix) {
+			continue
+		}
+		ts, err := strconv.ParseUint(strings.TrimSuffix(name, schemaSnapshotFileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		tss = append(tss, ts)
+	}
+	return tss, nil
+}
+
+// latestTs returns the ts of the latest snapshot whose ts is not greater than ts
+func (s *fileSchemaSnapshotStorage) latestTs(ts uint64) (uint64, error) {
+	tss, err := s.snapshotTs()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	for i := len(tss) - 1; i >= 0; i-- {
+		if tss[i] <= ts {
+			return tss[i], nil
+		}
+	}
+	return 0, errors.Annotatef(model.ErrSchemaSnapshotNotExists, "dir: %s, ts: %d", s.dir, ts)
+}
+
+// Get implements the SchemaSnapshotStorage interface
+func (s *fileSchemaSnapshotStorage) Get(ctx context.Context, ts uint64) (*model.SchemaSnapshot, error) {
+	latest, err := s.latestTs(ts)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	data, err := ioutil.ReadFile(s.path(latest))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	snap := &model.SchemaSnapshot{}
+	err = snap.Unmarshal(data)
+	return snap, errors.Trace(err)
+}
+
+// GC implements the SchemaSnapshotStorage interface
+func (s *fileSchemaSnapshotStorage) GC(ctx context.Context, ts uint64) error {
+	latest, err := s.latestTs(ts)
+	if errors.Cause(err) == model.ErrSchemaSnapshotNotExists {
+		return nil
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tss, err := s.snapshotTs()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, snapTs := range tss {
+		if snapTs >= latest {
+			break
+		}
+		if err := os.Remove(s.path(snapTs)); err != nil && !os.IsNotExist(err) {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// etcdSchemaSnapshotStorage stores the snapshots in etcd, which are shared by the captures
+type etcdSchemaSnapshotStorage struct {
+	cli          kv.CDCEtcdClient
+	changefeedID string
+}
+
+// Put implements the SchemaSnapshotStorage interface
+func (s *etcdSchemaSnapshotStorage) Put(ctx context.Context, snap *model.SchemaSnapshot) error {
+	return errors.Trace(s.cli.PutSchemaSnapshot(ctx, s.changefeedID, snap))
+}
+
+// Get implements the SchemaSnapshotStorage interface
+func (s *etcdSchemaSnapshotStorage) Get(ctx context.Context, ts uint64) (*model.SchemaSnapshot, error) {
+	snap, err := s.cli.GetSchemaSnapshot(ctx, s.changefeedID, ts)
+	return snap, errors.Trace(err)
+}
+
+// GC implements the SchemaSnapshotStorage interface
+func (s *etcdSchemaSnapshotStorage) GC(ctx context.Context, ts uint64) error {
+	snap, err := s.cli.GetSchemaSnapshot(ctx, s.changefeedID, ts)
+	if errors.Cause(err) == model.ErrSchemaSnapshotNotExists {
+		return nil
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(s.cli.DeleteSchemaSnapshots(ctx, s.changefeedID, snap.Ts))
+}
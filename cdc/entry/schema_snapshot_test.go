@@ -0,0 +1,126 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+)
+
+type schemaSnapshotSuite struct{}
+
+var _ = Suite(&schemaSnapshotSuite{})
+
+func (s *schemaSnapshotSuite) TestFileStorage(c *C) {
+	ctx := context.Background()
+	_, err := NewSchemaSnapshotStorage(&util.SchemaSnapshotConfig{Storage: util.SchemaSnapshotStorageFile}, kv.CDCEtcdClient{}, "cf")
+	c.Assert(err, NotNil)
+	_, err = NewSchemaSnapshotStorage(&util.SchemaSnapshotConfig{Storage: "unknown"}, kv.CDCEtcdClient{}, "cf")
+	c.Assert(err, NotNil)
+
+	storage, err := NewSchemaSnapshotStorage(&util.SchemaSnapshotConfig{
+		Storage: util.SchemaSnapshotStorageFile,
+		Dir:     c.MkDir(),
+	}, kv.CDCEtcdClient{}, "cf")
+	c.Assert(err, IsNil)
+	for _, ts := range []uint64{100, 200, 300} {
+		c.Assert(storage.Put(ctx, &model.SchemaSnapshot{Ts: ts, SchemaVersion: int64(ts / 10)}), IsNil)
+	}
+	_, err = storage.Get(ctx, 99)
+	c.Assert(errors.Cause(err), Equals, model.ErrSchemaSnapshotNotExists)
+	snap, err := storage.Get(ctx, 250)
+	c.Assert(err, IsNil)
+	c.Assert(snap.Ts, Equals, uint64(200))
+	c.Assert(snap.SchemaVersion, Equals, int64(20))
+
+	c.Assert(storage.GC(ctx, 50), IsNil)
+	c.Assert(storage.GC(ctx, 250), IsNil)
+	_, err = storage.Get(ctx, 199)
+	c.Assert(errors.Cause(err), Equals, model.ErrSchemaSnapshotNotExists)
+	snap, err = storage.Get(ctx, 250)
+	c.Assert(err, IsNil)
+	c.Assert(snap.Ts, Equals, uint64(200))
+	snap, err = storage.Get(ctx, 300)
+	c.Assert(err, IsNil)
+	c.Assert(snap.Ts, Equals, uint64(300))
+}
+
+func (s *schemaSnapshotSuite) TestBuildFromSnapshot(c *C) {
+	ctx := context.Background()
+	createDB := &timodel.Job{
+		ID:       1,
+		State:    timodel.JobStateSynced,
+		SchemaID: 1,
+		Type:     timodel.ActionCreateSchema,
+		BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 1,
+			DBInfo: &timodel.DBInfo{
+				ID:    1,
+				Name:  timodel.NewCIStr("testDB"),
+				State: timodel.StatePublic,
+			}, FinishedTS: 10},
+		Query: "create database testDB",
+	}
+	createTable1 := buildCreateTableJob(2, 1, 2, "testTBL1", 20)
+	createTable2 := buildCreateTableJob(3, 1, 3, "testTBL2", 40)
+	createTable2.BinlogInfo.SchemaVersion = 3
+
+	snap, err := NewStorageBuilder([]*timodel.Job{createDB, createTable1, createTable2}, nil).Snapshot(30)
+	c.Assert(err, IsNil)
+	c.Assert(snap.Ts, Equals, uint64(30))
+	c.Assert(snap.SchemaVersion, Equals, int64(2))
+
+	storage, err := NewSchemaSnapshotStorage(&util.SchemaSnapshotConfig{
+		Storage: util.SchemaSnapshotStorageFile,
+		Dir:     c.MkDir(),
+	}, kv.CDCEtcdClient{}, "cf")
+	c.Assert(err, IsNil)
+	c.Assert(storage.Put(ctx, snap), IsNil)
+	snap, err = storage.Get(ctx, 50)
+	c.Assert(err, IsNil)
+
+	// the jobs before the snapshot are skipped
+	builder := NewStorageBuilderFromSnapshot(snap, []*timodel.Job{createTable1, createTable2}, nil)
+	c.Assert(builder.GetResolvedTs(), Equals, uint64(40))
+	schemaStorage, err := builder.Build(40)
+	c.Assert(err, IsNil)
+	name, ok := schemaStorage.GetTableNameByID(2)
+	c.Assert(ok, IsTrue)
+	c.Assert(name, DeepEquals, TableName{Schema: "testDB", Table: "testTBL1"})
+	name, ok = schemaStorage.GetTableNameByID(3)
+	c.Assert(ok, IsTrue)
+	c.Assert(name, DeepEquals, TableName{Schema: "testDB", Table: "testTBL2"})
+
+	schemaStorage, err = builder.Build(30)
+	c.Assert(err, IsNil)
+	_, ok = schemaStorage.GetTableNameByID(3)
+	c.Assert(ok, IsFalse)
+
+	// the shared schema store built from the snapshot can't serve the users before the snapshot
+	store := NewSchemaStoreFromSnapshot(snap, []*timodel.Job{createTable1, createTable2}, 50, nil)
+	c.Assert(store.ResolvedTs(), Equals, uint64(50))
+	c.Assert(store.Register("p1", 20), IsFalse)
+	c.Assert(store.Register("p2", 30), IsTrue)
+	schemaStorage, err = store.GetSnapshot(45)
+	c.Assert(err, IsNil)
+	_, ok = schemaStorage.GetTableNameByID(3)
+	c.Assert(ok, IsTrue)
+	_, err = store.GetSnapshot(20)
+	c.Assert(err, NotNil)
+}
@@ -17,6 +17,7 @@ import (
 	"container/list"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync/atomic"
 
 	"github.com/pingcap/errors"
@@ -24,6 +25,7 @@ import (
 	timodel "github.com/pingcap/parser/model"
 	"github.com/pingcap/parser/mysql"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/pingcap/tidb/util/rowcodec"
 	"go.uber.org/zap"
 )
@@ -37,6 +39,8 @@ type Storage struct {
 
 	schemas map[int64]*timodel.DBInfo
 	tables  map[int64]*TableInfo
+	// partitionTables maps the physical IDs of the partitions to their partitioned tables
+	partitionTables map[int64]*TableInfo
 
 	truncateTableID   map[int64]struct{}
 	ineligibleTableID map[int64]struct{}
@@ -126,6 +130,7 @@ func WrapTableInfo(info *timodel.TableInfo) *TableInfo {
 			ti.IndieMarkCol = info.Name.O
 		}
 	}
+	ti.initRowColInfos()
 
 	return ti
 }
@@ -150,9 +155,12 @@ func (ti *TableInfo) GetIndexInfo(indexID int64) (info *timodel.IndexInfo, exist
 
 // GetRowColInfos returns all column infos for rowcodec
 func (ti *TableInfo) GetRowColInfos() (int64, []rowcodec.ColInfo) {
-	if len(ti.rowColInfos) != 0 {
-		return ti.handleColID, ti.rowColInfos
-	}
+	return ti.handleColID, ti.rowColInfos
+}
+
+// initRowColInfos initializes the column infos for rowcodec, they are initialized when the
+// TableInfo is created, so the rows can be decoded with the TableInfo concurrently
+func (ti *TableInfo) initRowColInfos() {
 	handleColID := int64(-1)
 	reqCols := make([]rowcodec.ColInfo, len(ti.Columns))
 	for i, col := range ti.Columns {
@@ -172,7 +180,6 @@ func (ti *TableInfo) GetRowColInfos() (int64, []rowcodec.ColInfo) {
 	}
 	ti.rowColInfos = reqCols
 	ti.handleColID = handleColID
-	return ti.handleColID, ti.rowColInfos
 }
 
 // IsColWritable returns is the col is writeable
@@ -208,6 +215,50 @@ func (ti *TableInfo) GetUniqueKeys() [][]string {
 	return uniqueKeys
 }
 
+// ToTableSchema returns the structured schema of the table, the columns are the writable ones
+// which are carried by the row changed events, and the version is the time the table is updated
+func (ti *TableInfo) ToTableSchema() *model.TableSchema {
+	schema := &model.TableSchema{
+		ID:      ti.ID,
+		Version: ti.UpdateTS,
+	}
+	for _, col := range ti.Columns {
+		if !ti.IsColWritable(col) {
+			continue
+		}
+		schema.Columns = append(schema.Columns, &model.ColumnSchema{
+			Name:     col.Name.O,
+			Type:     col.Tp,
+			FullType: col.FieldType.InfoSchemaStr(),
+			Nullable: !mysql.HasNotNullFlag(col.Flag),
+		})
+		if ti.PKIsHandle && mysql.HasPriKeyFlag(col.Flag) {
+			schema.Keys = append(schema.Keys, &model.KeySchema{
+				Name:    "PRIMARY",
+				Primary: true,
+				Unique:  true,
+				Columns: []string{col.Name.O},
+			})
+		}
+	}
+	for _, idx := range ti.Indices {
+		if idx.State != timodel.StatePublic {
+			continue
+		}
+		key := &model.KeySchema{
+			Name:    idx.Name.O,
+			Primary: idx.Primary,
+			Unique:  idx.Primary || idx.Unique,
+			Columns: make([]string, 0, len(idx.Columns)),
+		}
+		for _, col := range idx.Columns {
+			key.Columns = append(key.Columns, col.Name.O)
+		}
+		schema.Keys = append(schema.Keys, key)
+	}
+	return schema
+}
+
 // IsColumnUnique returns whether the column is unique
 func (ti *TableInfo) IsColumnUnique(colID int64) bool {
 	_, exist := ti.UniqueColumns[colID]
@@ -235,6 +286,30 @@ func (ti *TableInfo) IsIndexUnique(indexInfo *timodel.IndexInfo) bool {
 	return false
 }
 
+// GetPhysicalTableIDs returns the IDs of the partitions if the table is partitioned,
+// otherwise it returns the ID of the table, the rows are stored with these IDs
+func (ti *TableInfo) GetPhysicalTableIDs() []int64 {
+	pi := ti.GetPartitionInfo()
+	if pi == nil {
+		return []int64{ti.ID}
+	}
+	ids := make([]int64, 0, len(pi.Definitions))
+	for _, def := range pi.Definitions {
+		ids = append(ids, def.ID)
+	}
+	return ids
+}
+
+// IsPhysicalTableID returns true if the rows of the table are stored with the physical table ID
+func (ti *TableInfo) IsPhysicalTableID(id int64) bool {
+	for _, physicalID := range ti.GetPhysicalTableIDs() {
+		if physicalID == id {
+			return true
+		}
+	}
+	return false
+}
+
 // Clone clones the TableInfo
 func (ti *TableInfo) Clone() *TableInfo {
 	return WrapTableInfo(ti.TableInfo.Clone())
@@ -262,6 +337,7 @@ func NewSingleStorage() *Storage {
 	s.schemas = make(map[int64]*timodel.DBInfo)
 	s.schemaNameToID = make(map[string]int64)
 	s.tables = make(map[int64]*TableInfo)
+	s.partitionTables = make(map[int64]*TableInfo)
 
 	return s
 }
@@ -291,8 +367,12 @@ func (s *Storage) SchemaMetaVersion() int64 {
 	return s.schemaMetaVersion
 }
 
-// GetTableNameByID looks up a TableName with the given table id
+// GetTableNameByID looks up a TableName with the given table id,
+// the name of the partitioned table is returned if the id is a partition ID
 func (s *Storage) GetTableNameByID(id int64) (TableName, bool) {
+	if table, ok := s.partitionTables[id]; ok {
+		id = table.ID
+	}
 	name, ok := s.tableIDToName[id]
 	return name, ok
 }
@@ -324,7 +404,7 @@ func (s *Storage) SchemaByID(id int64) (val *timodel.DBInfo, ok bool) {
 
 // SchemaByTableID returns the schema ID by table ID
 func (s *Storage) SchemaByTableID(tableID int64) (*timodel.DBInfo, bool) {
-	tn, ok := s.tableIDToName[tableID]
+	tn, ok := s.GetTableNameByID(tableID)
 	if !ok {
 		return nil, false
 	}
@@ -335,9 +415,13 @@ func (s *Storage) SchemaByTableID(tableID int64) (*timodel.DBInfo, bool) {
 	return s.SchemaByID(schemaID)
 }
 
-// TableByID returns the TableInfo by table id
+// TableByID returns the TableInfo by table id,
+// the partitioned table is returned if the id is a partition ID
 func (s *Storage) TableByID(id int64) (val *TableInfo, ok bool) {
 	val, ok = s.tables[id]
+	if !ok {
+		val, ok = s.partitionTables[id]
+	}
 	return
 }
 
@@ -349,6 +433,9 @@ func (s *Storage) DropSchema(id int64) (string, error) {
 	}
 
 	for _, table := range schema.Tables {
+		if tbl, ok := s.tables[table.ID]; ok {
+			s.removePartitions(tbl)
+		}
 		delete(s.tables, table.ID)
 		tableName := s.tableIDToName[table.ID]
 		delete(s.tableIDToName, table.ID)
@@ -385,6 +472,7 @@ func (s *Storage) DropTable(id int64) (string, error) {
 		return "", errors.Trace(err)
 	}
 
+	s.removePartitions(table)
 	delete(s.tables, id)
 	tableName := s.tableIDToName[id]
 	delete(s.tableIDToName, id)
@@ -405,10 +493,8 @@ func (s *Storage) CreateTable(schema *timodel.DBInfo, table *timodel.TableInfo)
 	schema.Tables = append(schema.Tables, table)
 	tbl := WrapTableInfo(table)
 	s.tables[table.ID] = tbl
-	if !tbl.ExistTableUniqueColumn() {
-		log.Warn("this table is not eligible to replicate", zap.String("tableName", table.Name.O), zap.Int64("tableID", table.ID))
-		s.ineligibleTableID[table.ID] = struct{}{}
-	}
+	s.addPartitions(tbl)
+	s.checkEligible(tbl)
 	s.tableIDToName[table.ID] = TableName{Schema: schema.Name.O, Table: table.Name.O}
 	s.tableNameToID[s.tableIDToName[table.ID]] = table.ID
 
@@ -418,17 +504,52 @@ func (s *Storage) CreateTable(schema *timodel.DBInfo, table *timodel.TableInfo)
 
 // ReplaceTable replace the table by new tableInfo
 func (s *Storage) ReplaceTable(table *timodel.TableInfo) error {
-	_, ok := s.tables[table.ID]
+	oldTable, ok := s.tables[table.ID]
 	if !ok {
 		return errors.NotFoundf("table %s(%d)", table.Name, table.ID)
 	}
 	tbl := WrapTableInfo(table)
+	s.removePartitions(oldTable)
 	s.tables[table.ID] = tbl
-	if !tbl.ExistTableUniqueColumn() {
+	s.addPartitions(tbl)
+	// the rows of the dropped or truncated partitions are skipped
+	for _, id := range oldTable.GetPhysicalTableIDs() {
+		if id != table.ID && !tbl.IsPhysicalTableID(id) {
+			s.truncateTableID[id] = struct{}{}
+		}
+	}
+	s.checkEligible(tbl)
+	return nil
+}
+
+// checkEligible marks the table without a primary key or unique index as ineligible, the views and sequences
+// are not checked since they have no rows.
+func (s *Storage) checkEligible(table *TableInfo) {
+	if table.IsView() || table.IsSequence() {
+		return
+	}
+	if !table.ExistTableUniqueColumn() {
 		log.Warn("this table is not eligible to replicate", zap.String("tableName", table.Name.O), zap.Int64("tableID", table.ID))
 		s.ineligibleTableID[table.ID] = struct{}{}
 	}
-	return nil
+}
+
+func (s *Storage) addPartitions(table *TableInfo) {
+	if table.GetPartitionInfo() == nil {
+		return
+	}
+	for _, id := range table.GetPhysicalTableIDs() {
+		s.partitionTables[id] = table
+	}
+}
+
+func (s *Storage) removePartitions(table *TableInfo) {
+	if table.GetPartitionInfo() == nil {
+		return
+	}
+	for _, id := range table.GetPhysicalTableIDs() {
+		delete(s.partitionTables, id)
+	}
 }
 
 func (s *Storage) removeTable(tableID int64) error {
@@ -452,7 +573,12 @@ func (s *Storage) HandlePreviousDDLJobIfNeed(commitTs uint64) error {
 	if commitTs > atomic.LoadUint64(s.resolvedTs) {
 		return model.ErrUnresolved
 	}
-	currentJob, jobs := s.jobList.FetchNextJobs(s.currentJob, commitTs)
+	return errors.Trace(s.handleJobs(commitTs))
+}
+
+// handleJobs applies the jobs in the job list with FinishedTS less or equals `ts`.
+func (s *Storage) handleJobs(ts uint64) error {
+	currentJob, jobs := s.jobList.FetchNextJobs(s.currentJob, ts)
 	for _, job := range jobs {
 		if SkipJob(job) {
 			log.Info("skip DDL job because the job isn't synced and done", zap.Stringer("job", job))
@@ -551,7 +677,7 @@ func (s *Storage) HandleDDL(job *timodel.Job) (schemaName string, tableName stri
 		schemaName = schema.Name.O
 		tableName = table.Name.O
 
-	case timodel.ActionCreateTable, timodel.ActionCreateView, timodel.ActionRecoverTable:
+	case timodel.ActionCreateTable, timodel.ActionCreateView, timodel.ActionCreateSequence, timodel.ActionRecoverTable:
 		table := job.BinlogInfo.TableInfo.Clone()
 		if table == nil {
 			return "", "", "", errors.NotFoundf("table %d", job.TableID)
@@ -572,7 +698,7 @@ func (s *Storage) HandleDDL(job *timodel.Job) (schemaName string, tableName stri
 		schemaName = schema.Name.O
 		tableName = table.Name.O
 
-	case timodel.ActionDropTable, timodel.ActionDropView:
+	case timodel.ActionDropTable, timodel.ActionDropView, timodel.ActionDropSequence:
 		schema, ok := s.SchemaByID(job.SchemaID)
 		if !ok {
 			return "", "", "", errors.NotFoundf("schema %d", job.SchemaID)
@@ -593,6 +719,12 @@ func (s *Storage) HandleDDL(job *timodel.Job) (schemaName string, tableName stri
 			return "", "", "", errors.NotFoundf("schema %d", job.SchemaID)
 		}
 
+		// the rows of the partitions of the old table are skipped too
+		if oldTable, ok := s.tables[job.TableID]; ok && oldTable.GetPartitionInfo() != nil {
+			for _, id := range oldTable.GetPhysicalTableIDs() {
+				s.truncateTableID[id] = struct{}{}
+			}
+		}
 		// job.TableID is the old table id, different from table.ID
 		_, err := s.DropTable(job.TableID)
 		if err != nil {
@@ -644,17 +776,33 @@ func (s *Storage) HandleDDL(job *timodel.Job) (schemaName string, tableName stri
 	return
 }
 
-// CloneTables return a clone of the existing tables.
+// CloneTables return a clone of the existing tables, the views and sequences are not included.
 func (s *Storage) CloneTables() map[uint64]TableName {
 	mp := make(map[uint64]TableName, len(s.tableIDToName))
 
 	for id, table := range s.tableIDToName {
+		if tbl, ok := s.tables[id]; ok && (tbl.IsView() || tbl.IsSequence()) {
+			continue
+		}
 		mp[uint64(id)] = table
 	}
 
 	return mp
 }
 
+// CloneSequences return a clone of the existing sequences.
+func (s *Storage) CloneSequences() map[uint64]TableName {
+	mp := make(map[uint64]TableName)
+
+	for id, table := range s.tables {
+		if table.IsSequence() {
+			mp[uint64(id)] = s.tableIDToName[id]
+		}
+	}
+
+	return mp
+}
+
 // Clone clones Storage
 func (s *Storage) Clone() *Storage {
 	n := &Storage{
@@ -662,8 +810,9 @@ func (s *Storage) Clone() *Storage {
 		tableNameToID:  make(map[TableName]int64),
 		schemaNameToID: make(map[string]int64),
 
-		schemas: make(map[int64]*timodel.DBInfo),
-		tables:  make(map[int64]*TableInfo),
+		schemas:         make(map[int64]*timodel.DBInfo),
+		tables:          make(map[int64]*TableInfo),
+		partitionTables: make(map[int64]*TableInfo),
 
 		truncateTableID:     make(map[int64]struct{}),
 		ineligibleTableID:   make(map[int64]struct{}),
@@ -682,7 +831,9 @@ func (s *Storage) Clone() *Storage {
 		n.schemas[k] = v.Clone()
 	}
 	for k, v := range s.tables {
-		n.tables[k] = v.Clone()
+		table := v.Clone()
+		n.tables[k] = table
+		n.addPartitions(table)
 	}
 	for k, v := range s.truncateTableID {
 		n.truncateTableID[k] = v
@@ -702,6 +853,59 @@ func (s *Storage) Clone() *Storage {
 	return n
 }
 
+// snapshotJobs returns the jobs which create all the schemas and tables in the storage,
+// the last job is finished at ts.
+func (s *Storage) snapshotJobs(ts uint64) []*timodel.Job {
+	jobs := make([]*timodel.Job, 0, len(s.schemas)+len(s.tables))
+	for _, schema := range s.schemas {
+		db := schema.Clone()
+		db.Tables = nil
+		jobs = append(jobs, &timodel.Job{
+			Type:     timodel.ActionCreateSchema,
+			SchemaID: db.ID,
+			State:    timodel.JobStateSynced,
+			Query:    fmt.Sprintf("CREATE DATABASE %s", util.QuoteName(db.Name.O)),
+			BinlogInfo: &timodel.HistoryInfo{
+				SchemaVersion: s.currentVersion,
+				DBInfo:        db,
+			},
+		})
+	}
+	for id, table := range s.tables {
+		schema, ok := s.SchemaByTableID(id)
+		if !ok {
+			continue
+		}
+		jobs = append(jobs, &timodel.Job{
+			Type:     timodel.ActionCreateTable,
+			SchemaID: schema.ID,
+			TableID:  id,
+			State:    timodel.JobStateSynced,
+			Query:    fmt.Sprintf("CREATE TABLE %s", util.QuoteSchema(schema.Name.O, table.Name.O)),
+			BinlogInfo: &timodel.HistoryInfo{
+				SchemaVersion: s.currentVersion,
+				TableInfo:     table.TableInfo.Clone(),
+			},
+		})
+	}
+	// the schemas are created before the tables, and they are sorted by ID for a stable output
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if jobs[i].Type != jobs[j].Type {
+			return jobs[i].Type == timodel.ActionCreateSchema
+		}
+		if jobs[i].Type == timodel.ActionCreateSchema {
+			return jobs[i].SchemaID < jobs[j].SchemaID
+		}
+		return jobs[i].TableID < jobs[j].TableID
+	})
+	// a job is skipped if its finished ts is not greater than the last handled one,
+	// so the finished ts of the jobs are increased one by one up to ts
+	for i, job := range jobs {
+		job.BinlogInfo.FinishedTS = ts - uint64(len(jobs)-1-i)
+	}
+	return jobs
+}
+
 // IsTruncateTableID returns true if the table id have been truncated by truncate table DDL
 func (s *Storage) IsTruncateTableID(id int64) bool {
 	_, ok := s.truncateTableID[id]
@@ -710,6 +914,9 @@ func (s *Storage) IsTruncateTableID(id int64) bool {
 
 // IsIneligibleTableID returns true if the table is ineligible
 func (s *Storage) IsIneligibleTableID(id int64) bool {
+	if table, ok := s.partitionTables[id]; ok {
+		id = table.ID
+	}
 	_, ok := s.ineligibleTableID[id]
 	return ok
 }
@@ -22,6 +22,7 @@ import (
 	"github.com/pingcap/errors"
 	timodel "github.com/pingcap/parser/model"
 	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/tidb/types"
 )
 
@@ -386,6 +387,59 @@ func testDoDDLAndCheck(c *C, schema *Storage, job *timodel.Job, isErr bool, sql
 	c.Assert(tableName, Equals, expectedTable)
 }
 
+func (t *schemaSuite) TestPartitionTable(c *C) {
+	schema := NewSingleStorage()
+	dbInfo := &timodel.DBInfo{ID: 2, Name: timodel.NewCIStr("test"), State: timodel.StatePublic}
+	newTableInfo := func(partitionIDs ...int64) *timodel.TableInfo {
+		pi := &timodel.PartitionInfo{Type: timodel.PartitionTypeHash, Enable: true}
+		for _, id := range partitionIDs {
+			pi.Definitions = append(pi.Definitions, timodel.PartitionDefinition{ID: id, Name: timodel.NewCIStr(fmt.Sprintf("p%d", id))})
+		}
+		return &timodel.TableInfo{ID: 10, Name: timodel.NewCIStr("t"), State: timodel.StatePublic, Partition: pi}
+	}
+	jobs := []*timodel.Job{
+		{ID: 1, SchemaID: 2, Type: timodel.ActionCreateSchema, Query: "create database test",
+			BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 1, DBInfo: dbInfo}},
+		{ID: 2, SchemaID: 2, TableID: 10, Type: timodel.ActionCreateTable, Query: "create table t",
+			BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 2, TableInfo: newTableInfo(11, 12)}},
+		{ID: 3, SchemaID: 2, TableID: 10, Type: timodel.ActionTruncateTablePartition, Query: "alter table t truncate partition p12",
+			BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 3, TableInfo: newTableInfo(11, 13)}},
+	}
+	for _, job := range jobs {
+		job.State = timodel.JobStateSynced
+		_, _, _, err := schema.HandleDDL(job)
+		c.Assert(err, IsNil)
+	}
+
+	for _, storage := range []*Storage{schema, schema.Clone()} {
+		for _, id := range []int64{10, 11, 13} {
+			table, ok := storage.TableByID(id)
+			c.Assert(ok, IsTrue)
+			c.Assert(table.ID, Equals, int64(10))
+			c.Assert(table.IsPhysicalTableID(id), Equals, id != 10)
+			name, ok := storage.GetTableNameByID(id)
+			c.Assert(ok, IsTrue)
+			c.Assert(name, Equals, TableName{Schema: "test", Table: "t"})
+			db, ok := storage.SchemaByTableID(id)
+			c.Assert(ok, IsTrue)
+			c.Assert(db.ID, Equals, int64(2))
+		}
+		table, _ := storage.TableByID(10)
+		c.Assert(table.GetPhysicalTableIDs(), DeepEquals, []int64{11, 13})
+		// the rows of the truncated partition are skipped
+		_, ok := storage.TableByID(12)
+		c.Assert(ok, IsFalse)
+		c.Assert(storage.IsTruncateTableID(12), IsTrue)
+	}
+
+	_, err := schema.DropTable(10)
+	c.Assert(err, IsNil)
+	_, ok := schema.TableByID(11)
+	c.Assert(ok, IsFalse)
+	_, ok = schema.GetTableNameByID(13)
+	c.Assert(ok, IsFalse)
+}
+
 type getUniqueKeysSuite struct{}
 
 var _ = Suite(&getUniqueKeysSuite{})
@@ -470,3 +524,61 @@ func (s *getUniqueKeysSuite) TestPKShouldBeInTheFirstPlaceWhenPKIsHandle(c *C) {
 		{"uid"}, {"job"},
 	})
 }
+
+type tableSchemaSuite struct{}
+
+var _ = Suite(&tableSchemaSuite{})
+
+func (s *tableSchemaSuite) TestToTableSchema(c *C) {
+	newColumn := func(id int64, name string, tp byte, flen int, flag uint) *timodel.ColumnInfo {
+		return &timodel.ColumnInfo{
+			ID:        id,
+			Name:      timodel.NewCIStr(name),
+			Offset:    int(id - 1),
+			State:     timodel.StatePublic,
+			FieldType: parser_types.FieldType{Tp: tp, Flen: flen, Flag: flag},
+		}
+	}
+	generated := newColumn(4, "g", mysql.TypeLonglong, 20, 0)
+	generated.GeneratedExprString = "`c` + 1"
+	t := &timodel.TableInfo{
+		ID:       50,
+		Name:     timodel.NewCIStr("t1"),
+		UpdateTS: 415926,
+		Columns: []*timodel.ColumnInfo{
+			newColumn(1, "id", mysql.TypeLonglong, 20, mysql.PriKeyFlag|mysql.NotNullFlag),
+			newColumn(2, "name", mysql.TypeVarchar, 20, mysql.NotNullFlag),
+			newColumn(3, "c", mysql.TypeLong, 10, mysql.UnsignedFlag),
+			generated,
+		},
+		Indices: []*timodel.IndexInfo{{
+			Name:    timodel.NewCIStr("uk_name"),
+			State:   timodel.StatePublic,
+			Columns: []*timodel.IndexColumn{{Name: timodel.NewCIStr("name"), Offset: 1}},
+			Unique:  true,
+		}, {
+			Name:    timodel.NewCIStr("idx_c_name"),
+			State:   timodel.StatePublic,
+			Columns: []*timodel.IndexColumn{{Name: timodel.NewCIStr("c"), Offset: 2}, {Name: timodel.NewCIStr("name"), Offset: 1}},
+		}, {
+			Name:    timodel.NewCIStr("idx_adding"),
+			State:   timodel.StateWriteReorganization,
+			Columns: []*timodel.IndexColumn{{Name: timodel.NewCIStr("g"), Offset: 3}},
+		}},
+		PKIsHandle: true,
+	}
+	c.Assert(WrapTableInfo(t).ToTableSchema(), DeepEquals, &model.TableSchema{
+		ID:      50,
+		Version: 415926,
+		Columns: []*model.ColumnSchema{
+			{Name: "id", Type: mysql.TypeLonglong, FullType: "bigint(20)", Nullable: false},
+			{Name: "name", Type: mysql.TypeVarchar, FullType: "varchar(20)", Nullable: false},
+			{Name: "c", Type: mysql.TypeLong, FullType: "int(10) unsigned", Nullable: true},
+		},
+		Keys: []*model.KeySchema{
+			{Name: "PRIMARY", Primary: true, Unique: true, Columns: []string{"id"}},
+			{Name: "uk_name", Unique: true, Columns: []string{"name"}},
+			{Name: "idx_c_name", Columns: []string{"c", "name"}},
+		},
+	})
+}
@@ -0,0 +1,382 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cenkalti/backoff"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/retry"
+	"go.uber.org/zap"
+)
+
+// SchemaGetter returns the schemas at a ts to the mounters, the returned Storage must not be modified.
+type SchemaGetter interface {
+	// GetSnapshot returns the schemas at ts, model.ErrUnresolved is returned
+	// if the DDL jobs finished before ts are not all received.
+	GetSnapshot(ts uint64) (*Storage, error)
+}
+
+// GetSnapshot implements the SchemaGetter interface, the Storage built by a StorageBuilder
+// handles the DDL jobs before ts and returns itself, so it's only used by one mounter.
+func (s *Storage) GetSnapshot(ts uint64) (*Storage, error) {
+	if err := s.HandlePreviousDDLJobIfNeed(ts); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SchemaStore keeps the schemas of multiple versions, which are shared by the mounters of the tables
+// and the changefeeds. A version is created when it's first queried by copying the maps of the previous
+// version and handling the DDL jobs between them, the schemas and tables which are not changed by the
+// jobs are shared by the versions, so a version is never modified after it's created.
+type SchemaStore struct {
+	resolvedTs uint64
+	ddlEventCh <-chan *model.RawKVEntry
+
+	mu sync.RWMutex
+	// jobs are sorted by the finished ts, the jobs handled by the first version are removed
+	jobs []*timodel.Job
+	// versions are sorted by the finished ts of the last jobs handled by them
+	versions []*Storage
+	gcTs     uint64
+	// usersTs are the ts of the users of the store, such as the processors,
+	// the versions before the minimal one are removed
+	usersTs map[string]uint64
+	// err is returned to the queries after the store is closed
+	err error
+}
+
+// NewSchemaStore creates a SchemaStore from the history DDL jobs, which are all the jobs finished
+// before resolvedTs. The newer jobs are received from ddlEventCh.
+func NewSchemaStore(historyDDL []*timodel.Job, resolvedTs uint64, ddlEventCh <-chan *model.RawKVEntry) *SchemaStore {
+	s := &SchemaStore{
+		ddlEventCh: ddlEventCh,
+		usersTs:    make(map[string]uint64),
+	}
+	sort.Slice(historyDDL, func(i, j int) bool {
+		return historyDDL[i].BinlogInfo.FinishedTS < historyDDL[j].BinlogInfo.FinishedTS
+	})
+	for _, job := range historyDDL {
+		s.appendJob(job)
+	}
+	if len(s.jobs) > 0 && resolvedTs < s.jobs[len(s.jobs)-1].BinlogInfo.FinishedTS {
+		resolvedTs = s.jobs[len(s.jobs)-1].BinlogInfo.FinishedTS
+	}
+	s.resolvedTs = resolvedTs
+
+	base := NewSingleStorage()
+	base.resolvedTs = &s.resolvedTs
+	s.versions = []*Storage{base}
+	return s
+}
+
+// NewSchemaStoreFromSnapshot creates a SchemaStore from the schema snapshot, only the jobs finished after
+// the snapshot are handled. The schemas before the snapshot can't be queried, so the users registered
+// before the ts of the snapshot are rejected.
+func NewSchemaStoreFromSnapshot(
+	snap *model.SchemaSnapshot, jobs []*timodel.Job, resolvedTs uint64, ddlEventCh <-chan *model.RawKVEntry,
+) *SchemaStore {
+	s := NewSchemaStore(snapshotHistoryJobs(snap, jobs), resolvedTs, ddlEventCh)
+	s.gcTs = snap.Ts
+	return s
+}
+
+// appendJob appends a job finished after the last one, the caller must hold the lock or own the store
+func (s *SchemaStore) appendJob(job *timodel.Job) {
+	if SkipJob(job) {
+		log.Info("skip DDL job because the job isn't synced and done", zap.Stringer("job", job))
+		return
+	}
+	if len(s.jobs) > 0 && job.BinlogInfo.FinishedTS <= s.jobs[len(s.jobs)-1].BinlogInfo.FinishedTS {
+		log.Debug("skip DDL job because the job is already received", zap.Stringer("job", job))
+		return
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// Run receives the DDL jobs from the DDL puller
+func (s *SchemaStore) Run(ctx context.Context) error {
+	for {
+		var rawKV *model.RawKVEntry
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case rawKV = <-s.ddlEventCh:
+		}
+		if rawKV == nil {
+			return errors.Trace(ctx.Err())
+		}
+		if rawKV.Ts < s.ResolvedTs() {
+			continue
+		}
+
+		if rawKV.OpType == model.OpTypeResolved {
+			atomic.StoreUint64(&s.resolvedTs, rawKV.Ts)
+			continue
+		}
+
+		job, err := UnmarshalDDL(rawKV)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if job == nil {
+			continue
+		}
+		s.mu.Lock()
+		s.appendJob(job)
+		s.mu.Unlock()
+		atomic.StoreUint64(&s.resolvedTs, rawKV.Ts)
+	}
+}
+
+// ResolvedTs returns the ts before which the DDL jobs are all received
+func (s *SchemaStore) ResolvedTs() uint64 {
+	return atomic.LoadUint64(&s.resolvedTs)
+}
+
+// Close closes the store if the DDL jobs can't be received any more, err is returned to the following queries
+func (s *SchemaStore) Close(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// GetSnapshot implements the SchemaGetter interface
+func (s *SchemaStore) GetSnapshot(ts uint64) (*Storage, error) {
+	if ts > s.ResolvedTs() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		if s.err != nil {
+			return nil, errors.Annotate(s.err, "the schema store is closed")
+		}
+		return nil, model.ErrUnresolved
+	}
+	return s.snapshot(ts)
+}
+
+// WaitSnapshot returns the schemas at ts, it retries if the DDL jobs before ts are not all received
+func (s *SchemaStore) WaitSnapshot(ts uint64) (*Storage, error) {
+	var snap *Storage
+	err := retry.Run(func() error {
+		var err error
+		snap, err = s.GetSnapshot(ts)
+		if errors.Cause(err) != model.ErrUnresolved {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, 5)
+	return snap, errors.Trace(err)
+}
+
+// Snapshot returns the schema snapshot at ts, the history jobs are all received
+// when the store is created, so it doesn't wait for the DDL puller to resolve ts.
+func (s *SchemaStore) Snapshot(ts uint64) (*model.SchemaSnapshot, error) {
+	storage, err := s.snapshot(ts)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &model.SchemaSnapshot{
+		Ts:            ts,
+		SchemaVersion: storage.currentVersion,
+		Jobs:          storage.snapshotJobs(ts),
+	}, nil
+}
+
+// SnapshotJobs returns the jobs which create the schemas and tables at ts, the last one is finished at ts.
+func (s *SchemaStore) SnapshotJobs(ts uint64) ([]*timodel.Job, error) {
+	snap, err := s.Snapshot(ts)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return snap.Jobs, nil
+}
+
+func (s *SchemaStore) snapshot(ts uint64) (*Storage, error) {
+	s.mu.RLock()
+	storage, ok, err := s.findVersion(ts)
+	s.mu.RUnlock()
+	if err != nil || ok {
+		return storage, errors.Trace(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	storage, ok, err = s.findVersion(ts)
+	if err != nil || ok {
+		return storage, errors.Trace(err)
+	}
+	return s.createVersion(storage, ts)
+}
+
+// findVersion returns the latest version before ts, ok is true if it's the version at ts
+func (s *SchemaStore) findVersion(ts uint64) (storage *Storage, ok bool, err error) {
+	if s.err != nil {
+		return nil, false, errors.Annotate(s.err, "the schema store is closed")
+	}
+	if ts < s.gcTs {
+		return nil, false, errors.Errorf("the schemas at %d are removed, the gc ts of the schema store is %d", ts, s.gcTs)
+	}
+	i := sort.Search(len(s.versions), func(i int) bool {
+		return s.versions[i].lastHandledTs > ts
+	})
+	storage = s.versions[i-1]
+	next := s.nextJob(storage.lastHandledTs)
+	return storage, next == len(s.jobs) || s.jobs[next].BinlogInfo.FinishedTS > ts, nil
+}
+
+// nextJob returns the index of the first job finished after ts
+func (s *SchemaStore) nextJob(ts uint64) int {
+	return sort.Search(len(s.jobs), func(i int) bool {
+		return s.jobs[i].BinlogInfo.FinishedTS > ts
+	})
+}
+
+// createVersion creates the version at ts from the previous version, the caller must hold the write lock
+func (s *SchemaStore) createVersion(prev *Storage, ts uint64) (*Storage, error) {
+	storage := prev.shallowClone()
+	for _, job := range s.jobs[s.nextJob(prev.lastHandledTs):] {
+		if job.BinlogInfo.FinishedTS > ts {
+			break
+		}
+		storage.copySchemasOnWrite(job)
+		if _, _, _, err := storage.HandleDDL(job); err != nil {
+			return nil, errors.Annotatef(err, "handle ddl job %v failed, the schema info: %s", job, storage)
+		}
+	}
+	i := sort.Search(len(s.versions), func(i int) bool {
+		return s.versions[i].lastHandledTs > storage.lastHandledTs
+	})
+	s.versions = append(s.versions, nil)
+	copy(s.versions[i+1:], s.versions[i:])
+	s.versions[i] = storage
+	return storage, nil
+}
+
+// Register registers the user which queries the schemas after ts, the versions needed by the
+// user are kept until it's unregistered. It returns false if the schemas at ts are removed.
+func (s *SchemaStore) Register(userID string, ts uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ts < s.gcTs {
+		return false
+	}
+	s.usersTs[userID] = ts
+	return true
+}
+
+// Unregister unregisters the user
+func (s *SchemaStore) Unregister(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.usersTs, userID)
+}
+
+// DoGC updates the ts of the user, and removes the versions and jobs which are not
+// needed by the registered users.
+func (s *SchemaStore) DoGC(userID string, ts uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.usersTs[userID]; ok {
+		s.usersTs[userID] = ts
+	}
+	gcTs := uint64(0)
+	for _, ts := range s.usersTs {
+		if gcTs == 0 || ts < gcTs {
+			gcTs = ts
+		}
+	}
+	if gcTs <= s.gcTs || gcTs > s.ResolvedTs() {
+		return nil
+	}
+
+	storage, ok, err := s.findVersion(gcTs)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !ok {
+		if storage, err = s.createVersion(storage, gcTs); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	i := sort.Search(len(s.versions), func(i int) bool {
+		return s.versions[i].lastHandledTs >= storage.lastHandledTs
+	})
+	s.versions = append([]*Storage(nil), s.versions[i:]...)
+	s.jobs = append([]*timodel.Job(nil), s.jobs[s.nextJob(storage.lastHandledTs):]...)
+	s.gcTs = gcTs
+	return nil
+}
+
+// shallowClone clones the Storage without cloning the schemas and tables, they're shared with s
+func (s *Storage) shallowClone() *Storage {
+	n := NewSingleStorage()
+	for k, v := range s.tableIDToName {
+		n.tableIDToName[k] = v
+	}
+	for k, v := range s.tableNameToID {
+		n.tableNameToID[k] = v
+	}
+	for k, v := range s.schemaNameToID {
+		n.schemaNameToID[k] = v
+	}
+	for k, v := range s.schemas {
+		n.schemas[k] = v
+	}
+	for k, v := range s.tables {
+		n.tables[k] = v
+	}
+	for k, v := range s.partitionTables {
+		n.partitionTables[k] = v
+	}
+	for k, v := range s.truncateTableID {
+		n.truncateTableID[k] = v
+	}
+	for k, v := range s.ineligibleTableID {
+		n.ineligibleTableID[k] = v
+	}
+	for k, v := range s.version2SchemaTable {
+		n.version2SchemaTable[k] = v
+	}
+	n.schemaMetaVersion = s.schemaMetaVersion
+	n.lastHandledTs = s.lastHandledTs
+	n.resolvedTs = s.resolvedTs
+	n.currentVersion = s.currentVersion
+	return n
+}
+
+// copySchemasOnWrite copies the schemas whose tables are changed by the job, so the schemas shared
+// with the other versions are not modified. The tables are replaced rather than modified by the jobs.
+func (s *Storage) copySchemasOnWrite(job *timodel.Job) {
+	ids := []int64{job.SchemaID}
+	if schema, ok := s.SchemaByTableID(job.TableID); ok {
+		ids = append(ids, schema.ID)
+	}
+	for _, id := range ids {
+		schema, ok := s.schemas[id]
+		if !ok {
+			continue
+		}
+		copied := *schema
+		copied.Tables = append([]*timodel.TableInfo(nil), schema.Tables...)
+		s.schemas[id] = &copied
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+type schemaStoreSuite struct{}
+
+var _ = Suite(&schemaStoreSuite{})
+
+func (s *schemaStoreSuite) TestMultiVersion(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	historyJobs := []*timodel.Job{{
+		ID:       1,
+		State:    timodel.JobStateSynced,
+		SchemaID: 1,
+		Type:     timodel.ActionCreateSchema,
+		BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 1,
+			DBInfo: &timodel.DBInfo{
+				ID:    1,
+				Name:  timodel.NewCIStr("testDB"),
+				State: timodel.StatePublic,
+			}, FinishedTS: 10},
+		Query: "create database testDB",
+	}, buildCreateTableJob(2, 1, 1, "testTBL1", 20)}
+	ddlEventCh := make(chan *model.RawKVEntry)
+	store := NewSchemaStore(historyJobs, 25, ddlEventCh)
+	c.Assert(store.ResolvedTs(), Equals, uint64(25))
+	go func() {
+		err := store.Run(ctx)
+		c.Assert(errors.Cause(err), Equals, context.Canceled)
+	}()
+
+	_, err := store.GetSnapshot(30)
+	c.Assert(errors.Cause(err), Equals, model.ErrUnresolved)
+	for i := 3; i < 10; i++ {
+		job := buildCreateTableJob(int64(i), 1, int64(i-1), fmt.Sprintf("testTBL%d", i-1), uint64(i*10))
+		ddlEventCh <- job2RawKvEntry(job)
+	}
+	ddlEventCh <- &model.RawKVEntry{OpType: model.OpTypeResolved, Ts: 100}
+	for retry := 0; retry < 100 && store.ResolvedTs() < 100; retry++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(store.ResolvedTs(), Equals, uint64(100))
+
+	// the versions are queried out of order
+	for _, i := range []int{5, 9, 3, 7, 4} {
+		storage, err := store.GetSnapshot(uint64(i*10 + 5))
+		c.Assert(err, IsNil)
+		for id := 1; id < 10; id++ {
+			_, ok := storage.TableByID(int64(id))
+			c.Assert(ok, Equals, id < i, Commentf("version %d, table %d", i, id))
+		}
+		schema, ok := storage.SchemaByID(1)
+		c.Assert(ok, IsTrue)
+		c.Assert(schema.Tables, HasLen, i-1)
+	}
+
+	// the tables not changed are shared by the versions created from each other
+	v5, err := store.GetSnapshot(55)
+	c.Assert(err, IsNil)
+	v9, err := store.GetSnapshot(99)
+	c.Assert(err, IsNil)
+	again, err := store.GetSnapshot(90)
+	c.Assert(err, IsNil)
+	c.Assert(again, Equals, v9)
+	table5, _ := v5.TableByID(1)
+	table9, _ := v9.TableByID(1)
+	c.Assert(table5, Equals, table9)
+
+	// the versions before the minimal ts of the users are removed
+	c.Assert(store.Register("p1", 45), IsTrue)
+	c.Assert(store.Register("p2", 65), IsTrue)
+	c.Assert(store.DoGC("p1", 55), IsNil)
+	_, err = store.GetSnapshot(45)
+	c.Assert(err, NotNil)
+	storage, err := store.GetSnapshot(55)
+	c.Assert(err, IsNil)
+	_, ok := storage.TableByID(4)
+	c.Assert(ok, IsTrue)
+	c.Assert(store.Register("p3", 50), IsFalse)
+	store.Unregister("p1")
+	c.Assert(store.DoGC("p2", 70), IsNil)
+	_, err = store.GetSnapshot(65)
+	c.Assert(err, NotNil)
+	storage, err = store.GetSnapshot(70)
+	c.Assert(err, IsNil)
+	_, ok = storage.TableByID(6)
+	c.Assert(ok, IsTrue)
+	_, ok = storage.TableByID(7)
+	c.Assert(ok, IsFalse)
+
+	snap, err := store.Snapshot(80)
+	c.Assert(err, IsNil)
+	c.Assert(snap.Jobs, HasLen, 8)
+
+	store.Close(errors.New("closed"))
+	_, err = store.GetSnapshot(200)
+	c.Assert(err, ErrorMatches, ".*closed.*")
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+	"sync/atomic"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/types"
+)
+
+type viewSequenceSuite struct{}
+
+var _ = Suite(&viewSequenceSuite{})
+
+func (s *viewSequenceSuite) TestMountWithViewsAndSequences(c *C) {
+	pk := &timodel.ColumnInfo{ID: 1, Name: timodel.NewCIStr("a"), Offset: 0, State: timodel.StatePublic}
+	pk.Tp = mysql.TypeLonglong
+	pk.Flag = mysql.PriKeyFlag
+	col := &timodel.ColumnInfo{ID: 2, Name: timodel.NewCIStr("b"), Offset: 1, State: timodel.StatePublic}
+	col.Tp = mysql.TypeLonglong
+	newJob := func(id int64, tp timodel.ActionType, table *timodel.TableInfo, ts uint64, query string) *timodel.Job {
+		return &timodel.Job{
+			ID:         id,
+			State:      timodel.JobStateSynced,
+			SchemaID:   1,
+			TableID:    table.ID,
+			Type:       tp,
+			BinlogInfo: &timodel.HistoryInfo{SchemaVersion: id, FinishedTS: ts, TableInfo: table},
+			Query:      query,
+		}
+	}
+	table := &timodel.TableInfo{
+		ID:         50,
+		Name:       timodel.NewCIStr("t1"),
+		State:      timodel.StatePublic,
+		PKIsHandle: true,
+		Columns:    []*timodel.ColumnInfo{pk, col},
+	}
+	view := &timodel.TableInfo{
+		ID:      51,
+		Name:    timodel.NewCIStr("v1"),
+		State:   timodel.StatePublic,
+		Columns: []*timodel.ColumnInfo{pk},
+		View:    &timodel.ViewInfo{SelectStmt: "SELECT a FROM test.t1"},
+	}
+	sequence := &timodel.TableInfo{
+		ID:       52,
+		Name:     timodel.NewCIStr("s1"),
+		State:    timodel.StatePublic,
+		Sequence: &timodel.SequenceInfo{Start: 1, Increment: 1, Cache: true, CacheValue: 1000},
+	}
+	altered := sequence.Clone()
+	altered.Sequence.Increment = 2
+	historyJobs := []*timodel.Job{{
+		ID:       1,
+		State:    timodel.JobStateSynced,
+		SchemaID: 1,
+		Type:     timodel.ActionCreateSchema,
+		BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 1,
+			DBInfo:     &timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test"), State: timodel.StatePublic},
+			FinishedTS: 10},
+		Query: "create database test",
+	},
+		newJob(2, timodel.ActionCreateTable, table, 20, "create table t1 (a bigint primary key, b bigint)"),
+		newJob(3, timodel.ActionCreateView, view, 30, "create view v1 as select a from t1"),
+		newJob(4, timodel.ActionCreateSequence, sequence, 40, "create sequence s1"),
+		newJob(5, timodel.ActionAlterSequence, altered, 50, "alter sequence s1 increment by 2"),
+	}
+
+	builder := NewStorageBuilder(historyJobs, nil)
+	atomic.StoreUint64(&builder.resolvedTs, 100)
+	storage, err := builder.Build(60)
+	c.Assert(err, IsNil)
+	// the views and sequences are not ineligible tables, and they're never scheduled
+	c.Assert(storage.IsIneligibleTableID(51), IsFalse)
+	c.Assert(storage.IsIneligibleTableID(52), IsFalse)
+	c.Assert(storage.CloneTables(), DeepEquals, map[uint64]TableName{50: {Schema: "test", Table: "t1"}})
+	c.Assert(storage.CloneSequences(), DeepEquals, map[uint64]TableName{52: {Schema: "test", Table: "s1"}})
+	seq, ok := storage.TableByID(52)
+	c.Assert(ok, IsTrue)
+	c.Assert(seq.Sequence.Increment, Equals, int64(2))
+	_, ok = storage.TableByID(51)
+	c.Assert(ok, IsTrue)
+
+	value, err := tablecodec.EncodeOldRow(&stmtctx.StatementContext{},
+		[]types.Datum{types.NewIntDatum(10)}, []int64{2}, nil, nil)
+	c.Assert(err, IsNil)
+	rawCh := make(chan *model.RawKVEntry, 2)
+	rawCh <- &model.RawKVEntry{OpType: model.OpTypePut, Key: tablecodec.EncodeRowKeyWithHandle(50, 1), Value: value, Ts: 60}
+	rawCh <- &model.RawKVEntry{OpType: model.OpTypeResolved, Ts: 70}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mounter := NewMounter(rawCh, storage, nil, false, nil)
+	go func() {
+		err := mounter.Run(ctx)
+		c.Assert(errors.Cause(err), Equals, context.Canceled)
+	}()
+	row := <-mounter.Output()
+	c.Assert(row.Ts, Equals, uint64(60))
+	c.Assert(row.Table, Equals, "t1")
+	c.Assert(row.Columns["a"].Value, Equals, int64(1))
+	c.Assert(row.Columns["b"].Value, Equals, int64(10))
+	row = <-mounter.Output()
+	c.Assert(row.Resolved, IsTrue)
+
+	// the view and the sequence are dropped
+	for _, job := range []*timodel.Job{
+		newJob(6, timodel.ActionDropView, view, 80, "drop view v1"),
+		newJob(7, timodel.ActionDropSequence, altered, 90, "drop sequence s1"),
+	} {
+		_, _, _, err := storage.HandleDDL(job)
+		c.Assert(err, IsNil)
+	}
+	_, ok = storage.TableByID(51)
+	c.Assert(ok, IsFalse)
+	c.Assert(storage.CloneSequences(), HasLen, 0)
+}
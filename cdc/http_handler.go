@@ -16,15 +16,20 @@ package cdc
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/tidb/store/tikv/oracle"
 	"go.etcd.io/etcd/clientv3/concurrency"
 )
 
 const (
 	opVarAdminJob     = "admin-job"
 	opVarChangefeedID = "cf-id"
+	opVarDDLTs        = "ddl-ts"
+	opVarDDLSkip      = "ddl-skip"
+	opVarDDLQuery     = "ddl-query"
 )
 
 type commonResp struct {
@@ -32,6 +37,14 @@ type commonResp struct {
 	Message string `json:"message"`
 }
 
+// ChangefeedResp holds the most common usage information for a changefeed
+type ChangefeedResp struct {
+	FeedState    string `json:"state"`
+	TSO          uint64 `json:"tso"`
+	Checkpoint   string `json:"checkpoint"`
+	AdminJobType string `json:"admin-job-type"`
+}
+
 func handleOwnerResp(w http.ResponseWriter, err error) {
 	if err != nil {
 		if errors.Cause(err) == concurrency.ErrElectionNotLeader {
@@ -73,6 +86,86 @@ func (s *Server) handleChangefeedAdmin(w http.ResponseWriter, req *http.Request)
 		CfID: req.Form.Get(opVarChangefeedID),
 		Type: model.AdminJobType(typ),
 	}
+	if job.Type == model.AdminOverrideDDL {
+		job.DDLOverride, err = s.parseDDLOverride(req, job.CfID)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
 	err = s.capture.ownerWorker.EnqueueJob(job)
 	handleOwnerResp(w, err)
 }
+
+// parseDDLOverride parses the override of the DDL and checks it against the stopped changefeed,
+// the owner checks it again when it handles the job.
+func (s *Server) parseDDLOverride(req *http.Request, changefeedID string) (*model.DDLOverride, error) {
+	tsStr := req.Form.Get(opVarDDLTs)
+	ts, err := strconv.ParseUint(tsStr, 10, 64)
+	if err != nil || ts == 0 {
+		return nil, errors.Errorf("invalid DDL ts: %s", tsStr)
+	}
+	override := &model.DDLOverride{Ts: ts, Query: req.Form.Get(opVarDDLQuery)}
+	if skipStr := req.Form.Get(opVarDDLSkip); len(skipStr) > 0 {
+		override.Skip, err = strconv.ParseBool(skipStr)
+		if err != nil {
+			return nil, errors.Errorf("invalid DDL skip: %s", skipStr)
+		}
+	}
+	if !override.Skip && len(strings.TrimSpace(override.Query)) == 0 {
+		return nil, errors.New("the query is empty, skip the DDL instead")
+	}
+	info, err := s.capture.etcdClient.GetChangeFeedInfo(req.Context(), changefeedID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	status, err := s.capture.etcdClient.GetChangeFeedStatus(req.Context(), changefeedID)
+	if err != nil && errors.Cause(err) != model.ErrChangeFeedNotExists {
+		return nil, errors.Trace(err)
+	}
+	if _, err := info.OverrideDDL(status, override); err != nil {
+		return nil, errors.Annotatef(err, "changefeed %s", changefeedID)
+	}
+	return override, nil
+}
+
+func (s *Server) handleChangefeedQuery(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusBadRequest, errors.New("this api only supports POST method"))
+		return
+	}
+	err := req.ParseForm()
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	changefeedID := req.Form.Get(opVarChangefeedID)
+	if len(changefeedID) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid changefeed id"))
+		return
+	}
+	cf, err := s.capture.etcdClient.GetChangeFeedInfo(req.Context(), changefeedID)
+	if err != nil {
+		if errors.Cause(err) == model.ErrChangeFeedNotExists {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeInternalServerError(w, err)
+		return
+	}
+	resp := &ChangefeedResp{
+		FeedState:    string(cf.GetState()),
+		AdminJobType: cf.AdminJobType.String(),
+	}
+	status, err := s.capture.etcdClient.GetChangeFeedStatus(req.Context(), changefeedID)
+	if err != nil && errors.Cause(err) != model.ErrChangeFeedNotExists {
+		writeInternalServerError(w, err)
+		return
+	}
+	if status != nil {
+		resp.TSO = status.CheckpointTs
+		tm := oracle.GetTimeFromTS(status.CheckpointTs)
+		resp.Checkpoint = tm.Format("2006-01-02 15:04:05.000")
+	}
+	writeData(w, resp)
+}
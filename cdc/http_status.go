@@ -22,6 +22,7 @@ import (
 	"net/http/pprof"
 	"os"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/prometheus/client_golang/prometheus"
@@ -32,7 +33,7 @@ import (
 
 const defaultStatusPort = 8300
 
-func (s *Server) startStatusHTTP() {
+func (s *Server) startStatusHTTP() error {
 	serverMux := http.NewServeMux()
 
 	serverMux.HandleFunc("/debug/pprof/", pprof.Index)
@@ -43,21 +44,34 @@ func (s *Server) startStatusHTTP() {
 
 	serverMux.HandleFunc("/status", s.handleStatus)
 	serverMux.HandleFunc("/debug/info", s.handleDebugInfo)
+	serverMux.HandleFunc("/debug/kv/stalled-regions", s.handleStalledRegions)
 	serverMux.HandleFunc("/capture/owner/resign", s.handleResignOwner)
 	serverMux.HandleFunc("/capture/owner/admin", s.handleChangefeedAdmin)
+	serverMux.HandleFunc("/capture/owner/changefeed/query", s.handleChangefeedQuery)
 
 	prometheus.DefaultGatherer = registry
 	serverMux.Handle("/metrics", promhttp.Handler())
 
+	tlsConfig, err := s.opts.credential.ToTLSConfigWithVerify()
+	if err != nil {
+		return errors.Annotate(err, "invalid TLS config of the status server")
+	}
 	addr := fmt.Sprintf("%s:%d", s.opts.statusHost, s.opts.statusPort)
-	s.statusServer = &http.Server{Addr: addr, Handler: serverMux}
-	log.Info("status http server is running", zap.String("addr", addr))
+	s.statusServer = &http.Server{Addr: addr, Handler: serverMux, TLSConfig: tlsConfig}
+	log.Info("status http server is running", zap.String("addr", addr), zap.Bool("tls", tlsConfig != nil))
 	go func() {
-		err := s.statusServer.ListenAndServe()
+		var err error
+		if tlsConfig != nil {
+			// the certificates are loaded in the TLS config
+			err = s.statusServer.ListenAndServeTLS("", "")
+		} else {
+			err = s.statusServer.ListenAndServe()
+		}
 		if err != nil && err != http.ErrServerClosed {
 			log.Error("status server error", zap.Error(err))
 		}
 	}()
+	return nil
 }
 
 // status of cdc server
@@ -94,6 +108,10 @@ func (s *Server) handleDebugInfo(w http.ResponseWriter, req *http.Request) {
 	s.writeEtcdInfo(req.Context(), s.capture.etcdClient, w)
 }
 
+func (s *Server) handleStalledRegions(w http.ResponseWriter, req *http.Request) {
+	writeData(w, kv.StalledRegions())
+}
+
 func (s *Server) handleStatus(w http.ResponseWriter, req *http.Request) {
 	st := status{
 		Version: "0.0.1",
@@ -14,12 +14,20 @@
 package cdc
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/etcd"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"go.etcd.io/etcd/clientv3"
 )
 
 type httpStatusSuite struct{}
@@ -28,8 +36,8 @@ var _ = check.Suite(&httpStatusSuite{})
 
 const retryTime = 20
 
-func (s *httpStatusSuite) waitUntilServerOnline(c *check.C) {
-	statusURL := fmt.Sprintf("http://%s:%d/status", defaultServerOptions.statusHost, defaultServerOptions.statusPort)
+func (s *httpStatusSuite) waitUntilServerOnline(c *check.C, port int) {
+	statusURL := fmt.Sprintf("http://%s:%d/status", defaultServerOptions.statusHost, port)
 	for i := 0; i < retryTime; i++ {
 		resp, err := http.Get(statusURL)
 		if err == nil {
@@ -50,10 +58,11 @@ func (s *httpStatusSuite) TestHTTPStatus(c *check.C) {
 		c.Assert(server.statusServer.Close(), check.IsNil)
 	}()
 
-	s.waitUntilServerOnline(c)
+	s.waitUntilServerOnline(c, defaultServerOptions.statusPort)
 
 	testPprof(c)
 	testReisgnOwner(c)
+	testQueryChangefeed(c)
 }
 
 func testPprof(c *check.C) {
@@ -72,3 +81,76 @@ func testReisgnOwner(c *check.C) {
 	defer resp.Body.Close()
 	c.Assert(resp.StatusCode, check.Equals, http.StatusBadRequest)
 }
+
+func testQueryChangefeed(c *check.C) {
+	uri := fmt.Sprintf("http://%s:%d/capture/owner/changefeed/query", defaultServerOptions.statusHost, defaultServerOptions.statusPort)
+	resp, err := http.Get(uri)
+	c.Assert(err, check.IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusBadRequest)
+}
+
+func (s *httpStatusSuite) TestQueryChangefeedState(c *check.C) {
+	ctx := context.Background()
+	clientURL, e, err := etcd.SetupEmbedEtcd(c.MkDir())
+	c.Assert(err, check.IsNil)
+	defer e.Close()
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{clientURL.String()},
+		DialTimeout: 3 * time.Second,
+	})
+	c.Assert(err, check.IsNil)
+	defer client.Close()
+	cli := kv.NewCDCEtcdClient(client)
+
+	opts := defaultServerOptions
+	opts.statusPort = defaultStatusPort + 1
+	server := &Server{opts: opts, capture: &Capture{etcdClient: cli, info: &model.CaptureInfo{ID: "capture"}}}
+	c.Assert(server.startStatusHTTP(), check.IsNil)
+	defer func() {
+		c.Assert(server.statusServer.Close(), check.IsNil)
+	}()
+	s.waitUntilServerOnline(c, opts.statusPort)
+
+	finished := &model.ChangeFeedInfo{SinkURI: "blackhole://", State: model.StateFinished, AdminJobType: model.AdminFinish}
+	c.Assert(cli.SaveChangeFeedInfo(ctx, finished, "finished"), check.IsNil)
+	checkpointTs := oracle.ComposeTS(oracle.GetPhysical(time.Now()), 0)
+	status := &model.ChangeFeedStatus{CheckpointTs: checkpointTs, ResolvedTs: checkpointTs, AdminJobType: model.AdminFinish}
+	c.Assert(cli.PutChangeFeedStatus(ctx, "finished", status), check.IsNil)
+	failed := &model.ChangeFeedInfo{SinkURI: "blackhole://", State: model.StateFailed, AdminJobType: model.AdminStop, Error: "gc safepoint"}
+	c.Assert(cli.SaveChangeFeedInfo(ctx, failed, "failed"), check.IsNil)
+
+	uri := fmt.Sprintf("http://%s:%d/capture/owner/changefeed/query", opts.statusHost, opts.statusPort)
+	query := func(id string) (int, *ChangefeedResp) {
+		resp, err := http.PostForm(uri, url.Values{opVarChangefeedID: {id}})
+		c.Assert(err, check.IsNil)
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		c.Assert(err, check.IsNil)
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		cfResp := &ChangefeedResp{}
+		c.Assert(json.Unmarshal(data, cfResp), check.IsNil)
+		return resp.StatusCode, cfResp
+	}
+
+	code, resp := query("finished")
+	c.Assert(code, check.Equals, http.StatusOK)
+	c.Assert(resp.FeedState, check.Equals, string(model.StateFinished))
+	c.Assert(resp.AdminJobType, check.Equals, model.AdminFinish.String())
+	c.Assert(resp.TSO, check.Equals, checkpointTs)
+	c.Assert(resp.Checkpoint, check.Equals, oracle.GetTimeFromTS(checkpointTs).Format("2006-01-02 15:04:05.000"))
+
+	// the changefeed without status has no checkpoint
+	code, resp = query("failed")
+	c.Assert(code, check.Equals, http.StatusOK)
+	c.Assert(resp.FeedState, check.Equals, string(model.StateFailed))
+	c.Assert(resp.AdminJobType, check.Equals, model.AdminStop.String())
+	c.Assert(resp.TSO, check.Equals, uint64(0))
+
+	code, _ = query("unknown")
+	c.Assert(code, check.Equals, http.StatusBadRequest)
+	code, _ = query("")
+	c.Assert(code, check.Equals, http.StatusBadRequest)
+}
@@ -15,8 +15,7 @@ package kv
 
 import (
 	"context"
-	"io"
-	"math/rand"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -28,6 +27,7 @@ import (
 	pd "github.com/pingcap/pd/client"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/pkg/retry"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	tidbkv "github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/store/tikv"
@@ -54,6 +54,8 @@ type singleRegionInfo struct {
 	ts           uint64
 	failStoreIDs map[uint64]struct{}
 	rpcCtx       *tikv.RPCContext
+	// stream is the stream which subscribes the region
+	stream *sharedStream
 }
 
 func newSingleRegionInfo(verID tikv.RegionVerID, span util.Span, ts uint64, rpcCtx *tikv.RPCContext) singleRegionInfo {
@@ -71,45 +73,91 @@ type regionErrorInfo struct {
 	err error
 }
 
-type syncRegionInfoMap struct {
-	mu            *sync.Mutex
-	regionInfoMap map[uint64]singleRegionInfo
+// regionMerger collects the subscriptions of a region which are rejected or canceled due to
+// a duplicate request. A duplicate request happens when the spans of several regions are
+// merged into one region, since TiKV accepts only one subscription for a region in a stream,
+// the collected subscriptions are resubscribed together.
+type regionMerger struct {
+	mu     sync.Mutex
+	groups map[uint64]*regionMergeGroup
 }
 
-func newSyncRegionInfoMap() *syncRegionInfoMap {
-	return &syncRegionInfoMap{
-		mu:            &sync.Mutex{},
-		regionInfoMap: make(map[uint64]singleRegionInfo),
-	}
+type regionMergeGroup struct {
+	expected int
+	infos    []singleRegionInfo
+}
+
+func newRegionMerger() *regionMerger {
+	return &regionMerger{groups: make(map[uint64]*regionMergeGroup)}
 }
 
-func (m *syncRegionInfoMap) replace(regionID uint64, sri singleRegionInfo) (singleRegionInfo, bool) {
+// expect sets the number of the subscriptions to collect for the region
+func (m *regionMerger) expect(regionID uint64, n int) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-
-	oldSri, ok := m.regionInfoMap[regionID]
-	m.regionInfoMap[regionID] = sri
-	return oldSri, ok
+	group, ok := m.groups[regionID]
+	if !ok {
+		group = &regionMergeGroup{}
+		m.groups[regionID] = group
+	}
+	group.expected += n
 }
 
-func (m *syncRegionInfoMap) take(regionID uint64) (singleRegionInfo, bool) {
+// expecting returns whether the subscriptions of the region are being collected
+func (m *regionMerger) expecting(regionID uint64) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-
-	sri, ok := m.regionInfoMap[regionID]
-	if ok {
-		delete(m.regionInfoMap, regionID)
-	}
-	return sri, ok
+	_, ok := m.groups[regionID]
+	return ok
 }
 
-func (m *syncRegionInfoMap) takeAll() map[uint64]singleRegionInfo {
+// add adds a subscription of the region, all the collected subscriptions are returned
+// if no more subscription is expected.
+func (m *regionMerger) add(sri singleRegionInfo) ([]singleRegionInfo, bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	regionID := sri.verID.GetID()
+	group, ok := m.groups[regionID]
+	if !ok {
+		return []singleRegionInfo{sri}, true
+	}
+	group.infos = append(group.infos, sri)
+	if len(group.infos) < group.expected {
+		return nil, false
+	}
+	delete(m.groups, regionID)
+	return group.infos, true
+}
 
-	oldMap := m.regionInfoMap
-	m.regionInfoMap = make(map[uint64]singleRegionInfo)
-	return oldMap
+// mergeRegionInfos merges the adjacent or overlapped spans, the merged span
+// starts from the minimum ts of the spans.
+func mergeRegionInfos(infos []singleRegionInfo) []singleRegionInfo {
+	sort.Slice(infos, func(i, j int) bool {
+		return util.StartCompare(infos[i].span.Start, infos[j].span.Start) < 0
+	})
+	merged := make([]singleRegionInfo, 0, len(infos))
+	for _, sri := range infos {
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+			if util.EndCompare(sri.span.Start, last.span.End) <= 0 {
+				if util.EndCompare(sri.span.End, last.span.End) > 0 {
+					last.span.End = sri.span.End
+				}
+				if sri.ts < last.ts {
+					last.ts = sri.ts
+				}
+				continue
+			}
+		}
+		merged = append(merged, sri)
+	}
+	return merged
+}
+
+func newDuplicateRequestError(regionID uint64) error {
+	return &eventError{err: &cdcpb.Error{
+		DuplicateRequest: &cdcpb.Error_DuplicateRequest{RegionId: regionID},
+	}}
 }
 
 type connArray struct {
@@ -118,20 +166,24 @@ type connArray struct {
 	v      []*grpc.ClientConn
 }
 
-func newConnArray(ctx context.Context, maxSize uint, addr string) (*connArray, error) {
+func newConnArray(ctx context.Context, maxSize uint, addr string, credential *security.Credential) (*connArray, error) {
 	a := &connArray{
 		target: addr,
 		index:  0,
 		v:      make([]*grpc.ClientConn, maxSize),
 	}
-	err := a.Init(ctx)
+	err := a.Init(ctx, credential)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 	return a, nil
 }
 
-func (a *connArray) Init(ctx context.Context) error {
+func (a *connArray) Init(ctx context.Context, credential *security.Credential) error {
+	grpcTLSOption, err := credential.ToGRPCDialOption()
+	if err != nil {
+		return errors.Trace(err)
+	}
 	for i := range a.v {
 		ctx, cancel := context.WithTimeout(ctx, dialTimeout)
 
@@ -140,7 +192,7 @@ func (a *connArray) Init(ctx context.Context) error {
 			a.target,
 			grpc.WithInitialWindowSize(grpcInitialWindowSize),
 			grpc.WithInitialConnWindowSize(grpcInitialConnWindowSize),
-			grpc.WithInsecure(),
+			grpcTLSOption,
 			grpc.WithConnectParams(grpc.ConnectParams{
 				Backoff: gbackoff.Config{
 					BaseDelay:  time.Second,
@@ -187,7 +239,8 @@ func (a *connArray) Close() {
 
 // CDCClient to get events from TiKV
 type CDCClient struct {
-	pd pd.Client
+	pd         pd.Client
+	credential *security.Credential
 
 	clusterID uint64
 
@@ -197,16 +250,38 @@ type CDCClient struct {
 	}
 
 	regionCache *tikv.RegionCache
+
+	config      *ClientConfig
+	scanLimiter *scanLimiter
+
+	// ctx is canceled when the client is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+	// requestID is the ID of the last request
+	requestID uint64
+	// streams maps from the store address to the streams shared by all the EventFeed calls
+	streamsMu sync.Mutex
+	streams   map[string][]*sharedStream
 }
 
-// NewCDCClient creates a CDCClient instance
-func NewCDCClient(pd pd.Client) (c *CDCClient, err error) {
+// NewCDCClient creates a CDCClient instance, the default config is used if cfg is nil
+func NewCDCClient(pd pd.Client, credential *security.Credential, cfg *ClientConfig) (c *CDCClient, err error) {
 	clusterID := pd.GetClusterID(context.Background())
 	log.Info("get clusterID", zap.Uint64("id", clusterID))
 
+	if cfg == nil {
+		cfg = NewClientConfig()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
 	c = &CDCClient{
+		ctx:         ctx,
+		cancel:      cancel,
+		streams:     make(map[string][]*sharedStream),
 		clusterID:   clusterID,
 		pd:          pd,
+		credential:  credential,
+		config:      cfg,
+		scanLimiter: newScanLimiter(cfg),
 		regionCache: tikv.NewRegionCache(pd),
 		mu: struct {
 			sync.Mutex
@@ -221,6 +296,7 @@ func NewCDCClient(pd pd.Client) (c *CDCClient, err error) {
 
 // Close CDCClient
 func (c *CDCClient) Close() error {
+	c.cancel()
 	c.mu.Lock()
 	for _, conn := range c.mu.conns {
 		conn.Close()
@@ -235,10 +311,14 @@ func (c *CDCClient) getConn(ctx context.Context, addr string) (*grpc.ClientConn,
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if err := c.ctx.Err(); err != nil {
+		// the client is closed
+		return nil, errors.Trace(err)
+	}
 	if conns, ok := c.mu.conns[addr]; ok {
 		return conns.Get(), nil
 	}
-	ca, err := newConnArray(ctx, grpcConnCount, addr)
+	ca, err := newConnArray(ctx, grpcConnCount, addr, c.credential)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -261,6 +341,8 @@ func (c *CDCClient) getStream(ctx context.Context, addr string) (stream cdcpb.Ch
 // a EventFeed to each of the individual region. It streams back result on the
 // provided channel.
 // The `Start` and `End` field in input span must be memcomparable encoded.
+// The regions of all the EventFeed calls are subscribed through the streams
+// shared by the CDCClient.
 func (c *CDCClient) EventFeed(
 	ctx context.Context, span util.Span, ts uint64, eventCh chan<- *model.RegionFeedEvent,
 ) error {
@@ -271,15 +353,20 @@ func (c *CDCClient) EventFeed(
 
 	g, ctx := errgroup.WithContext(ctx)
 
-	regionCh := make(chan singleRegionInfo, 16)
-	errCh := make(chan regionErrorInfo, 16)
+	session := &eventFeedSession{
+		ctx:      ctx,
+		regionCh: make(chan singleRegionInfo, 16),
+		errCh:    make(chan regionErrorInfo, 16),
+		eventCh:  eventCh,
+		merger:   newRegionMerger(),
+	}
 
 	g.Go(func() error {
-		return c.dispatchRequest(ctx, g, regionCh, errCh, eventCh)
+		return c.dispatchRequest(ctx, g, session)
 	})
 
 	g.Go(func() error {
-		err := c.divideAndSendEventFeedToRegions(ctx, span, ts, regionCh)
+		err := c.divideAndSendEventFeedToRegions(ctx, span, ts, session.regionCh)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -287,8 +374,8 @@ func (c *CDCClient) EventFeed(
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case errInfo := <-errCh:
-				err = c.handleError(ctx, errInfo, regionCh)
+			case errInfo := <-session.errCh:
+				err = c.handleError(ctx, errInfo, session.regionCh, session.merger)
 				if err != nil {
 					return errors.Trace(err)
 				}
@@ -299,146 +386,124 @@ func (c *CDCClient) EventFeed(
 	return g.Wait()
 }
 
-// dispatchRequest manages a set of streams and dispatch event feed requests
-// to these streams. Streams to each store will be created on need. After
-// establishing new stream, a goroutine will be spawned to handle events from
-// the stream.
+// dispatchRequest dispatches the event feed requests of the session to the shared
+// streams. Streams to each store will be created on need.
 // Regions from `regionCh` will be connected. If any error happens to a
 // region, the error will be send to `errCh` and the receiver of `errCh` is
 // responsible for handling the error.
-func (c *CDCClient) dispatchRequest(
-	ctx context.Context,
-	g *errgroup.Group,
-	regionCh chan singleRegionInfo,
-	errCh chan<- regionErrorInfo,
-	eventCh chan<- *model.RegionFeedEvent,
-) error {
-	streams := make(map[string]cdcpb.ChangeData_EventFeedClient)
-	// Stores pending regions info for each stream. After sending a new request, the region info wil be put to the map,
-	// and it will be loaded by the receiver thread when it receives the first response from that region. We need this
-	// to pass the region info to the receiver since the region info cannot be inferred from the response from TiKV.
-	storePendingRegions := make(map[string]*syncRegionInfoMap)
-
-MainLoop:
+func (c *CDCClient) dispatchRequest(ctx context.Context, g *errgroup.Group, session *eventFeedSession) error {
 	for {
 		var sri singleRegionInfo
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case sri = <-regionCh:
+		case sri = <-session.regionCh:
 		}
 
 		log.Debug("dispatching region", zap.Uint64("regionID", sri.verID.GetID()))
 
-		// Loop for retrying in case the stream has disconnected.
-		// TODO: Should we break if retries and fails too many times?
-		for {
-			rpcCtx, err := c.getRPCContextForRegion(ctx, sri.verID)
-			if err != nil {
+		rpcCtx, err := c.getRPCContextForRegion(ctx, sri.verID)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if rpcCtx == nil {
+			// The region info is invalid. Retry the span.
+			log.Info("cannot get rpcCtx, retry span",
+				zap.Uint64("regionID", sri.verID.GetID()),
+				zap.Reflect("span", sri.span))
+			// Workaround: spawn to a new goroutine, otherwise the function may blocks when sending to regionCh but
+			// regionCh can only be received from `dispatchRequest`.
+			// TODO: Find better solution after a refactoring
+			g.Go(func() error {
+				err := c.divideAndSendEventFeedToRegions(ctx, sri.span, sri.ts, session.regionCh)
 				return errors.Trace(err)
-			}
-			if rpcCtx == nil {
-				// The region info is invalid. Retry the span.
-				log.Info("cannot get rpcCtx, retry span",
-					zap.Uint64("regionID", sri.verID.GetID()),
-					zap.Reflect("span", sri.span))
-				// Workaround: spawn to a new goroutine, otherwise the function may blocks when sending to regionCh but
-				// regionCh can only be received from `dispatchRequest`.
-				// TODO: Find better solution after a refactoring
-				g.Go(func() error {
-					err := c.divideAndSendEventFeedToRegions(ctx, sri.span, sri.ts, regionCh)
-					return errors.Trace(err)
-				})
-				continue MainLoop
-			}
-			sri.rpcCtx = rpcCtx
-
-			req := &cdcpb.ChangeDataRequest{
-				Header: &cdcpb.Header{
-					ClusterId: c.clusterID,
-				},
-				RegionId:     rpcCtx.Meta.GetId(),
-				RegionEpoch:  rpcCtx.Meta.RegionEpoch,
-				CheckpointTs: sri.ts,
-				StartKey:     sri.span.Start,
-				EndKey:       sri.span.End,
-			}
-
-			// The receiver thread need to know the span, which is only known in the sender thread. So create the
-			// receiver thread for region here so that it can know the span.
-			// TODO: Find a better way to handle this.
-			// TODO: Make sure there will not be goroutine leak.
-			// TODO: Here we use region id to index the regionInfo. However, in case that region merge is enabled, there
-			// may be multiple streams to the same regions. Maybe we need to add a requestID field to the protocol for it.
-
-			// Get region info collection of the addr
-			pendingRegions, ok := storePendingRegions[rpcCtx.Addr]
-			if !ok {
-				pendingRegions = newSyncRegionInfoMap()
-				storePendingRegions[rpcCtx.Addr] = pendingRegions
-			}
-
-			_, hasOld := pendingRegions.replace(sri.verID.GetID(), sri)
-			if hasOld {
-				log.Error("region is already pending for the first response while trying to send another request."+
-					"region merge may have happened which is not supported yet",
-					zap.Uint64("regionID", sri.verID.GetID()))
-			}
-
-			stream, ok := streams[rpcCtx.Addr]
-			// Establish the stream if it has not been connected yet.
-			if !ok {
-				stream, err = c.getStream(ctx, rpcCtx.Addr)
-				if err != nil {
-					return errors.Trace(err)
-				}
-				streams[rpcCtx.Addr] = stream
+			})
+			continue
+		}
+		sri.rpcCtx = rpcCtx
 
-				g.Go(func() error {
-					return c.receiveFromStream(ctx, g, rpcCtx.Addr, rpcCtx.GetStoreID(), stream, regionCh, eventCh, errCh, pendingRegions)
-				})
-			}
+		// TiKV accepts only one subscription for a region in a stream. If several spans of the session are
+		// merged into one region, the subscriptions are collected by `merger` and resubscribed together.
+		if old, ok := c.takeLiveSubscription(session, rpcCtx.Addr, sri.verID.GetID()); ok {
+			c.coalesceRegion(g, session, old, sri)
+			continue
+		}
 
-			log.Info("start new request", zap.Reflect("request", req), zap.String("addr", rpcCtx.Addr))
-			err = stream.Send(req)
+		// Wait for the incremental scans of the store before subscribing the region.
+		releaseScan, err := c.scanLimiter.acquire(ctx, rpcCtx.GetStoreID())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		sub := &regionSubscription{
+			requestID:   atomic.AddUint64(&c.requestID, 1),
+			session:     session,
+			releaseScan: releaseScan,
+		}
+		stream, err := c.acquireStream(rpcCtx.Addr, rpcCtx.GetStoreID(), sub, sri)
+		if err != nil {
+			releaseScan()
+			return errors.Trace(err)
+		}
 
-			// If Send error, the receiver should have received error too or will receive error soon. So we doesn't need
-			// to do extra work here.
-			if err != nil {
-				log.Error("send request to stream failed",
-					zap.String("addr", rpcCtx.Addr),
-					zap.Uint64("storeID", rpcCtx.GetStoreID()),
-					zap.Error(err))
-				err1 := stream.CloseSend()
-				if err1 != nil {
-					log.Error("failed to close stream", zap.Error(err1))
-				}
-				// Delete the stream from the map so that the next time the store is accessed, the stream will be
-				// re-established.
-				delete(streams, rpcCtx.Addr)
-
-				// Remove the region from pendingRegions. If it's already removed, it should be already retried by
-				// `receiveFromStream`, so no need to retry here.
-				_, ok := pendingRegions.take(sri.verID.GetID())
-				if !ok {
-					break
-				}
+		req := &cdcpb.ChangeDataRequest{
+			Header: &cdcpb.Header{
+				ClusterId: c.clusterID,
+			},
+			RegionId:     rpcCtx.Meta.GetId(),
+			RegionEpoch:  rpcCtx.Meta.RegionEpoch,
+			CheckpointTs: sri.ts,
+			StartKey:     sri.span.Start,
+			EndKey:       sri.span.End,
+		}
+		log.Info("start new request",
+			zap.Uint64("requestID", sub.requestID), zap.Reflect("request", req), zap.String("addr", rpcCtx.Addr))
+		err = stream.send(req)
 
-				// Wait for a while and retry sending the request
-				time.Sleep(time.Millisecond * time.Duration(rand.Intn(100)))
-				// Break if ctx has been canceled.
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				default:
-				}
+		// If Send error, the receiver should have received error too or will receive error soon, and the pending
+		// regions of the stream are retried by the receiver. So we doesn't need to do extra work here.
+		if err != nil {
+			log.Error("send request to stream failed",
+				zap.String("addr", rpcCtx.Addr),
+				zap.Uint64("storeID", rpcCtx.GetStoreID()),
+				zap.Error(err))
+			c.closeStream(stream)
+			continue
+		}
+		if c.config.RegionInitTimeout > 0 {
+			time.AfterFunc(c.config.RegionInitTimeout, func() {
+				c.checkRegionInit(stream, sub)
+			})
+		}
+	}
+}
 
-				continue
+// coalesceRegion abandons the live subscription of the region, and resubscribes it
+// together with the new one, since the spans have been merged into one region.
+func (c *CDCClient) coalesceRegion(g *errgroup.Group, session *eventFeedSession, old *regionSubscription, sri singleRegionInfo) {
+	regionID := sri.verID.GetID()
+	log.Info("region is already subscribed while trying to send another request, region merge may have happened",
+		zap.Uint64("regionID", regionID),
+		zap.Uint64("requestID", old.requestID),
+		zap.Reflect("subscribedSpan", old.sri.span),
+		zap.Reflect("span", sri.span))
+	session.merger.expect(regionID, 2)
+	dupErr := newDuplicateRequestError(regionID)
+	ch, dropped := old.sri.stream.handlerChannels(old)
+	g.Go(func() error {
+		if ch != nil {
+			// The handler reports its span and checkpoint after receiving the error. If the subscription is
+			// dropped, the handler reports them with errSubscriptionDropped, which is collected by the merger.
+			select {
+			case ch <- &cdcpb.Event{RegionId: regionID, Event: &cdcpb.Event_Error{Error: dupErr.(*eventError).err}}:
+			case <-dropped:
+			case <-session.ctx.Done():
 			}
-
-			break
+		} else {
+			session.sendError(regionErrorInfo{singleRegionInfo: old.sri, err: dupErr})
 		}
-	}
+		session.sendError(regionErrorInfo{singleRegionInfo: sri, err: dupErr})
+		return nil
+	})
 }
 
 func needReloadRegion(failStoreIDs map[uint64]struct{}, rpcCtx *tikv.RPCContext) (need bool) {
@@ -461,6 +526,7 @@ func (c *CDCClient) partialRegionFeed(
 	ctx context.Context,
 	regionInfo singleRegionInfo,
 	receiver <-chan *cdcpb.Event,
+	dropped <-chan struct{},
 	errCh chan<- regionErrorInfo,
 	eventCh chan<- *model.RegionFeedEvent,
 	isStopped *int32,
@@ -474,6 +540,8 @@ func (c *CDCClient) partialRegionFeed(
 			for {
 				select {
 				case <-receiver:
+				case <-dropped:
+					return
 				case <-timer:
 					return
 				}
@@ -488,7 +556,7 @@ func (c *CDCClient) partialRegionFeed(
 		return errors.New("partialRegionFeed exceeds rate limit")
 	}
 
-	maxTs, err := c.singleEventFeed(ctx, regionInfo.span, regionInfo.ts, receiver, eventCh)
+	maxTs, err := c.singleEventFeed(ctx, regionInfo, receiver, dropped, eventCh)
 	log.Debug("singleEventFeed quit")
 
 	if err == nil || errors.Cause(err) == context.Canceled {
@@ -509,9 +577,12 @@ func (c *CDCClient) partialRegionFeed(
 
 	// We need to ensure when the error is handled, `isStopped` must be set. So set it before sending the error.
 	atomic.StoreInt32(isStopped, 1)
-	errCh <- regionErrorInfo{
+	select {
+	case errCh <- regionErrorInfo{
 		singleRegionInfo: regionInfo,
 		err:              err,
+	}:
+	case <-ctx.Done():
 	}
 
 	return nil
@@ -588,8 +659,46 @@ func (c *CDCClient) divideAndSendEventFeedToRegions(
 
 // handleError handles error returned by a region. If some new EventFeed connection should be established, the region
 // info will be sent to `regionCh`.
-func (c *CDCClient) handleError(ctx context.Context, errInfo regionErrorInfo, regionCh chan<- singleRegionInfo) error {
+func (c *CDCClient) handleError(
+	ctx context.Context, errInfo regionErrorInfo, regionCh chan<- singleRegionInfo, merger *regionMerger,
+) error {
 	err := errInfo.err
+	if errors.Cause(err) == errSubscriptionDropped {
+		// the subscription has been abandoned by the stream, resubscribe the region from the checkpoint
+		eventFeedErrorCounter.WithLabelValues("SubscriptionDropped").Inc()
+		if merger.expecting(errInfo.verID.GetID()) {
+			// the subscription is dropped while being coalesced with another span of the merged region
+			return c.resubscribeMergedRegion(ctx, errInfo.singleRegionInfo, regionCh, merger)
+		}
+		select {
+		case regionCh <- errInfo.singleRegionInfo:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return nil
+	}
+	if errors.Cause(err) == errRegionInitTimeout {
+		// the subscription has been abandoned, resubscribe the region in another stream
+		eventFeedErrorCounter.WithLabelValues("InitTimeout").Inc()
+		select {
+		case regionCh <- errInfo.singleRegionInfo:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return nil
+	}
+	if errors.Cause(err) == errRegionStalled {
+		// Only the stalled subscription is abandoned, the other subscriptions of the stream are not affected.
+		if errInfo.stream != nil && errInfo.stream.abandon(errInfo.verID.GetID()) {
+			c.closeStream(errInfo.stream)
+		}
+		select {
+		case regionCh <- errInfo.singleRegionInfo:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return nil
+	}
 	switch eerr := errors.Cause(err).(type) {
 	case *eventError:
 		innerErr := eerr.err
@@ -604,11 +713,9 @@ func (c *CDCClient) handleError(ctx context.Context, errInfo regionErrorInfo, re
 		} else if innerErr.GetRegionNotFound() != nil {
 			eventFeedErrorCounter.WithLabelValues("RegionNotFound").Inc()
 			return c.divideAndSendEventFeedToRegions(ctx, errInfo.span, errInfo.ts, regionCh)
-		} else if duplicatedRequest := innerErr.GetDuplicateRequest(); duplicatedRequest != nil {
+		} else if innerErr.GetDuplicateRequest() != nil {
 			eventFeedErrorCounter.WithLabelValues("DuplicateRequest").Inc()
-			log.Error("tikv reported duplicated request to the same region. region merge should happened which is not supported",
-				zap.Uint64("regionID", duplicatedRequest.RegionId))
-			return nil
+			return c.resubscribeMergedRegion(ctx, errInfo.singleRegionInfo, regionCh, merger)
 		} else {
 			eventFeedErrorCounter.WithLabelValues("Unknown").Inc()
 			log.Warn("receive empty or unknown error msg", zap.Stringer("error", innerErr))
@@ -625,6 +732,29 @@ func (c *CDCClient) handleError(ctx context.Context, errInfo regionErrorInfo, re
 	return nil
 }
 
+// resubscribeMergedRegion collects the subscription of the merged region, and resubscribes the merged
+// spans after all the subscriptions of the region are collected.
+func (c *CDCClient) resubscribeMergedRegion(
+	ctx context.Context, sri singleRegionInfo, regionCh chan<- singleRegionInfo, merger *regionMerger,
+) error {
+	infos, ok := merger.add(sri)
+	if !ok {
+		// wait for the other subscriptions of the merged region
+		return nil
+	}
+	for _, merged := range mergeRegionInfos(infos) {
+		log.Info("region merged, resubscribe the span",
+			zap.Uint64("regionID", sri.verID.GetID()),
+			zap.Reflect("span", merged.span),
+			zap.Uint64("ts", merged.ts))
+		err := c.divideAndSendEventFeedToRegions(ctx, merged.span, merged.ts, regionCh)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
 func (c *CDCClient) getRPCContextForRegion(ctx context.Context, id tikv.RegionVerID) (*tikv.RPCContext, error) {
 	bo := tikv.NewBackoffer(ctx, tikvRequestMaxBackoff)
 	rpcCtx, err := c.regionCache.GetTiKVRPCContext(bo, id, tidbkv.ReplicaReadLeader, 0)
@@ -634,113 +764,6 @@ func (c *CDCClient) getRPCContextForRegion(ctx context.Context, id tikv.RegionVe
 	return rpcCtx, nil
 }
 
-func (c *CDCClient) receiveFromStream(
-	ctx context.Context,
-	g *errgroup.Group,
-	addr string,
-	storeID uint64,
-	stream cdcpb.ChangeData_EventFeedClient,
-	regionCh <-chan singleRegionInfo,
-	eventCh chan<- *model.RegionFeedEvent,
-	errCh chan<- regionErrorInfo,
-	pendingRegions *syncRegionInfoMap,
-) error {
-	// Cancel the pending regions if the stream failed. Otherwise it will remain unhandled in the pendingRegions list
-	// however not registered in the new reconnected stream.
-	defer func() {
-		log.Info("stream to store closed", zap.String("addr", addr), zap.Uint64("storeID", storeID))
-
-		remainingRegions := pendingRegions.takeAll()
-
-		for _, r := range remainingRegions {
-			select {
-			case <-ctx.Done():
-				return
-			case errCh <- regionErrorInfo{
-				singleRegionInfo: r,
-				err:              errors.New("pending region cancelled due to stream disconnecting"),
-			}:
-			}
-		}
-	}()
-
-	// Each region has it's own goroutine to handle its messages. `regionHandlers` stores the channels to these
-	// channels.
-	// Maps from regionID to the channel.
-	regionHandlers := make(map[uint64]chan *cdcpb.Event)
-	// If an error occurs on a region, the goroutine to process messages of that region should exit, and therefore
-	// the channel to that goroutine is invalidated. We need to know whether it's exited here. If it exited,
-	// `regionStopped` will be set to false.
-	regionStopped := make(map[uint64]*int32)
-
-	for {
-		cevent, err := stream.Recv()
-
-		// TODO: Should we have better way to handle the errors?
-		if err == io.EOF {
-			for _, ch := range regionHandlers {
-				close(ch)
-			}
-			return nil
-		}
-		if err != nil {
-			log.Error(
-				"failed to receive from stream",
-				zap.String("addr", addr),
-				zap.Uint64("storeID", storeID),
-				zap.Error(err))
-
-			for _, ch := range regionHandlers {
-				select {
-				case ch <- nil:
-				case <-ctx.Done():
-					return ctx.Err()
-				}
-			}
-
-			// Do no return error but gracefully stop the goroutine here. Then the whole job will not be canceled and
-			// connection will be retried.
-			return nil
-		}
-
-		for _, event := range cevent.Events {
-			isStopped := false
-			if pIsStopped, ok := regionStopped[event.RegionId]; ok {
-				isStopped = atomic.LoadInt32(pIsStopped) > 0
-			}
-
-			ch, ok := regionHandlers[event.RegionId]
-			if !ok || isStopped {
-				// It's the first response for this region. If the region is newly connected, the region info should
-				// have been put in `pendingRegions`. So here we load the region info from `pendingRegions` and start
-				// a new goroutine to handle messages from this region.
-				// Firstly load the region info.
-				sri, ok := pendingRegions.take(event.RegionId)
-				if !ok {
-					log.Warn("drop event due to region stopped", zap.Uint64("regionID", event.RegionId))
-					continue
-				}
-
-				// Then spawn the goroutine to process messages of this region.
-				ch = make(chan *cdcpb.Event, 16)
-				regionHandlers[event.RegionId] = ch
-
-				isStopped := new(int32)
-				regionStopped[event.RegionId] = isStopped
-				g.Go(func() error {
-					return c.partialRegionFeed(ctx, sri, ch, errCh, eventCh, isStopped)
-				})
-			}
-
-			select {
-			case ch <- event:
-			case <-ctx.Done():
-				return ctx.Err()
-			}
-		}
-	}
-}
-
 // singleEventFeed handles events of a single EventFeed stream.
 // Results will be send to eventCh
 // EventFeed RPC will not return checkpoint event directly
@@ -748,13 +771,35 @@ func (c *CDCClient) receiveFromStream(
 // Return the maximum checkpoint
 func (c *CDCClient) singleEventFeed(
 	ctx context.Context,
-	span util.Span,
-	checkpointTs uint64,
+	regionInfo singleRegionInfo,
 	receiverCh <-chan *cdcpb.Event,
+	dropped <-chan struct{},
 	eventCh chan<- *model.RegionFeedEvent,
 ) (uint64, error) {
 	captureID := util.CaptureIDFromCtx(ctx)
 	changefeedID := util.ChangefeedIDFromCtx(ctx)
+	span := regionInfo.span
+	checkpointTs := regionInfo.ts
+
+	var stallCheckCh <-chan time.Time
+	var watchdog *regionWatchdog
+	if c.config.RegionStallDuration > 0 {
+		ticker := time.NewTicker(c.config.stallCheckInterval())
+		defer ticker.Stop()
+		stallCheckCh = ticker.C
+		region := StalledRegion{
+			RegionID:     regionInfo.verID.GetID(),
+			Span:         span,
+			ResolvedTs:   checkpointTs,
+			CaptureID:    captureID,
+			ChangefeedID: changefeedID,
+		}
+		if regionInfo.rpcCtx != nil {
+			region.Addr = regionInfo.rpcCtx.Addr
+		}
+		watchdog = newRegionWatchdog(c.config, region)
+		defer watchdog.reset()
+	}
 
 	var initialized uint32
 
@@ -788,16 +833,27 @@ func (c *CDCClient) singleEventFeed(
 	for {
 
 		var event *cdcpb.Event
-		var ok bool
 		select {
 		case <-ctx.Done():
 			return atomic.LoadUint64(&checkpointTs), ctx.Err()
-		case event, ok = <-receiverCh:
-		}
-
-		if !ok {
-			log.Debug("singleEventFeed receiver closed")
-			return atomic.LoadUint64(&checkpointTs), nil
+		case now := <-stallCheckCh:
+			resolvedTs := atomic.LoadUint64(&checkpointTs)
+			if !watchdog.check(now, atomic.LoadUint32(&initialized) == 1, resolvedTs) {
+				continue
+			}
+			if err := c.handleStalledRegion(ctx, watchdog.stalled); err != nil {
+				return resolvedTs, errors.Trace(err)
+			}
+			continue
+		case <-dropped:
+			// the buffered events are consumed to resubscribe the region from a greater checkpoint
+			select {
+			case event = <-receiverCh:
+			default:
+				log.Debug("singleEventFeed subscription dropped")
+				return atomic.LoadUint64(&checkpointTs), errSubscriptionDropped
+			}
+		case event = <-receiverCh:
 		}
 
 		if event == nil {
@@ -928,6 +984,37 @@ func updateCheckpointTS(checkpointTs *uint64, newValue uint64) {
 	}
 }
 
+// handleStalledRegion takes the configured action on the stalled region,
+// errRegionStalled is returned if the region should be resubscribed.
+func (c *CDCClient) handleStalledRegion(ctx context.Context, region *StalledRegion) error {
+	action := c.config.RegionStallAction
+	log.Warn("region resolved ts stalled",
+		zap.Uint64("regionID", region.RegionID),
+		zap.String("addr", region.Addr),
+		zap.Reflect("span", region.Span),
+		zap.Uint64("resolvedTs", region.ResolvedTs),
+		zap.Time("stalledSince", region.StalledSince),
+		zap.String("action", action))
+	regionStallActionCounter.WithLabelValues(action, region.CaptureID).Inc()
+	switch action {
+	case RegionStallActionReconnect:
+		return errRegionStalled
+	case RegionStallActionResolveLock:
+		if c.config.LockResolver == nil {
+			log.Warn("no lock resolver is configured, skip resolving locks", zap.Uint64("regionID", region.RegionID))
+			return nil
+		}
+		regionID, resolvedTs := region.RegionID, region.ResolvedTs
+		go func() {
+			err := c.config.LockResolver.Resolve(ctx, regionID, resolvedTs)
+			if err != nil && errors.Cause(err) != context.Canceled {
+				log.Warn("resolve locks failed", zap.Uint64("regionID", regionID), zap.Error(err))
+			}
+		}()
+	}
+	return nil
+}
+
 // eventError wrap cdcpb.Event_Error to implements error interface.
 type eventError struct {
 	err *cdcpb.Error
@@ -5,9 +5,16 @@ import (
 	"math/rand"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/cdcpb"
+	"github.com/pingcap/ticdc/cdc/kv/mockkv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/pingcap/tidb/store/mockstore/mocktikv"
+	"github.com/pingcap/tidb/store/tikv"
+	"golang.org/x/sync/errgroup"
 )
 
 func Test(t *testing.T) { check.TestingT(t) }
@@ -21,7 +28,7 @@ func (s *clientSuite) TestNewClose(c *check.C) {
 	cluster := mocktikv.NewCluster()
 	pdCli := mocktikv.NewPDClient(cluster)
 
-	cli, err := NewCDCClient(pdCli)
+	cli, err := NewCDCClient(pdCli, nil, nil)
 	c.Assert(err, check.IsNil)
 
 	err = cli.Close()
@@ -67,7 +74,7 @@ func (s *clientSuite) TestUpdateCheckpointTS(c *check.C) {
 // ref: https://github.com/grpc/grpc-go/blob/master/grpclog/loggerv2.go#L67-L72
 func (s *etcdSuite) TestConnArray(c *check.C) {
 	addr := "127.0.0.1:2379"
-	ca, err := newConnArray(context.TODO(), 2, addr)
+	ca, err := newConnArray(context.TODO(), 2, addr, nil)
 	c.Assert(err, check.IsNil)
 
 	conn1 := ca.Get()
@@ -79,3 +86,345 @@ func (s *etcdSuite) TestConnArray(c *check.C) {
 
 	ca.Close()
 }
+
+func expectResolved(c *check.C, eventCh <-chan *model.RegionFeedEvent, start, end string, ts uint64) {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event := <-eventCh:
+			if event.Resolved != nil && string(event.Resolved.Span.Start) == start &&
+				string(event.Resolved.Span.End) == end && event.Resolved.ResolvedTs == ts {
+				return
+			}
+		case <-timeout:
+			c.Fatalf("wait for the resolved ts of [%s, %s) at %d timeout", start, end, ts)
+		}
+	}
+}
+
+func expectValue(c *check.C, eventCh <-chan *model.RegionFeedEvent, key, value string, commitTs uint64) {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event := <-eventCh:
+			if event.Val != nil && string(event.Val.Key) == key &&
+				string(event.Val.Value) == value && event.Val.Ts == commitTs {
+				return
+			}
+		case <-timeout:
+			c.Fatalf("wait for the value of %s at %d timeout", key, commitTs)
+		}
+	}
+}
+
+func newMockClient(c *check.C, cluster *mockkv.Cluster, cfg *ClientConfig) *CDCClient {
+	cli, err := NewCDCClient(cluster.PDClient(), nil, cfg)
+	c.Assert(err, check.IsNil)
+	return cli
+}
+
+// testRegionMerge subscribes two regions, merges them and checks the merged region
+// is resubscribed from the minimum checkpoint ts. If respondFirst is true, the region
+// responds the first resubscription before the second one, so the duplicate request
+// is rejected by the server, otherwise it is coalesced by the client.
+func (s *etcdSuite) testRegionMerge(c *check.C, respondFirst bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cluster, region1 := mockkv.NewCluster(c, 1)
+	defer cluster.Close()
+	region2 := cluster.Split(region1, "m")
+	cli := newMockClient(c, cluster, nil)
+	defer func() {
+		c.Assert(cli.Close(), check.IsNil)
+	}()
+
+	eventCh := make(chan *model.RegionFeedEvent, 64)
+	go func() {
+		_ = cli.EventFeed(ctx, util.Span{Start: []byte("a"), End: []byte("z")}, 100, eventCh)
+	}()
+	cluster.ExpectRequest(region1, "a", "m", 100)
+	cluster.ExpectRequest(region2, "m", "z", 100)
+	cluster.Send(mockkv.InitializedEvent(region1), mockkv.ResolvedEvent(region1, 120))
+	expectResolved(c, eventCh, "a", "m", 120)
+	cluster.Send(mockkv.InitializedEvent(region2), mockkv.ResolvedEvent(region2, 110))
+	expectResolved(c, eventCh, "m", "z", 110)
+
+	cluster.Merge(region1, region2)
+	cluster.Send(mockkv.EpochNotMatchEvent(region1))
+	cluster.ExpectRequest(region1, "a", "m", 120)
+	if respondFirst {
+		cluster.Send(mockkv.InitializedEvent(region1))
+	}
+	cluster.Send(mockkv.EpochNotMatchEvent(region2))
+	cluster.ExpectRequest(region1, "a", "z", 110)
+
+	cluster.Send(mockkv.InitializedEvent(region1), mockkv.ResolvedEvent(region1, 130))
+	expectResolved(c, eventCh, "a", "z", 130)
+}
+
+func (s *etcdSuite) TestRegionMerge(c *check.C) {
+	s.testRegionMerge(c, false)
+	s.testRegionMerge(c, true)
+}
+
+func (s *etcdSuite) TestRegionSplit(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cluster, region1 := mockkv.NewCluster(c, 1)
+	defer cluster.Close()
+	cli := newMockClient(c, cluster, nil)
+	defer func() {
+		c.Assert(cli.Close(), check.IsNil)
+	}()
+
+	eventCh := make(chan *model.RegionFeedEvent, 64)
+	go func() {
+		_ = cli.EventFeed(ctx, util.Span{Start: []byte("a"), End: []byte("z")}, 100, eventCh)
+	}()
+	cluster.ExpectRequest(region1, "a", "z", 100)
+	cluster.Send(mockkv.CommittedEvent(region1, []byte("b"), []byte("v1"), 90, 95), mockkv.InitializedEvent(region1))
+	expectValue(c, eventCh, "b", "v1", 95)
+	cluster.Send(mockkv.ResolvedEvent(region1, 110))
+	expectResolved(c, eventCh, "a", "z", 110)
+
+	// both the regions are resubscribed from the checkpoint of the region before splitting
+	region2 := cluster.Split(region1, "m")
+	cluster.Send(mockkv.EpochNotMatchEvent(region1))
+	cluster.ExpectRequest(region1, "a", "m", 110)
+	cluster.ExpectRequest(region2, "m", "z", 110)
+
+	cluster.Send(mockkv.InitializedEvent(region2),
+		mockkv.PrewriteEvent(region2, []byte("n"), []byte("v2"), 120),
+		mockkv.CommitEvent(region2, []byte("n"), 120, 125, false),
+		mockkv.ResolvedEvent(region2, 130))
+	expectValue(c, eventCh, "n", "v2", 125)
+	expectResolved(c, eventCh, "m", "z", 130)
+}
+
+func (s *etcdSuite) TestNotLeader(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cluster, region := mockkv.NewCluster(c, 2)
+	defer cluster.Close()
+	stores := cluster.StoreIDs()
+	cli := newMockClient(c, cluster, nil)
+	defer func() {
+		c.Assert(cli.Close(), check.IsNil)
+	}()
+
+	eventCh := make(chan *model.RegionFeedEvent, 64)
+	go func() {
+		_ = cli.EventFeed(ctx, util.Span{Start: []byte("a"), End: []byte("z")}, 100, eventCh)
+	}()
+	req := cluster.ExpectRequest(region, "a", "z", 100)
+	c.Assert(req.StoreID, check.Equals, stores[0])
+	cluster.Send(mockkv.InitializedEvent(region), mockkv.ResolvedEvent(region, 110))
+	expectResolved(c, eventCh, "a", "z", 110)
+
+	// the region is resubscribed from the new leader
+	cluster.ChangeLeader(region, stores[1])
+	cluster.Send(mockkv.NotLeaderEvent(region, stores[1]))
+	req = cluster.ExpectRequest(region, "a", "z", 110)
+	c.Assert(req.StoreID, check.Equals, stores[1])
+	cluster.Send(mockkv.InitializedEvent(region), mockkv.ResolvedEvent(region, 120))
+	expectResolved(c, eventCh, "a", "z", 120)
+
+	// the region is reloaded if it's not found
+	cluster.ChangeLeader(region, stores[0])
+	cluster.Send(mockkv.RegionNotFoundEvent(region))
+	req = cluster.ExpectRequest(region, "a", "z", 120)
+	c.Assert(req.StoreID, check.Equals, stores[0])
+}
+
+// TestSharedStream checks the event feeds of a client share the streams to a store,
+// and a region subscribed by several event feeds is subscribed in different streams.
+func (s *etcdSuite) TestSharedStream(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cluster, region1 := mockkv.NewCluster(c, 1)
+	defer cluster.Close()
+	region2 := cluster.Split(region1, "m")
+	addr := cluster.StoreAddr(cluster.StoreIDs()[0])
+	cli := newMockClient(c, cluster, nil)
+	defer func() {
+		c.Assert(cli.Close(), check.IsNil)
+	}()
+	streamCount := func() int {
+		cli.streamsMu.Lock()
+		defer cli.streamsMu.Unlock()
+		return len(cli.streams[addr])
+	}
+
+	eventCh1 := make(chan *model.RegionFeedEvent, 64)
+	eventCh2 := make(chan *model.RegionFeedEvent, 64)
+	eventCh3 := make(chan *model.RegionFeedEvent, 64)
+	go func() {
+		_ = cli.EventFeed(ctx, util.Span{Start: []byte("a"), End: []byte("m")}, 100, eventCh1)
+	}()
+	cluster.ExpectRequest(region1, "a", "m", 100)
+	stream1 := cluster.SubscribedStream(region1)
+	go func() {
+		_ = cli.EventFeed(ctx, util.Span{Start: []byte("m"), End: []byte("z")}, 100, eventCh2)
+	}()
+	cluster.ExpectRequest(region2, "m", "z", 100)
+	c.Assert(streamCount(), check.Equals, 1)
+
+	// region 1 is subscribed again by another event feed
+	go func() {
+		_ = cli.EventFeed(ctx, util.Span{Start: []byte("b"), End: []byte("c")}, 100, eventCh3)
+	}()
+	cluster.ExpectRequest(region1, "b", "c", 100)
+	c.Assert(streamCount(), check.Equals, 2)
+
+	c.Assert(stream1.Send(mockkv.InitializedEvent(region1), mockkv.ResolvedEvent(region1, 110)), check.IsNil)
+	expectResolved(c, eventCh1, "a", "m", 110)
+	cluster.Send(mockkv.InitializedEvent(region2), mockkv.ResolvedEvent(region2, 120))
+	expectResolved(c, eventCh2, "m", "z", 120)
+	cluster.Send(mockkv.InitializedEvent(region1), mockkv.ResolvedEvent(region1, 130))
+	expectResolved(c, eventCh3, "b", "c", 130)
+	c.Assert(eventCh1, check.HasLen, 0)
+}
+
+// TestDropSlowSubscription checks a region whose events are not consumed in time doesn't block
+// the other regions of the stream, and it's resubscribed from its checkpoint in another stream.
+func (s *etcdSuite) TestDropSlowSubscription(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cluster, region1 := mockkv.NewCluster(c, 1)
+	defer cluster.Close()
+	region2 := cluster.Split(region1, "m")
+	cli := newMockClient(c, cluster, nil)
+	defer func() {
+		c.Assert(cli.Close(), check.IsNil)
+	}()
+
+	slowCh := make(chan *model.RegionFeedEvent)
+	eventCh := make(chan *model.RegionFeedEvent, 64)
+	go func() {
+		_ = cli.EventFeed(ctx, util.Span{Start: []byte("a"), End: []byte("m")}, 100, slowCh)
+	}()
+	cluster.ExpectRequest(region1, "a", "m", 100)
+	stream1 := cluster.SubscribedStream(region1)
+	go func() {
+		_ = cli.EventFeed(ctx, util.Span{Start: []byte("m"), End: []byte("z")}, 100, eventCh)
+	}()
+	cluster.ExpectRequest(region2, "m", "z", 100)
+
+	// the handler of region 1 is blocked by the resolved ts until the channel is drained
+	events := []*cdcpb.Event{mockkv.InitializedEvent(region1), mockkv.ResolvedEvent(region1, 110)}
+	for i := 0; i < regionEventChanSize+16; i++ {
+		events = append(events, mockkv.CommittedEvent(region1, []byte("b"), []byte("v"), 111, 112))
+	}
+	c.Assert(stream1.Send(events...), check.IsNil)
+	cluster.Send(mockkv.InitializedEvent(region2), mockkv.ResolvedEvent(region2, 120))
+	expectResolved(c, eventCh, "m", "z", 120)
+
+	go func() {
+		for {
+			select {
+			case <-slowCh:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	cluster.ExpectRequest(region1, "a", "m", 110)
+	c.Assert(cluster.SubscribedStream(region1), check.Not(check.Equals), stream1)
+}
+
+// TestCoalesceDroppedSubscription checks a region can be coalesced while the channel of its subscription
+// is full and the subscription is dropped, the dropped subscription is merged with the new span.
+func (s *etcdSuite) TestCoalesceDroppedSubscription(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cluster, region1 := mockkv.NewCluster(c, 1)
+	defer cluster.Close()
+	cli := newMockClient(c, cluster, nil)
+	defer func() {
+		c.Assert(cli.Close(), check.IsNil)
+	}()
+
+	session := &eventFeedSession{ctx: ctx, errCh: make(chan regionErrorInfo, 4), merger: newRegionMerger()}
+	stream := &sharedStream{subscriptions: make(map[uint64]*regionSubscription)}
+	loc, err := cli.regionCache.LocateKey(tikv.NewBackoffer(ctx, tikvRequestMaxBackoff), []byte("a"))
+	c.Assert(err, check.IsNil)
+	verID := loc.Region
+	old := &regionSubscription{session: session, releaseScan: func() {}}
+	old.sri = singleRegionInfo{verID: verID, span: util.Span{Start: []byte("a"), End: []byte("m")}, ts: 110, stream: stream}
+	c.Assert(stream.tryAdd(old), check.IsTrue)
+	sub, start, _ := stream.route(mockkv.ResolvedEvent(region1, 110))
+	c.Assert(start, check.IsTrue)
+	for i := 0; i < regionEventChanSize; i++ {
+		sub.ch <- mockkv.ResolvedEvent(region1, 110)
+	}
+
+	taken, ok := stream.takeLive(session, region1)
+	c.Assert(ok, check.IsTrue)
+	var g errgroup.Group
+	sri := singleRegionInfo{verID: verID, span: util.Span{Start: []byte("m"), End: []byte("z")}, ts: 120}
+	cli.coalesceRegion(&g, session, taken, sri)
+	// the receiver drops the subscription while the duplicate request error is waiting for the full channel
+	stream.drop(sub)
+	c.Assert(g.Wait(), check.IsNil)
+
+	regionCh := make(chan singleRegionInfo, 4)
+	errInfo := <-session.errCh
+	c.Assert(errInfo.span, check.DeepEquals, sri.span)
+	c.Assert(cli.handleError(ctx, errInfo, regionCh, session.merger), check.IsNil)
+	c.Assert(regionCh, check.HasLen, 0)
+	// the handler of the dropped subscription reports its checkpoint
+	dropped := old.sri
+	dropped.ts = 115
+	err = cli.handleError(ctx, regionErrorInfo{singleRegionInfo: dropped, err: errSubscriptionDropped}, regionCh, session.merger)
+	c.Assert(err, check.IsNil)
+	merged := <-regionCh
+	c.Assert(merged.span, check.DeepEquals, util.Span{Start: []byte("a"), End: []byte("z")})
+	c.Assert(merged.ts, check.Equals, uint64(115))
+}
+
+func (s *clientSuite) TestAbandonSubscription(c *check.C) {
+	session := &eventFeedSession{ctx: context.Background()}
+	stream := &sharedStream{subscriptions: map[uint64]*regionSubscription{
+		1: {session: session, stopped: 1, releaseScan: func() {}},
+		2: {session: session, releaseScan: func() {}},
+	}}
+	// only the stopped subscription is abandoned
+	c.Assert(stream.abandon(1), check.IsFalse)
+	c.Assert(stream.subscriptions[1].orphan, check.IsTrue)
+	c.Assert(stream.abandon(2), check.IsFalse)
+	c.Assert(stream.subscriptions[2].orphan, check.IsFalse)
+	c.Assert(stream.abandon(3), check.IsFalse)
+	c.Assert(stream.orphans, check.Equals, 1)
+
+	// the stream is recycled if all the subscriptions are orphans
+	stream.subscriptions[2].stopped = 1
+	c.Assert(stream.abandon(2), check.IsTrue)
+}
+
+func (s *clientSuite) TestMergeRegionInfos(c *check.C) {
+	newInfo := func(start, end string, ts uint64) singleRegionInfo {
+		return singleRegionInfo{span: util.Span{Start: []byte(start), End: []byte(end)}, ts: ts}
+	}
+	infos := mergeRegionInfos([]singleRegionInfo{
+		newInfo("m", "z", 110), newInfo("a", "m", 120), newInfo("0", "1", 90),
+	})
+	c.Assert(infos, check.DeepEquals, []singleRegionInfo{newInfo("0", "1", 90), newInfo("a", "z", 110)})
+
+	merger := newRegionMerger()
+	info := newInfo("a", "m", 120)
+	infos, ok := merger.add(info)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(infos, check.HasLen, 1)
+	merger.expect(info.verID.GetID(), 2)
+	_, ok = merger.add(info)
+	c.Assert(ok, check.IsFalse)
+	infos, ok = merger.add(info)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(infos, check.HasLen, 2)
+}
@@ -48,6 +48,9 @@ const (
 
 	// JobKeyPrefix is the prefix of job keys
 	JobKeyPrefix = EtcdKeyBase + "/job"
+
+	// SchemaSnapshotKeyPrefix is the prefix of schema snapshot keys
+	SchemaSnapshotKeyPrefix = EtcdKeyBase + "/schema/snapshot"
 )
 
 // GetEtcdKeyChangeFeedList returns the prefix key of all changefeed config
@@ -100,6 +103,17 @@ func GetEtcdKeyJob(changeFeedID string) string {
 	return JobKeyPrefix + "/" + changeFeedID
 }
 
+// GetEtcdKeySchemaSnapshotList returns the prefix key of the schema snapshots of a changefeed
+func GetEtcdKeySchemaSnapshotList(changefeedID string) string {
+	return SchemaSnapshotKeyPrefix + "/" + changefeedID + "/"
+}
+
+// GetEtcdKeySchemaSnapshot returns the key of a schema snapshot, the ts is padded
+// so the keys are sorted by the ts.
+func GetEtcdKeySchemaSnapshot(changefeedID string, ts uint64) string {
+	return fmt.Sprintf("%s%020d", GetEtcdKeySchemaSnapshotList(changefeedID), ts)
+}
+
 // CDCEtcdClient is a wrap of etcd client
 type CDCEtcdClient struct {
 	Client *clientv3.Client
@@ -451,6 +465,40 @@ func (c CDCEtcdClient) DeleteTaskStatus(
 	return errors.Trace(err)
 }
 
+// PutSchemaSnapshot puts a schema snapshot of the changefeed into etcd
+func (c CDCEtcdClient) PutSchemaSnapshot(ctx context.Context, changefeedID string, snap *model.SchemaSnapshot) error {
+	data, err := snap.Marshal()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = c.Client.Put(ctx, GetEtcdKeySchemaSnapshot(changefeedID, snap.Ts), string(data))
+	return errors.Trace(err)
+}
+
+// GetSchemaSnapshot returns the latest schema snapshot of the changefeed whose ts is not greater than ts
+func (c CDCEtcdClient) GetSchemaSnapshot(ctx context.Context, changefeedID string, ts uint64) (*model.SchemaSnapshot, error) {
+	resp, err := c.Client.Get(ctx, GetEtcdKeySchemaSnapshotList(changefeedID),
+		clientv3.WithRange(GetEtcdKeySchemaSnapshot(changefeedID, ts)+"\x00"),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortDescend),
+		clientv3.WithLimit(1))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, errors.Annotatef(model.ErrSchemaSnapshotNotExists, "changefeed: %s, ts: %d", changefeedID, ts)
+	}
+	snap := &model.SchemaSnapshot{}
+	err = snap.Unmarshal(resp.Kvs[0].Value)
+	return snap, errors.Trace(err)
+}
+
+// DeleteSchemaSnapshots deletes the schema snapshots of the changefeed whose ts is less than ts
+func (c CDCEtcdClient) DeleteSchemaSnapshots(ctx context.Context, changefeedID string, ts uint64) error {
+	_, err := c.Client.Delete(ctx, GetEtcdKeySchemaSnapshotList(changefeedID),
+		clientv3.WithRange(GetEtcdKeySchemaSnapshot(changefeedID, ts)))
+	return errors.Trace(err)
+}
+
 // PutCaptureInfo put capture info into etcd.
 func (c CDCEtcdClient) PutCaptureInfo(ctx context.Context, info *model.CaptureInfo, leaseID clientv3.LeaseID) error {
 	data, err := info.Marshal()
@@ -165,6 +165,42 @@ func (s *etcdSuite) TestGetPutTaskPosition(c *check.C) {
 	c.Assert(errors.Cause(err), check.Equals, model.ErrTaskStatusNotExists)
 }
 
+func (s *etcdSuite) TestSchemaSnapshot(c *check.C) {
+	ctx := context.Background()
+	feedID := "feedid"
+	for _, ts := range []uint64{100, 200, 300} {
+		err := s.client.PutSchemaSnapshot(ctx, feedID, &model.SchemaSnapshot{Ts: ts, SchemaVersion: int64(ts / 10)})
+		c.Assert(err, check.IsNil)
+	}
+	// the snapshots of the changefeed with the same prefix aren't returned
+	err := s.client.PutSchemaSnapshot(ctx, feedID+"2", &model.SchemaSnapshot{Ts: 150})
+	c.Assert(err, check.IsNil)
+
+	_, err = s.client.GetSchemaSnapshot(ctx, feedID, 99)
+	c.Assert(errors.Cause(err), check.Equals, model.ErrSchemaSnapshotNotExists)
+	snap, err := s.client.GetSchemaSnapshot(ctx, feedID, 200)
+	c.Assert(err, check.IsNil)
+	c.Assert(snap.Ts, check.Equals, uint64(200))
+	c.Assert(snap.SchemaVersion, check.Equals, int64(20))
+	snap, err = s.client.GetSchemaSnapshot(ctx, feedID, 299)
+	c.Assert(err, check.IsNil)
+	c.Assert(snap.Ts, check.Equals, uint64(200))
+	snap, err = s.client.GetSchemaSnapshot(ctx, feedID, 1000)
+	c.Assert(err, check.IsNil)
+	c.Assert(snap.Ts, check.Equals, uint64(300))
+
+	err = s.client.DeleteSchemaSnapshots(ctx, feedID, 200)
+	c.Assert(err, check.IsNil)
+	_, err = s.client.GetSchemaSnapshot(ctx, feedID, 199)
+	c.Assert(errors.Cause(err), check.Equals, model.ErrSchemaSnapshotNotExists)
+	snap, err = s.client.GetSchemaSnapshot(ctx, feedID, 200)
+	c.Assert(err, check.IsNil)
+	c.Assert(snap.Ts, check.Equals, uint64(200))
+	snap, err = s.client.GetSchemaSnapshot(ctx, feedID+"2", 1000)
+	c.Assert(err, check.IsNil)
+	c.Assert(snap.Ts, check.Equals, uint64(150))
+}
+
 func (s *etcdSuite) TestDeleteTaskPosition(c *check.C) {
 	ctx := context.Background()
 	info := &model.TaskPosition{
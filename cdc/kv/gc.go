@@ -0,0 +1,150 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/parser/terror"
+	"github.com/pingcap/tidb/store/tikv"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+)
+
+const (
+	// CDCServiceSafePointID is the ID of CDC in the service safepoint list
+	CDCServiceSafePointID = "ticdc"
+
+	// gcServiceSafePointPrefix is the prefix of the min start ts of the TiDB servers in PD's etcd,
+	// the GC worker of TiDB never advances the GC safepoint beyond any of them. They're not the
+	// service safepoints of PD, which are not supported by the PD client of this version.
+	gcServiceSafePointPrefix = "/tidb/server/minstartts"
+)
+
+// GCSafePointManager reads the GC safepoint of the cluster and registers
+// service safepoints to prevent the data needed by CDC from being garbage collected.
+// A service safepoint is written as a min start ts of TiDB, which is respected by the GC worker of TiDB.
+type GCSafePointManager interface {
+	// GetGCSafePoint returns the current GC safepoint, 0 is returned if GC never runs.
+	GetGCSafePoint(ctx context.Context) (uint64, error)
+	// UpdateServiceSafePoint registers a service safepoint, which expires after ttl seconds
+	// unless it is updated again.
+	UpdateServiceSafePoint(ctx context.Context, serviceID string, ttl int64, safePoint uint64) error
+	// RemoveServiceSafePoint removes a service safepoint.
+	RemoveServiceSafePoint(ctx context.Context, serviceID string) error
+}
+
+type etcdGCSafePointManager struct {
+	cli CDCEtcdClient
+
+	mu sync.Mutex
+	// leases are the leases of the service safepoints, which are kept alive by the updates
+	leases map[string]serviceLease
+}
+
+type serviceLease struct {
+	id  clientv3.LeaseID
+	ttl int64
+}
+
+// NewGCSafePointManager returns a GCSafePointManager which stores the service
+// safepoints in the etcd embedded in PD.
+func NewGCSafePointManager(cli CDCEtcdClient) GCSafePointManager {
+	return &etcdGCSafePointManager{cli: cli, leases: make(map[string]serviceLease)}
+}
+
+// CreatingServiceSafePointID returns the ID of the service safepoint registered when creating a changefeed,
+// it's removed after the changefeed is protected by the service safepoint of CDC.
+func CreatingServiceSafePointID(changefeedID string) string {
+	return CDCServiceSafePointID + "-creating-" + changefeedID
+}
+
+// GetEtcdKeyServiceSafePoint returns the key of a service safepoint
+func GetEtcdKeyServiceSafePoint(serviceID string) string {
+	return gcServiceSafePointPrefix + "/" + serviceID
+}
+
+// GetGCSafePoint implements GCSafePointManager interface.
+func (m *etcdGCSafePointManager) GetGCSafePoint(ctx context.Context) (uint64, error) {
+	resp, err := m.cli.Client.Get(ctx, tikv.GcSavedSafePoint)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if resp.Count == 0 {
+		return 0, nil
+	}
+	safePoint, err := strconv.ParseUint(string(resp.Kvs[0].Value), 10, 64)
+	return safePoint, errors.Trace(err)
+}
+
+// UpdateServiceSafePoint implements GCSafePointManager interface.
+func (m *etcdGCSafePointManager) UpdateServiceSafePoint(ctx context.Context, serviceID string, ttl int64, safePoint uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lease, ok := m.leases[serviceID]
+	if ok && lease.ttl == ttl {
+		_, err := m.cli.Client.KeepAliveOnce(ctx, lease.id)
+		if err == nil {
+			_, err = m.cli.Client.Put(ctx, GetEtcdKeyServiceSafePoint(serviceID),
+				strconv.FormatUint(safePoint, 10), clientv3.WithLease(lease.id))
+			return errors.Trace(err)
+		}
+		if !terror.ErrorEqual(err, rpctypes.ErrLeaseNotFound) {
+			return errors.Trace(err)
+		}
+	}
+
+	// grant a new lease if the lease expires or the ttl changes
+	resp, err := m.cli.Client.Grant(ctx, ttl)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = m.cli.Client.Put(ctx, GetEtcdKeyServiceSafePoint(serviceID),
+		strconv.FormatUint(safePoint, 10), clientv3.WithLease(resp.ID))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	m.leases[serviceID] = serviceLease{id: resp.ID, ttl: ttl}
+	if ok {
+		// the key has been attached to the new lease, so it isn't deleted with the previous one
+		return errors.Trace(m.revoke(ctx, lease.id))
+	}
+	return nil
+}
+
+// RemoveServiceSafePoint implements GCSafePointManager interface.
+func (m *etcdGCSafePointManager) RemoveServiceSafePoint(ctx context.Context, serviceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.cli.Client.Delete(ctx, GetEtcdKeyServiceSafePoint(serviceID))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if lease, ok := m.leases[serviceID]; ok {
+		delete(m.leases, serviceID)
+		return errors.Trace(m.revoke(ctx, lease.id))
+	}
+	return nil
+}
+
+func (m *etcdGCSafePointManager) revoke(ctx context.Context, id clientv3.LeaseID) error {
+	_, err := m.cli.Client.Revoke(ctx, id)
+	if err != nil && !terror.ErrorEqual(err, rpctypes.ErrLeaseNotFound) {
+		return errors.Trace(err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tidb/store/tikv"
+	"go.etcd.io/etcd/clientv3"
+)
+
+func (s *etcdSuite) TestGCSafePointManager(c *check.C) {
+	ctx := context.Background()
+	m := NewGCSafePointManager(s.client)
+
+	safePoint, err := m.GetGCSafePoint(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(safePoint, check.Equals, uint64(0))
+
+	_, err = s.client.Client.Put(ctx, tikv.GcSavedSafePoint, "415419856410918913")
+	c.Assert(err, check.IsNil)
+	safePoint, err = m.GetGCSafePoint(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(safePoint, check.Equals, uint64(415419856410918913))
+
+	err = m.UpdateServiceSafePoint(ctx, CDCServiceSafePointID, 10, 415419856410918914)
+	c.Assert(err, check.IsNil)
+	resp, err := s.client.Client.Get(ctx, GetEtcdKeyServiceSafePoint(CDCServiceSafePointID))
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Kvs, check.HasLen, 1)
+	c.Assert(string(resp.Kvs[0].Value), check.Equals, "415419856410918914")
+	lease := resp.Kvs[0].Lease
+	c.Assert(lease, check.Not(check.Equals), int64(clientv3.NoLease))
+
+	// the lease is kept alive by the updates
+	err = m.UpdateServiceSafePoint(ctx, CDCServiceSafePointID, 10, 415419856410918915)
+	c.Assert(err, check.IsNil)
+	resp, err = s.client.Client.Get(ctx, GetEtcdKeyServiceSafePoint(CDCServiceSafePointID))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(resp.Kvs[0].Value), check.Equals, "415419856410918915")
+	c.Assert(resp.Kvs[0].Lease, check.Equals, lease)
+
+	// the previous lease is revoked if the ttl changes
+	err = m.UpdateServiceSafePoint(ctx, CDCServiceSafePointID, 20, 415419856410918916)
+	c.Assert(err, check.IsNil)
+	resp, err = s.client.Client.Get(ctx, GetEtcdKeyServiceSafePoint(CDCServiceSafePointID))
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Kvs, check.HasLen, 1)
+	c.Assert(resp.Kvs[0].Lease, check.Not(check.Equals), lease)
+	leases, err := s.client.Client.Leases(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(leases.Leases, check.HasLen, 1)
+
+	err = m.RemoveServiceSafePoint(ctx, CDCServiceSafePointID)
+	c.Assert(err, check.IsNil)
+	resp, err = s.client.Client.Get(ctx, GetEtcdKeyServiceSafePoint(CDCServiceSafePointID))
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Kvs, check.HasLen, 0)
+	leases, err = s.client.Client.Leases(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(leases.Leases, check.HasLen, 0)
+}
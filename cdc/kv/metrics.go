@@ -60,6 +60,41 @@ var (
 			Name:      "send_event_count",
 			Help:      "event count sent to event channel by this puller",
 		}, []string{"type", "capture", "changefeed"})
+	stalledRegionGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "kvclient",
+			Name:      "stalled_region_count",
+			Help:      "The number of regions whose resolved ts doesn't advance",
+		}, []string{"capture"})
+	regionStallActionCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "kvclient",
+			Name:      "region_stall_action_count",
+			Help:      "The number of actions taken on the stalled regions",
+		}, []string{"action", "capture"})
+	resolveLockCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "kvclient",
+			Name:      "resolve_lock_count",
+			Help:      "The number of locks scanned and resolve lock requests rate limited",
+		}, []string{"type"})
+	streamGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "kvclient",
+			Name:      "stream_count",
+			Help:      "The number of streams to each store shared by the event feeds",
+		}, []string{"store"})
+	initializingRegionGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "kvclient",
+			Name:      "initializing_region_count",
+			Help:      "The number of regions in incremental scan of each store",
+		}, []string{"store"})
 )
 
 // InitMetrics registers all metrics in the kv package
@@ -70,4 +105,9 @@ func InitMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(eventFeedGauge)
 	registry.MustRegister(pullEventCounter)
 	registry.MustRegister(sendEventCounter)
+	registry.MustRegister(stalledRegionGauge)
+	registry.MustRegister(regionStallActionCounter)
+	registry.MustRegister(resolveLockCounter)
+	registry.MustRegister(streamGauge)
+	registry.MustRegister(initializingRegionGauge)
 }
@@ -0,0 +1,79 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"sync"
+)
+
+var _ GCSafePointManager = &MockGCSafePointManager{}
+
+// MockGCSafePointManager is a GCSafePointManager which keeps all safepoints in memory.
+// It's used for testing.
+type MockGCSafePointManager struct {
+	mu                  sync.Mutex
+	gcSafePoint         uint64
+	serviceSafePoints   map[string]uint64
+	serviceSafePointTTL map[string]int64
+}
+
+// NewMockGCSafePointManager creates a new MockGCSafePointManager.
+func NewMockGCSafePointManager(gcSafePoint uint64) *MockGCSafePointManager {
+	return &MockGCSafePointManager{
+		gcSafePoint:         gcSafePoint,
+		serviceSafePoints:   make(map[string]uint64),
+		serviceSafePointTTL: make(map[string]int64),
+	}
+}
+
+// SetGCSafePoint sets the GC safepoint.
+func (m *MockGCSafePointManager) SetGCSafePoint(safePoint uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gcSafePoint = safePoint
+}
+
+// ServiceSafePoint returns the service safepoint and its TTL.
+func (m *MockGCSafePointManager) ServiceSafePoint(serviceID string) (safePoint uint64, ttl int64, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	safePoint, ok = m.serviceSafePoints[serviceID]
+	return safePoint, m.serviceSafePointTTL[serviceID], ok
+}
+
+// GetGCSafePoint implements GCSafePointManager interface.
+func (m *MockGCSafePointManager) GetGCSafePoint(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gcSafePoint, nil
+}
+
+// UpdateServiceSafePoint implements GCSafePointManager interface.
+func (m *MockGCSafePointManager) UpdateServiceSafePoint(ctx context.Context, serviceID string, ttl int64, safePoint uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.serviceSafePoints[serviceID] = safePoint
+	m.serviceSafePointTTL[serviceID] = ttl
+	return nil
+}
+
+// RemoveServiceSafePoint implements GCSafePointManager interface.
+func (m *MockGCSafePointManager) RemoveServiceSafePoint(ctx context.Context, serviceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.serviceSafePoints, serviceID)
+	delete(m.serviceSafePointTTL, serviceID)
+	return nil
+}
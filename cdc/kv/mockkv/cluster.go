@@ -0,0 +1,239 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mockkv provides an in-process fake TiKV cluster for testing the kv client.
+// The stores serve the ChangeData service whose events are scripted by the test,
+// and the regions are kept by a mock PD which the region cache of the client loads from.
+package mockkv
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/cdcpb"
+	pd "github.com/pingcap/pd/client"
+	"github.com/pingcap/tidb/store/mockstore/mocktikv"
+	"google.golang.org/grpc"
+)
+
+const expectTimeout = 10 * time.Second
+
+// Request is a ChangeDataRequest accepted by a store
+type Request struct {
+	*cdcpb.ChangeDataRequest
+	StoreID uint64
+}
+
+// Cluster is a fake TiKV cluster, the regions are bootstrapped with a region
+// covering the whole key space, which has a peer on each store and the leader
+// on the first store.
+type Cluster struct {
+	c       *check.C
+	cluster *mocktikv.Cluster
+	stores  []*store
+	reqCh   chan Request
+
+	mu sync.Mutex
+	// streams maps from the region ID to the stream which subscribes the region lastly
+	streams map[uint64]*Stream
+}
+
+// store is a ChangeData server which accepts only one subscription for a region
+// in a stream like TiKV.
+type store struct {
+	cluster *Cluster
+	id      uint64
+	addr    string
+	server  *grpc.Server
+}
+
+// Stream is an EventFeed stream served by a store
+type Stream struct {
+	mu         sync.Mutex
+	server     cdcpb.ChangeData_EventFeedServer
+	subscribed map[uint64]struct{}
+}
+
+// NewCluster creates a Cluster with storeCount stores, the ID of the first region is returned.
+func NewCluster(c *check.C, storeCount int) (*Cluster, uint64) {
+	cluster := &Cluster{
+		c:       c,
+		cluster: mocktikv.NewCluster(),
+		reqCh:   make(chan Request, 64),
+		streams: make(map[uint64]*Stream),
+	}
+	storeIDs := cluster.cluster.AllocIDs(storeCount)
+	for _, storeID := range storeIDs {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		c.Assert(err, check.IsNil)
+		s := &store{cluster: cluster, id: storeID, addr: lis.Addr().String(), server: grpc.NewServer()}
+		cdcpb.RegisterChangeDataServer(s.server, s)
+		go func() {
+			_ = s.server.Serve(lis)
+		}()
+		cluster.stores = append(cluster.stores, s)
+		cluster.cluster.AddStore(storeID, s.addr)
+	}
+	regionID := cluster.cluster.AllocID()
+	peerIDs := cluster.cluster.AllocIDs(storeCount)
+	cluster.cluster.Bootstrap(regionID, storeIDs, peerIDs, peerIDs[0])
+	return cluster, regionID
+}
+
+// Close stops all the stores.
+func (c *Cluster) Close() {
+	for _, s := range c.stores {
+		s.server.Stop()
+	}
+}
+
+// PDClient returns a mock PD client which serves the regions of the cluster.
+func (c *Cluster) PDClient() pd.Client {
+	return mocktikv.NewPDClient(c.cluster)
+}
+
+// StoreIDs returns the IDs of the stores.
+func (c *Cluster) StoreIDs() []uint64 {
+	ids := make([]uint64, len(c.stores))
+	for i, s := range c.stores {
+		ids[i] = s.id
+	}
+	return ids
+}
+
+// StoreAddr returns the address of the store.
+func (c *Cluster) StoreAddr(storeID uint64) string {
+	for _, s := range c.stores {
+		if s.id == storeID {
+			return s.addr
+		}
+	}
+	c.c.Fatalf("store %d not found", storeID)
+	return ""
+}
+
+// Split splits the region at key, the ID of the new region on the right is returned.
+func (c *Cluster) Split(regionID uint64, key string) uint64 {
+	_, leaderPeerID := c.cluster.GetRegion(regionID)
+	newRegionID := c.cluster.AllocID()
+	peerIDs := c.cluster.AllocIDs(len(c.stores))
+	// the leader of the new region is on the same store as the old one
+	leader := peerIDs[0]
+	region, _ := c.cluster.GetRegion(regionID)
+	for i, peer := range region.GetPeers() {
+		if peer.GetId() == leaderPeerID {
+			leader = peerIDs[i]
+		}
+	}
+	c.cluster.SplitRaw(regionID, newRegionID, []byte(key), peerIDs, leader)
+	return newRegionID
+}
+
+// Merge merges the region on the right into the one on the left.
+func (c *Cluster) Merge(leftRegionID, rightRegionID uint64) {
+	c.cluster.Merge(leftRegionID, rightRegionID)
+}
+
+// ChangeLeader transfers the leader of the region to the store.
+func (c *Cluster) ChangeLeader(regionID, storeID uint64) {
+	region, _ := c.cluster.GetRegion(regionID)
+	for _, peer := range region.GetPeers() {
+		if peer.GetStoreId() == storeID {
+			c.cluster.ChangeLeader(regionID, peer.GetId())
+			return
+		}
+	}
+	c.c.Fatalf("region %d has no peer on store %d", regionID, storeID)
+}
+
+// ExpectRequest waits for the request of the region with the span and the checkpoint ts,
+// the other requests received before it are skipped.
+func (c *Cluster) ExpectRequest(regionID uint64, start, end string, ts uint64) Request {
+	timeout := time.After(expectTimeout)
+	for {
+		select {
+		case req := <-c.reqCh:
+			if req.RegionId == regionID && string(req.StartKey) == start &&
+				string(req.EndKey) == end && req.CheckpointTs == ts {
+				return req
+			}
+		case <-timeout:
+			c.c.Fatalf("wait for the request of region %d [%s, %s) at %d timeout", regionID, start, end, ts)
+		}
+	}
+}
+
+// ExpectNoRequest checks no request is received in the duration.
+func (c *Cluster) ExpectNoRequest(d time.Duration) {
+	select {
+	case req := <-c.reqCh:
+		c.c.Fatalf("unexpected request %v", req)
+	case <-time.After(d):
+	}
+}
+
+// SubscribedStream returns the stream which subscribes the region lastly.
+func (c *Cluster) SubscribedStream(regionID uint64) *Stream {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stream, ok := c.streams[regionID]
+	if !ok {
+		c.c.Fatalf("region %d is not subscribed", regionID)
+	}
+	return stream
+}
+
+// Send sends the events to the stream which subscribes the region of the first event lastly.
+func (c *Cluster) Send(events ...*cdcpb.Event) {
+	c.c.Assert(c.SubscribedStream(events[0].RegionId).Send(events...), check.IsNil)
+}
+
+// EventFeed implements the ChangeDataServer interface.
+func (s *store) EventFeed(server cdcpb.ChangeData_EventFeedServer) error {
+	stream := &Stream{server: server, subscribed: make(map[uint64]struct{})}
+	for {
+		req, err := server.Recv()
+		if err != nil {
+			return err
+		}
+		stream.mu.Lock()
+		_, duplicated := stream.subscribed[req.RegionId]
+		stream.subscribed[req.RegionId] = struct{}{}
+		stream.mu.Unlock()
+		if duplicated {
+			if err := stream.Send(DuplicateRequestEvent(req.RegionId)); err != nil {
+				return err
+			}
+			continue
+		}
+		s.cluster.mu.Lock()
+		s.cluster.streams[req.RegionId] = stream
+		s.cluster.mu.Unlock()
+		s.cluster.reqCh <- Request{ChangeDataRequest: req, StoreID: s.id}
+	}
+}
+
+// Send sends the events in a ChangeDataEvent, the region is unsubscribed after
+// an error is sent except the duplicate request error, like TiKV.
+func (s *Stream) Send(events ...*cdcpb.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range events {
+		if e, ok := event.Event.(*cdcpb.Event_Error); ok && e.Error.DuplicateRequest == nil {
+			delete(s.subscribed, event.RegionId)
+		}
+	}
+	return s.server.Send(&cdcpb.ChangeDataEvent{Events: events})
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockkv
+
+import (
+	"github.com/pingcap/kvproto/pkg/cdcpb"
+	"github.com/pingcap/kvproto/pkg/errorpb"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+func entriesEvent(regionID uint64, rows ...*cdcpb.Event_Row) *cdcpb.Event {
+	return &cdcpb.Event{
+		RegionId: regionID,
+		Event:    &cdcpb.Event_Entries_{Entries: &cdcpb.Event_Entries{Entries: rows}},
+	}
+}
+
+func errorEvent(regionID uint64, err *cdcpb.Error) *cdcpb.Event {
+	return &cdcpb.Event{RegionId: regionID, Event: &cdcpb.Event_Error{Error: err}}
+}
+
+// InitializedEvent creates the event sent after the incremental scan of the region
+func InitializedEvent(regionID uint64) *cdcpb.Event {
+	return entriesEvent(regionID, &cdcpb.Event_Row{Type: cdcpb.Event_INITIALIZED})
+}
+
+// ResolvedEvent creates the resolved ts event of the region
+func ResolvedEvent(regionID, ts uint64) *cdcpb.Event {
+	return &cdcpb.Event{RegionId: regionID, Event: &cdcpb.Event_ResolvedTs{ResolvedTs: ts}}
+}
+
+// CommittedEvent creates the event of a row committed before the checkpoint, it's sent
+// during the incremental scan. The row is deleted if value is nil.
+func CommittedEvent(regionID uint64, key, value []byte, startTs, commitTs uint64) *cdcpb.Event {
+	return entriesEvent(regionID, &cdcpb.Event_Row{
+		Type:     cdcpb.Event_COMMITTED,
+		OpType:   opType(value),
+		Key:      key,
+		Value:    value,
+		StartTs:  startTs,
+		CommitTs: commitTs,
+	})
+}
+
+// PrewriteEvent creates the prewrite event of a row, the row is deleted if value is nil
+func PrewriteEvent(regionID uint64, key, value []byte, startTs uint64) *cdcpb.Event {
+	return entriesEvent(regionID, &cdcpb.Event_Row{
+		Type:    cdcpb.Event_PREWRITE,
+		OpType:  opType(value),
+		Key:     key,
+		Value:   value,
+		StartTs: startTs,
+	})
+}
+
+// CommitEvent creates the commit event of a row prewritten by PrewriteEvent
+func CommitEvent(regionID uint64, key []byte, startTs, commitTs uint64, deleted bool) *cdcpb.Event {
+	op := cdcpb.Event_Row_PUT
+	if deleted {
+		op = cdcpb.Event_Row_DELETE
+	}
+	return entriesEvent(regionID, &cdcpb.Event_Row{
+		Type:     cdcpb.Event_COMMIT,
+		OpType:   op,
+		Key:      key,
+		StartTs:  startTs,
+		CommitTs: commitTs,
+	})
+}
+
+// RollbackEvent creates the rollback event of a row prewritten by PrewriteEvent
+func RollbackEvent(regionID uint64, key []byte, startTs uint64) *cdcpb.Event {
+	return entriesEvent(regionID, &cdcpb.Event_Row{
+		Type:    cdcpb.Event_ROLLBACK,
+		Key:     key,
+		StartTs: startTs,
+	})
+}
+
+func opType(value []byte) cdcpb.Event_Row_OpType {
+	if value == nil {
+		return cdcpb.Event_Row_DELETE
+	}
+	return cdcpb.Event_Row_PUT
+}
+
+// EpochNotMatchEvent creates the error sent after the region is split or merged
+func EpochNotMatchEvent(regionID uint64) *cdcpb.Event {
+	return errorEvent(regionID, &cdcpb.Error{EpochNotMatch: &errorpb.EpochNotMatch{}})
+}
+
+// NotLeaderEvent creates the error sent after the leader of the region is transferred to the store
+func NotLeaderEvent(regionID, leaderStoreID uint64) *cdcpb.Event {
+	return errorEvent(regionID, &cdcpb.Error{NotLeader: &errorpb.NotLeader{
+		RegionId: regionID,
+		Leader:   &metapb.Peer{StoreId: leaderStoreID},
+	}})
+}
+
+// RegionNotFoundEvent creates the error sent if the store doesn't have the region
+func RegionNotFoundEvent(regionID uint64) *cdcpb.Event {
+	return errorEvent(regionID, &cdcpb.Error{RegionNotFound: &errorpb.RegionNotFound{RegionId: regionID}})
+}
+
+// DuplicateRequestEvent creates the error sent if the region is subscribed twice in a stream
+func DuplicateRequestEvent(regionID uint64) *cdcpb.Event {
+	return errorEvent(regionID, &cdcpb.Error{DuplicateRequest: &cdcpb.Error_DuplicateRequest{RegionId: regionID}})
+}
@@ -0,0 +1,129 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pingcap/log"
+	tidbkv "github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/store/tikv"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"github.com/pingcap/tidb/store/tikv/tikvrpc"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	scanLockLimit = 1024
+
+	defaultResolveLockThreshold = time.Minute
+	defaultResolveLockRate      = 1.0
+)
+
+// tikvLockResolver resolves the locks in a region through the lock resolver of TiKV.
+// Only the expired locks are resolved, the locks of the ongoing transactions are kept.
+type tikvLockResolver struct {
+	store     tikv.Storage
+	threshold time.Duration
+	limiter   *rate.Limiter
+}
+
+// NewLockResolver creates a LockResolver which resolves the locks older than threshold,
+// at most rateLimit regions are resolved per second.
+func NewLockResolver(store tikv.Storage, threshold time.Duration, rateLimit float64) LockResolver {
+	return &tikvLockResolver{
+		store:     store,
+		threshold: threshold,
+		limiter:   rate.NewLimiter(rate.Limit(rateLimit), 1),
+	}
+}
+
+// Resolve implements LockResolver interface.
+func (r *tikvLockResolver) Resolve(ctx context.Context, regionID uint64, resolvedTs uint64) error {
+	if !r.limiter.Allow() {
+		resolveLockCounter.WithLabelValues("rate-limited").Inc()
+		log.Info("resolving locks is rate limited", zap.Uint64("regionID", regionID))
+		return nil
+	}
+	currentVer, err := r.store.CurrentVersion()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	maxVersion := oracle.ComposeTS(oracle.ExtractPhysical(currentVer.Ver)-r.threshold.Nanoseconds()/int64(time.Millisecond), 0)
+	if maxVersion <= resolvedTs {
+		// the locks which block the resolved ts are not old enough
+		return nil
+	}
+
+	bo := tikv.NewBackoffer(ctx, tikvRequestMaxBackoff)
+	loc, err := r.store.GetRegionCache().LocateRegionByID(bo, regionID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	req := tikvrpc.NewRequest(tikvrpc.CmdScanLock, &kvrpcpb.ScanLockRequest{
+		MaxVersion: maxVersion,
+		Limit:      scanLockLimit,
+	})
+	key := loc.StartKey
+	for {
+		req.ScanLock().StartKey = key
+		resp, err := r.store.SendReq(bo, req, loc.Region, tikv.ReadTimeoutMedium)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		regionErr, err := resp.GetRegionError()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if regionErr != nil {
+			// the region is changed, the locks are resolved when it stalls again
+			return errors.Errorf("scan locks of region %d failed: %s", regionID, regionErr)
+		}
+		if resp.Resp == nil {
+			return errors.Trace(tikv.ErrBodyMissing)
+		}
+		locksResp := resp.Resp.(*kvrpcpb.ScanLockResponse)
+		if locksResp.GetError() != nil {
+			return errors.Errorf("unexpected scan lock error: %s", locksResp)
+		}
+		locksInfo := locksResp.GetLocks()
+		locks := make([]*tikv.Lock, len(locksInfo))
+		for i := range locksInfo {
+			locks[i] = tikv.NewLock(locksInfo[i])
+		}
+		resolveLockCounter.WithLabelValues("scan").Add(float64(len(locks)))
+		log.Info("resolve locks of the stalled region",
+			zap.Uint64("regionID", regionID),
+			zap.Uint64("resolvedTs", resolvedTs),
+			zap.Uint64("maxVersion", maxVersion),
+			zap.Int("locks", len(locks)))
+
+		_, _, err = r.store.GetLockResolver().ResolveLocks(bo, currentVer.Ver, locks)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if len(locks) < scanLockLimit {
+			return nil
+		}
+		key = tidbkv.Key(locks[len(locks)-1].Key).Next()
+		if len(loc.EndKey) != 0 && bytes.Compare(key, loc.EndKey) >= 0 {
+			return nil
+		}
+	}
+}
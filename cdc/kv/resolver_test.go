@@ -0,0 +1,81 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pingcap/tidb/store/mockstore"
+	"github.com/pingcap/tidb/store/mockstore/mocktikv"
+	"github.com/pingcap/tidb/store/tikv"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+)
+
+type resolverSuite struct{}
+
+var _ = check.Suite(&resolverSuite{})
+
+func prewrite(c *check.C, mvccStore mocktikv.MVCCStore, key string, startTs uint64, ttl uint64) {
+	errs := mvccStore.Prewrite(&kvrpcpb.PrewriteRequest{
+		Mutations: []*kvrpcpb.Mutation{{
+			Op:    kvrpcpb.Op_Put,
+			Key:   []byte(key),
+			Value: []byte(key),
+		}},
+		PrimaryLock:  []byte(key),
+		StartVersion: startTs,
+		LockTtl:      ttl,
+	})
+	for _, err := range errs {
+		c.Assert(err, check.IsNil)
+	}
+}
+
+func (s *resolverSuite) TestResolveLocks(c *check.C) {
+	cluster := mocktikv.NewCluster()
+	_, _, regionID := mocktikv.BootstrapWithSingleStore(cluster)
+	mvccStore := mocktikv.MustNewMVCCStore()
+	store, err := mockstore.NewMockTikvStore(mockstore.WithCluster(cluster), mockstore.WithMVCCStore(mvccStore))
+	c.Assert(err, check.IsNil)
+	defer func() {
+		c.Assert(store.Close(), check.IsNil)
+	}()
+
+	now := time.Now()
+	oldTs := oracle.ComposeTS(oracle.GetPhysical(now.Add(-10*time.Minute)), 0)
+	// an expired lock left by a crashed client
+	prewrite(c, mvccStore, "a", oldTs, 1)
+	// a recent lock
+	prewrite(c, mvccStore, "c", oracle.ComposeTS(oracle.GetPhysical(now), 0), 1)
+
+	resolver := NewLockResolver(store.(tikv.Storage), time.Minute, 1)
+	err = resolver.Resolve(context.Background(), regionID, oldTs-1)
+	c.Assert(err, check.IsNil)
+
+	locks, err := mvccStore.ScanLock(nil, nil, oracle.ComposeTS(oracle.GetPhysical(now.Add(time.Minute)), 0))
+	c.Assert(err, check.IsNil)
+	c.Assert(locks, check.HasLen, 1)
+	c.Assert(string(locks[0].Key), check.Equals, "c")
+
+	// the second request is rate limited
+	prewrite(c, mvccStore, "d", oldTs+2, 1)
+	err = resolver.Resolve(context.Background(), regionID, oldTs-1)
+	c.Assert(err, check.IsNil)
+	locks, err = mvccStore.ScanLock(nil, nil, oracle.ComposeTS(oracle.GetPhysical(now.Add(time.Minute)), 0))
+	c.Assert(err, check.IsNil)
+	c.Assert(locks, check.HasLen, 2)
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultMaxInitializingRegionsPerStore = 64
+	defaultRegionInitTimeout              = time.Minute
+)
+
+var errRegionInitTimeout = errors.New("region sends no event in time after being subscribed")
+
+// scanLimiter limits the incremental scans of the regions, a region is initializing
+// until TiKV sends its INITIALIZED event.
+type scanLimiter struct {
+	perStore int64
+	// limiter limits the rate of the scans across the stores, it's nil if the rate is unlimited
+	limiter *rate.Limiter
+
+	mu     sync.Mutex
+	stores map[uint64]*semaphore.Weighted
+}
+
+func newScanLimiter(cfg *ClientConfig) *scanLimiter {
+	l := &scanLimiter{
+		perStore: int64(cfg.MaxInitializingRegionsPerStore),
+		stores:   make(map[uint64]*semaphore.Weighted),
+	}
+	if cfg.RegionScanRate > 0 {
+		l.limiter = rate.NewLimiter(rate.Limit(cfg.RegionScanRate), 1)
+	}
+	return l
+}
+
+// acquire waits until a region of the store is allowed to start the incremental scan,
+// release should be called after the region is initialized or unsubscribed.
+func (l *scanLimiter) acquire(ctx context.Context, storeID uint64) (release func(), err error) {
+	var sem *semaphore.Weighted
+	if l.perStore > 0 {
+		l.mu.Lock()
+		sem = l.stores[storeID]
+		if sem == nil {
+			sem = semaphore.NewWeighted(l.perStore)
+			l.stores[storeID] = sem
+		}
+		l.mu.Unlock()
+		if err := sem.Acquire(ctx, 1); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if l.limiter != nil {
+		if err := l.limiter.Wait(ctx); err != nil {
+			if sem != nil {
+				sem.Release(1)
+			}
+			return nil, errors.Trace(err)
+		}
+	}
+
+	gauge := initializingRegionGauge.WithLabelValues(strconv.FormatUint(storeID, 10))
+	gauge.Inc()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			gauge.Dec()
+			if sem != nil {
+				sem.Release(1)
+			}
+		})
+	}, nil
+}
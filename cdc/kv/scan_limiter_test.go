@@ -0,0 +1,131 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/kv/mockkv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+)
+
+type scanLimiterSuite struct{}
+
+var _ = check.Suite(&scanLimiterSuite{})
+
+func (s *scanLimiterSuite) TestInitializingRegionsLimit(c *check.C) {
+	l := newScanLimiter(&ClientConfig{MaxInitializingRegionsPerStore: 2})
+	release1, err := l.acquire(context.Background(), 1)
+	c.Assert(err, check.IsNil)
+	release2, err := l.acquire(context.Background(), 1)
+	c.Assert(err, check.IsNil)
+	// the limit is per store
+	release3, err := l.acquire(context.Background(), 2)
+	c.Assert(err, check.IsNil)
+	defer release3()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx, 1)
+	c.Assert(err, check.NotNil)
+
+	// releasing twice doesn't free more slots
+	release1()
+	release1()
+	release4, err := l.acquire(context.Background(), 1)
+	c.Assert(err, check.IsNil)
+	defer release4()
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx, 1)
+	c.Assert(err, check.NotNil)
+	release2()
+}
+
+func (s *scanLimiterSuite) TestScanRate(c *check.C) {
+	l := newScanLimiter(&ClientConfig{RegionScanRate: 10})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := l.acquire(context.Background(), uint64(i))
+		c.Assert(err, check.IsNil)
+		release()
+	}
+	c.Assert(time.Since(start), check.GreaterEqual, 150*time.Millisecond)
+}
+
+func (s *etcdSuite) TestLimitInitializingRegions(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cluster, region1 := mockkv.NewCluster(c, 1)
+	defer cluster.Close()
+	region2 := cluster.Split(region1, "m")
+	cfg := NewClientConfig()
+	cfg.MaxInitializingRegionsPerStore = 1
+	cli := newMockClient(c, cluster, cfg)
+	defer func() {
+		c.Assert(cli.Close(), check.IsNil)
+	}()
+
+	eventCh := make(chan *model.RegionFeedEvent, 64)
+	go func() {
+		_ = cli.EventFeed(ctx, util.Span{Start: []byte("a"), End: []byte("z")}, 100, eventCh)
+	}()
+	cluster.ExpectRequest(region1, "a", "m", 100)
+	cluster.ExpectNoRequest(200 * time.Millisecond)
+
+	// region 2 is subscribed after region 1 is initialized
+	cluster.Send(mockkv.InitializedEvent(region1), mockkv.ResolvedEvent(region1, 110))
+	cluster.ExpectRequest(region2, "m", "z", 100)
+	cluster.Send(mockkv.InitializedEvent(region2), mockkv.ResolvedEvent(region2, 120))
+	expectResolved(c, eventCh, "m", "z", 120)
+}
+
+// TestRegionInitTimeout checks a region which sends no event after being subscribed releases
+// its incremental scan slot and is resubscribed after the init timeout.
+func (s *etcdSuite) TestRegionInitTimeout(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cluster, region1 := mockkv.NewCluster(c, 1)
+	defer cluster.Close()
+	region2 := cluster.Split(region1, "m")
+	cfg := NewClientConfig()
+	cfg.MaxInitializingRegionsPerStore = 1
+	cfg.RegionInitTimeout = 300 * time.Millisecond
+	cli := newMockClient(c, cluster, cfg)
+	defer func() {
+		c.Assert(cli.Close(), check.IsNil)
+	}()
+
+	eventCh := make(chan *model.RegionFeedEvent, 64)
+	go func() {
+		_ = cli.EventFeed(ctx, util.Span{Start: []byte("a"), End: []byte("z")}, 100, eventCh)
+	}()
+	cluster.ExpectRequest(region1, "a", "m", 100)
+	stream1 := cluster.SubscribedStream(region1)
+
+	// region 1 sends nothing, so region 2 is subscribed after the timeout, and region 1 is
+	// resubscribed in another stream after region 2 is initialized
+	cluster.ExpectRequest(region2, "m", "z", 100)
+	cluster.Send(mockkv.InitializedEvent(region2), mockkv.ResolvedEvent(region2, 120))
+	expectResolved(c, eventCh, "m", "z", 120)
+	cluster.ExpectRequest(region1, "a", "m", 100)
+	c.Assert(cluster.SubscribedStream(region1), check.Not(check.Equals), stream1)
+	cluster.Send(mockkv.InitializedEvent(region1), mockkv.ResolvedEvent(region1, 110))
+	expectResolved(c, eventCh, "a", "m", 110)
+}
@@ -25,6 +25,8 @@ import (
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/pkg/security"
+	"github.com/pingcap/tidb/config"
 	tidbkv "github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
 )
@@ -47,6 +49,56 @@ func loadHistoryDDLJobs(tiStore tidbkv.Storage) ([]*model.Job, error) {
 	return jobs, nil
 }
 
+// historyDDLJobsBatchSize is the number of the history DDL jobs read in a batch by loadHistoryDDLJobsAfter
+const historyDDLJobsBatchSize = 128
+
+// loadHistoryDDLJobsAfter reads the history DDL jobs from the newest one, and returns the jobs whose schema
+// version is greater than schemaVersion. The jobs in a DDL queue are finished in the order of their IDs, but
+// an add index job, which is in its own queue, may finish after the jobs with greater IDs in the general queue.
+// So the scan stops only after a finished job not greater than schemaVersion is read from both the queues,
+// all the jobs are read if there is no such add index job.
+func loadHistoryDDLJobsAfter(tiStore tidbkv.Storage, schemaVersion int64) ([]*model.Job, error) {
+	snapMeta, err := getSnapshotMeta(tiStore)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	iter, err := snapMeta.GetLastHistoryDDLJobsIterator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var jobs, batch []*model.Job
+	generalQueueDone, addIndexQueueDone := false, false
+	for !generalQueueDone || !addIndexQueueDone {
+		batch, err = iter.GetLastJobs(historyDDLJobsBatchSize, batch)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, job := range batch {
+			if job.BinlogInfo.SchemaVersion > schemaVersion {
+				jobs = append(jobs, job)
+				continue
+			}
+			// the canceled jobs don't have a schema version
+			if job.State != model.JobStateSynced && job.State != model.JobStateDone {
+				continue
+			}
+			if job.Type == model.ActionAddIndex || job.Type == model.ActionAddPrimaryKey {
+				addIndexQueueDone = true
+			} else {
+				generalQueueDone = true
+			}
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].BinlogInfo.FinishedTS < jobs[j].BinlogInfo.FinishedTS
+	})
+	return jobs, nil
+}
+
 func getSnapshotMeta(tiStore tidbkv.Storage) (*meta.Meta, error) {
 	version, err := tiStore.CurrentVersion()
 	if err != nil {
@@ -61,16 +113,32 @@ func getSnapshotMeta(tiStore tidbkv.Storage) (*meta.Meta, error) {
 
 // LoadHistoryDDLJobs loads all history DDL jobs from TiDB.
 func LoadHistoryDDLJobs(kvStore tidbkv.Storage) ([]*model.Job, error) {
-	originalJobs, err := loadHistoryDDLJobs(kvStore)
-	jobs := make([]*model.Job, 0, len(originalJobs))
+	return LoadHistoryDDLJobsAfter(kvStore, 0)
+}
+
+// LoadHistoryDDLJobsAfter loads the history DDL jobs whose schema version is greater than schemaVersion from TiDB.
+// The jobs are read from the newest one, and the scan stops at the old jobs, see loadHistoryDDLJobsAfter.
+func LoadHistoryDDLJobsAfter(kvStore tidbkv.Storage, schemaVersion int64) ([]*model.Job, error) {
+	var originalJobs []*model.Job
+	var err error
+	if schemaVersion > 0 {
+		originalJobs, err = loadHistoryDDLJobsAfter(kvStore, schemaVersion)
+	} else {
+		originalJobs, err = loadHistoryDDLJobs(kvStore)
+	}
 	if err != nil {
 		return nil, err
 	}
+	jobs := make([]*model.Job, 0, len(originalJobs))
 	tikvStorage, ok := kvStore.(tikv.Storage)
 	for _, job := range originalJobs {
 		if job.State != model.JobStateSynced && job.State != model.JobStateDone {
 			continue
 		}
+		// the finished ts is reset by a request to TiKV, so the old jobs are skipped before it
+		if job.BinlogInfo.SchemaVersion <= schemaVersion {
+			continue
+		}
 		if ok {
 			err := resetFinishedTs(tikvStorage, job)
 			if err != nil {
@@ -82,6 +150,25 @@ func LoadHistoryDDLJobs(kvStore tidbkv.Storage) ([]*model.Job, error) {
 	return jobs, nil
 }
 
+// LoadSequenceValues loads the values of the sequences at ts, the keys of seqs are the IDs of the sequences
+// and the values are the IDs of their schemas. The value of a sequence is the max value allocated by TiDB.
+func LoadSequenceValues(kvStore tidbkv.Storage, ts uint64, seqs map[int64]int64) (map[int64]int64, error) {
+	snapshot, err := kvStore.GetSnapshot(tidbkv.NewVersion(ts))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	snapMeta := meta.NewSnapshotMeta(snapshot)
+	values := make(map[int64]int64, len(seqs))
+	for id, schemaID := range seqs {
+		value, err := snapMeta.GetSequenceValue(schemaID, id)
+		if err != nil {
+			return nil, errors.Annotatef(err, "load the value of sequence %d", id)
+		}
+		values[id] = value
+	}
+	return values, nil
+}
+
 func resetFinishedTs(kvStore tikv.Storage, job *model.Job) error {
 	helper := helper.NewHelper(kvStore)
 	diffKey := schemaDiffKey(job.BinlogInfo.SchemaVersion)
@@ -104,12 +191,21 @@ func resetFinishedTs(kvStore tikv.Storage, job *model.Job) error {
 }
 
 // CreateTiStore creates a new tikv storage client
-func CreateTiStore(urls string) (tidbkv.Storage, error) {
+func CreateTiStore(urls string, credential *security.Credential) (tidbkv.Storage, error) {
 	urlv, err := flags.NewURLsValue(urls)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 
+	if credential.IsTLSEnabled() {
+		// the tikv driver reads the certificates from the global config of TiDB
+		cfg := *config.GetGlobalConfig()
+		cfg.Security.ClusterSSLCA = credential.CAPath
+		cfg.Security.ClusterSSLCert = credential.CertPath
+		cfg.Security.ClusterSSLKey = credential.KeyPath
+		config.StoreGlobalConfig(&cfg)
+	}
+
 	// Ignore error if it is already registered.
 	_ = store.Register("tikv", tikv.Driver{})
 
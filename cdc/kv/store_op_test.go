@@ -15,6 +15,7 @@ package kv
 
 import (
 	"github.com/pingcap/check"
+	"github.com/pingcap/parser/model"
 	"github.com/pingcap/tidb/session"
 	"github.com/pingcap/tidb/store/mockstore"
 	"github.com/pingcap/tidb/store/mockstore/mocktikv"
@@ -57,3 +58,42 @@ func (s *storeSuite) TestLoadHistoryDDLJobs(c *check.C) {
 	_, ok := oldJobIDs[latestJobs[len(latestJobs)-1].ID]
 	c.Assert(ok, check.IsFalse)
 }
+
+func (s *storeSuite) TestLoadHistoryDDLJobsAfter(c *check.C) {
+	mockCluster := mocktikv.NewCluster()
+	mocktikv.BootstrapWithSingleStore(mockCluster)
+	mockMvccStore := mocktikv.MustNewMVCCStore()
+	store, err := mockstore.NewMockTikvStore(
+		mockstore.WithCluster(mockCluster),
+		mockstore.WithMVCCStore(mockMvccStore),
+	)
+	c.Assert(err, check.IsNil)
+
+	session.SetSchemaLease(0)
+	session.DisableStats4Test()
+	domain, err := session.BootstrapSession(store)
+	c.Assert(err, check.IsNil)
+	domain.SetStatsUpdating(true)
+
+	tk := testkit.NewTestKit(c, store)
+	tk.MustExec("create table test.t1 (id bigint primary key, a int)")
+	tk.MustExec("alter table test.t1 add index idx_a(a)")
+	jobs, err := LoadHistoryDDLJobs(store)
+	c.Assert(err, check.IsNil)
+	schemaVersion := jobs[len(jobs)-1].BinlogInfo.SchemaVersion
+
+	tk.MustExec("create table test.t2 (id bigint primary key, a int)")
+	tk.MustExec("alter table test.t2 add index idx_a(a)")
+	tk.MustExec("create table test.t3 (id bigint primary key)")
+	jobs, err = LoadHistoryDDLJobsAfter(store, schemaVersion)
+	c.Assert(err, check.IsNil)
+	c.Assert(jobs, check.HasLen, 3)
+	for i, tp := range []model.ActionType{model.ActionCreateTable, model.ActionAddIndex, model.ActionCreateTable} {
+		c.Assert(jobs[i].Type, check.Equals, tp)
+		c.Assert(jobs[i].BinlogInfo.SchemaVersion, check.Greater, schemaVersion)
+	}
+
+	jobs, err = LoadHistoryDDLJobsAfter(store, jobs[2].BinlogInfo.SchemaVersion)
+	c.Assert(err, check.IsNil)
+	c.Assert(jobs, check.HasLen, 0)
+}
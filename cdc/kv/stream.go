@@ -0,0 +1,440 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/cdcpb"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"go.uber.org/zap"
+)
+
+// maxStreamOrphans is the number of orphan subscriptions after which a stream is recycled
+// if they are more than the live ones.
+const maxStreamOrphans = 1024
+
+// regionEventChanSize is the number of the events buffered for a subscription, the subscription
+// is dropped if its handler falls behind, rather than blocking the other subscriptions of the stream.
+const regionEventChanSize = 128
+
+// errSubscriptionDropped is returned by the handler of a subscription dropped by the stream.
+var errSubscriptionDropped = errors.New("region subscription dropped since the events are not consumed in time")
+
+// eventFeedSession is the state of an EventFeed call. The regions of all the sessions
+// are subscribed through the streams shared by the CDCClient.
+type eventFeedSession struct {
+	ctx      context.Context
+	regionCh chan singleRegionInfo
+	errCh    chan regionErrorInfo
+	eventCh  chan<- *model.RegionFeedEvent
+	merger   *regionMerger
+}
+
+func (s *eventFeedSession) sendError(errInfo regionErrorInfo) {
+	select {
+	case s.errCh <- errInfo:
+	case <-s.ctx.Done():
+	}
+}
+
+// regionSubscription is a subscription of a region in a shared stream. The ChangeData protocol
+// doesn't carry a request ID, so the client assigns one to each request, and the events of
+// a region are routed to the subscription which owns the region in the stream.
+type regionSubscription struct {
+	requestID uint64
+	session   *eventFeedSession
+	sri       singleRegionInfo
+	// ch is the channel to the goroutine handling the events, it's nil until the first event arrives.
+	// ch and dropped are set by the receiver under the lock of the stream.
+	ch chan *cdcpb.Event
+	// dropped is closed if the subscription is dropped since its handler falls behind
+	dropped chan struct{}
+	stopped int32
+	// orphan is set if the subscription is abandoned by the client while TiKV still
+	// sends its events, it's protected by the lock of the stream.
+	orphan bool
+	// releaseScan is called after the region is initialized or unsubscribed
+	releaseScan func()
+}
+
+func (s *regionSubscription) isLive() bool {
+	return !s.orphan && atomic.LoadInt32(&s.stopped) == 0 && s.session.ctx.Err() == nil
+}
+
+// sharedStream is an EventFeed stream to a store shared by all the sessions of a CDCClient.
+// TiKV accepts only one subscription for a region in a stream, and the protocol doesn't
+// support canceling a subscription. So a region is subscribed in another stream of the store
+// if it's occupied, and a stream is recycled when most of its subscriptions are orphans.
+type sharedStream struct {
+	addr    string
+	storeID uint64
+	client  cdcpb.ChangeData_EventFeedClient
+	cancel  context.CancelFunc
+
+	sendMu sync.Mutex
+
+	mu            sync.Mutex
+	closed        bool
+	subscriptions map[uint64]*regionSubscription
+	orphans       int
+}
+
+// tryAdd adds the subscription if the region isn't subscribed in the stream.
+func (s *sharedStream) tryAdd(sub *regionSubscription) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	regionID := sub.sri.verID.GetID()
+	if s.closed {
+		return false
+	}
+	if _, ok := s.subscriptions[regionID]; ok {
+		return false
+	}
+	s.subscriptions[regionID] = sub
+	return true
+}
+
+// send sends the request of the subscription.
+func (s *sharedStream) send(req *cdcpb.ChangeDataRequest) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	return s.client.Send(req)
+}
+
+// takeLive abandons the live subscription of the region owned by the session.
+func (s *sharedStream) takeLive(session *eventFeedSession, regionID uint64) (*regionSubscription, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.subscriptions[regionID]
+	if !ok || sub.session != session || !sub.isLive() {
+		return nil, false
+	}
+	s.markOrphan(sub)
+	return sub, true
+}
+
+func (s *sharedStream) markOrphan(sub *regionSubscription) {
+	// the incremental scan of an orphan is not waited for
+	sub.releaseScan()
+	if !sub.orphan {
+		sub.orphan = true
+		s.orphans++
+	}
+}
+
+// shouldRecycle returns whether the stream should be closed for the orphan subscriptions.
+func (s *sharedStream) shouldRecycle() bool {
+	if s.orphans == 0 {
+		return false
+	}
+	live := len(s.subscriptions) - s.orphans
+	return live == 0 || (s.orphans >= maxStreamOrphans && s.orphans > live)
+}
+
+// route finds the subscription for the event of the region, a new handler should be
+// started for the subscription if start is true.
+func (s *sharedStream) route(event *cdcpb.Event) (sub *regionSubscription, start bool, recycle bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.subscriptions[event.RegionId]
+	if !ok {
+		return nil, false, false
+	}
+	if errEvent, isErr := event.Event.(*cdcpb.Event_Error); isErr {
+		if errEvent.Error.GetDuplicateRequest() != nil {
+			// TiKV keeps another subscription of the region in the stream, which is unknown to the
+			// client, so the region is kept occupied and the subscription is resubscribed elsewhere.
+			log.Warn("region is subscribed twice in a stream",
+				zap.Uint64("regionID", event.RegionId), zap.String("addr", s.addr))
+			live := sub.isLive()
+			s.markOrphan(sub)
+			if !live {
+				return nil, false, s.shouldRecycle()
+			}
+		} else {
+			// TiKV removes the subscription after reporting an error
+			delete(s.subscriptions, event.RegionId)
+			sub.releaseScan()
+			if sub.orphan {
+				s.orphans--
+				return nil, false, false
+			}
+			if !sub.isLive() {
+				return nil, false, false
+			}
+		}
+	} else if !sub.isLive() {
+		s.markOrphan(sub)
+		return nil, false, s.shouldRecycle()
+	} else if isInitializedEvent(event) {
+		sub.releaseScan()
+	}
+	if sub.ch == nil {
+		sub.ch = make(chan *cdcpb.Event, regionEventChanSize)
+		sub.dropped = make(chan struct{})
+		start = true
+	}
+	return sub, start, false
+}
+
+// abandon marks the stopped subscription of the region as an orphan. TiKV keeps sending its events
+// since the protocol doesn't support canceling a subscription, so they are dropped until the stream
+// is recycled, and the region is resubscribed in another stream of the store.
+func (s *sharedStream) abandon(regionID uint64) (recycle bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.subscriptions[regionID]
+	if !ok || sub.isLive() {
+		return false
+	}
+	s.markOrphan(sub)
+	return s.shouldRecycle()
+}
+
+// abandonPending abandons the subscription if no event of it has arrived, the incremental scan slot
+// of the subscription is released.
+func (s *sharedStream) abandonPending(sub *regionSubscription) (abandoned bool, recycle bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscriptions[sub.sri.verID.GetID()] != sub || sub.ch != nil || !sub.isLive() {
+		return false, false
+	}
+	s.markOrphan(sub)
+	return true, s.shouldRecycle()
+}
+
+// drop abandons the subscription whose handler doesn't consume the events in time. The dropped
+// channel of the subscription is closed, so the handler resubscribes the region from its checkpoint.
+// The event channel is kept open since the events may still be sent by coalescing the region.
+func (s *sharedStream) drop(sub *regionSubscription) (recycle bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscriptions[sub.sri.verID.GetID()] == sub {
+		s.markOrphan(sub)
+	}
+	select {
+	case <-sub.dropped:
+	default:
+		close(sub.dropped)
+	}
+	return s.shouldRecycle()
+}
+
+// handlerChannels returns the event channel and the dropped channel of the subscription,
+// they're nil if the handler of the subscription hasn't been started.
+func (s *sharedStream) handlerChannels(sub *regionSubscription) (chan<- *cdcpb.Event, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sub.ch, sub.dropped
+}
+
+// takeAll closes the stream and returns all the subscriptions.
+func (s *sharedStream) takeAll() map[uint64]*regionSubscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	subs := s.subscriptions
+	for _, sub := range subs {
+		sub.releaseScan()
+	}
+	s.subscriptions = make(map[uint64]*regionSubscription)
+	s.orphans = 0
+	return subs
+}
+
+// acquireStream adds the subscription to a stream of the store where the region isn't subscribed,
+// a new stream is created if the region is subscribed in all the existing streams.
+func (c *CDCClient) acquireStream(
+	addr string, storeID uint64, sub *regionSubscription, sri singleRegionInfo,
+) (*sharedStream, error) {
+	c.streamsMu.Lock()
+	streams := c.streams[addr]
+	c.streamsMu.Unlock()
+	for _, stream := range streams {
+		sri.stream = stream
+		sub.sri = sri
+		if stream.tryAdd(sub) {
+			return stream, nil
+		}
+	}
+
+	streamCtx, cancel := context.WithCancel(c.ctx)
+	client, err := c.getStream(streamCtx, addr)
+	if err != nil {
+		cancel()
+		return nil, errors.Trace(err)
+	}
+	stream := &sharedStream{
+		addr:          addr,
+		storeID:       storeID,
+		client:        client,
+		cancel:        cancel,
+		subscriptions: map[uint64]*regionSubscription{sri.verID.GetID(): sub},
+	}
+	sri.stream = stream
+	sub.sri = sri
+	c.streamsMu.Lock()
+	c.streams[addr] = append(c.streams[addr], stream)
+	streamCount := len(c.streams[addr])
+	c.streamsMu.Unlock()
+	streamGauge.WithLabelValues(addr).Inc()
+	log.Info("create new stream to store",
+		zap.String("addr", addr), zap.Uint64("storeID", storeID), zap.Int("streams", streamCount))
+
+	go c.receiveFromSharedStream(stream)
+	return stream, nil
+}
+
+// closeStream removes the stream from the pool and cancels it, the subscriptions of the stream
+// are notified by the receiver of the stream.
+func (c *CDCClient) closeStream(stream *sharedStream) {
+	c.streamsMu.Lock()
+	streams := c.streams[stream.addr]
+	for i, s := range streams {
+		if s == stream {
+			streams = append(streams[:i:i], streams[i+1:]...)
+			streamGauge.WithLabelValues(stream.addr).Dec()
+			break
+		}
+	}
+	if len(streams) == 0 {
+		delete(c.streams, stream.addr)
+	} else {
+		c.streams[stream.addr] = streams
+	}
+	c.streamsMu.Unlock()
+	stream.cancel()
+}
+
+// takeLiveSubscription abandons the live subscription of the region owned by the session in the store.
+func (c *CDCClient) takeLiveSubscription(session *eventFeedSession, addr string, regionID uint64) (*regionSubscription, bool) {
+	c.streamsMu.Lock()
+	streams := c.streams[addr]
+	c.streamsMu.Unlock()
+	for _, stream := range streams {
+		if sub, ok := stream.takeLive(session, regionID); ok {
+			return sub, true
+		}
+	}
+	return nil, false
+}
+
+// receiveFromSharedStream receives the events from the stream and routes them to the subscriptions.
+func (c *CDCClient) receiveFromSharedStream(stream *sharedStream) {
+	defer func() {
+		log.Info("stream to store closed", zap.String("addr", stream.addr), zap.Uint64("storeID", stream.storeID))
+		c.closeStream(stream)
+		for _, sub := range stream.takeAll() {
+			if !sub.isLive() {
+				continue
+			}
+			if sub.ch != nil {
+				// notify the handler to resubscribe the region
+				select {
+				case sub.ch <- nil:
+				case <-sub.session.ctx.Done():
+				}
+				continue
+			}
+			sub.session.sendError(regionErrorInfo{
+				singleRegionInfo: sub.sri,
+				err:              errors.New("pending region cancelled due to stream disconnecting"),
+			})
+		}
+	}()
+
+	for {
+		cevent, err := stream.client.Recv()
+		if err != nil {
+			if errors.Cause(err) != context.Canceled {
+				log.Error("failed to receive from stream",
+					zap.String("addr", stream.addr),
+					zap.Uint64("storeID", stream.storeID),
+					zap.Error(err))
+			}
+			return
+		}
+
+		for _, event := range cevent.Events {
+			sub, start, recycle := stream.route(event)
+			if recycle {
+				log.Info("recycle the stream with orphan subscriptions",
+					zap.String("addr", stream.addr), zap.Uint64("storeID", stream.storeID))
+				c.closeStream(stream)
+			}
+			if sub == nil {
+				continue
+			}
+			if start {
+				c.startRegionHandler(sub)
+			}
+			select {
+			case sub.ch <- event:
+			default:
+				log.Warn("region handler falls behind, drop the subscription",
+					zap.Uint64("regionID", event.RegionId), zap.String("addr", stream.addr))
+				if stream.drop(sub) {
+					c.closeStream(stream)
+				}
+			}
+		}
+	}
+}
+
+// checkRegionInit resubscribes the region if no event of it arrives after the init timeout, since
+// the handler and the stall watchdog of the region are started by the first event.
+func (c *CDCClient) checkRegionInit(stream *sharedStream, sub *regionSubscription) {
+	abandoned, recycle := stream.abandonPending(sub)
+	if recycle {
+		c.closeStream(stream)
+	}
+	if !abandoned {
+		return
+	}
+	log.Warn("region sends no event after being subscribed, resubscribe it",
+		zap.Uint64("regionID", sub.sri.verID.GetID()),
+		zap.Uint64("requestID", sub.requestID),
+		zap.String("addr", stream.addr),
+		zap.Duration("timeout", c.config.RegionInitTimeout))
+	sub.session.sendError(regionErrorInfo{singleRegionInfo: sub.sri, err: errRegionInitTimeout})
+}
+
+func (c *CDCClient) startRegionHandler(sub *regionSubscription) {
+	session := sub.session
+	go func() {
+		err := c.partialRegionFeed(session.ctx, sub.sri, sub.ch, sub.dropped, session.errCh, session.eventCh, &sub.stopped)
+		if err != nil {
+			atomic.StoreInt32(&sub.stopped, 1)
+			session.sendError(regionErrorInfo{singleRegionInfo: sub.sri, err: err})
+		}
+	}()
+}
+
+func isInitializedEvent(event *cdcpb.Event) bool {
+	entries, ok := event.Event.(*cdcpb.Event_Entries_)
+	if !ok {
+		return false
+	}
+	for _, entry := range entries.Entries.GetEntries() {
+		if entry.Type == cdcpb.Event_INITIALIZED {
+			return true
+		}
+	}
+	return false
+}
@@ -124,7 +124,7 @@ func mustGetValue(t require.TestingT, eventCh <-chan *model.RegionFeedEvent, val
 // TestSplit try split on every region, and test can get value event from
 // every region after split.
 func TestSplit(t require.TestingT, pdCli pd.Client, storage kv.Storage) {
-	cli, err := NewCDCClient(pdCli)
+	cli, err := NewCDCClient(pdCli, nil, nil)
 	require.NoError(t, err)
 	defer cli.Close()
 
@@ -211,7 +211,7 @@ func mustDeleteKey(t require.TestingT, storage kv.Storage, key []byte) {
 
 // TestGetKVSimple test simple KV operations
 func TestGetKVSimple(t require.TestingT, pdCli pd.Client, storage kv.Storage) {
-	cli, err := NewCDCClient(pdCli)
+	cli, err := NewCDCClient(pdCli, nil, nil)
 	require.NoError(t, err)
 	defer cli.Close()
 
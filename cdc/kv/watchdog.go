@@ -0,0 +1,211 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/pkg/util"
+)
+
+const (
+	// RegionStallActionNone only reports the stalled regions
+	RegionStallActionNone = "none"
+	// RegionStallActionReconnect resubscribes the stalled regions
+	RegionStallActionReconnect = "reconnect"
+	// RegionStallActionResolveLock resolves the locks which block the resolved ts of the stalled regions
+	RegionStallActionResolveLock = "resolve-lock"
+
+	defaultRegionStallDuration  = 3 * time.Minute
+	minRegionStallCheckInterval = time.Second
+)
+
+var errRegionStalled = errors.New("region resolved ts stalled")
+
+// LockResolver resolves the locks which block the resolved ts of a region
+type LockResolver interface {
+	// Resolve resolves the locks in the region which block the resolved ts from advancing
+	Resolve(ctx context.Context, regionID uint64, resolvedTs uint64) error
+}
+
+// ClientConfig is the config of CDCClient
+type ClientConfig struct {
+	// RegionStallDuration is the duration after which a region whose resolved ts
+	// doesn't advance is considered stalled, the check is disabled if it's 0
+	RegionStallDuration time.Duration
+	// RegionStallAction is the action taken on the stalled regions
+	RegionStallAction string
+	// LockResolver is used by the resolve-lock action
+	LockResolver LockResolver
+	// ResolveLockThreshold is the minimum age of the locks resolved by the resolve-lock action
+	ResolveLockThreshold time.Duration
+	// ResolveLockRate is the max number of regions whose locks are resolved per second
+	ResolveLockRate float64
+	// MaxInitializingRegionsPerStore is the max number of regions in incremental scan
+	// of each store, it's unlimited if it's 0
+	MaxInitializingRegionsPerStore int
+	// RegionScanRate is the max number of regions starting the incremental scan per second
+	// across the stores, it's unlimited if it's 0
+	RegionScanRate float64
+	// RegionInitTimeout is the duration after which a subscribed region without any event is
+	// resubscribed, so it doesn't hold the incremental scan slot of the store, it's disabled if it's 0
+	RegionInitTimeout time.Duration
+}
+
+// NewClientConfig creates a ClientConfig with the default values
+func NewClientConfig() *ClientConfig {
+	return &ClientConfig{
+		RegionStallDuration:  defaultRegionStallDuration,
+		RegionStallAction:    RegionStallActionNone,
+		ResolveLockThreshold: defaultResolveLockThreshold,
+		ResolveLockRate:      defaultResolveLockRate,
+
+		MaxInitializingRegionsPerStore: defaultMaxInitializingRegionsPerStore,
+		RegionInitTimeout:              defaultRegionInitTimeout,
+	}
+}
+
+// Validate checks the config
+func (cfg *ClientConfig) Validate() error {
+	if cfg.RegionStallDuration < 0 {
+		return errors.Errorf("invalid region stall duration: %s", cfg.RegionStallDuration)
+	}
+	switch cfg.RegionStallAction {
+	case RegionStallActionNone, RegionStallActionReconnect, RegionStallActionResolveLock:
+	default:
+		return errors.Errorf("invalid region stall action: %s", cfg.RegionStallAction)
+	}
+	if cfg.ResolveLockThreshold < 0 {
+		return errors.Errorf("invalid resolve lock threshold: %s", cfg.ResolveLockThreshold)
+	}
+	if cfg.ResolveLockRate <= 0 {
+		return errors.Errorf("invalid resolve lock rate: %f", cfg.ResolveLockRate)
+	}
+	if cfg.MaxInitializingRegionsPerStore < 0 {
+		return errors.Errorf("invalid max initializing regions per store: %d", cfg.MaxInitializingRegionsPerStore)
+	}
+	if cfg.RegionScanRate < 0 {
+		return errors.Errorf("invalid region scan rate: %f", cfg.RegionScanRate)
+	}
+	if cfg.RegionInitTimeout < 0 {
+		return errors.Errorf("invalid region init timeout: %s", cfg.RegionInitTimeout)
+	}
+	return nil
+}
+
+func (cfg *ClientConfig) stallCheckInterval() time.Duration {
+	interval := cfg.RegionStallDuration / 10
+	if interval < minRegionStallCheckInterval {
+		interval = minRegionStallCheckInterval
+	}
+	return interval
+}
+
+// StalledRegion is a region whose resolved ts doesn't advance
+type StalledRegion struct {
+	RegionID     uint64    `json:"region-id"`
+	Addr         string    `json:"addr"`
+	Span         util.Span `json:"span"`
+	ResolvedTs   uint64    `json:"resolved-ts"`
+	StalledSince time.Time `json:"stalled-since"`
+	CaptureID    string    `json:"capture-id"`
+	ChangefeedID string    `json:"changefeed-id"`
+}
+
+// stalledRegions keeps the stalled regions of all the CDCClients in the process
+var stalledRegions = struct {
+	sync.Mutex
+	regions map[*StalledRegion]struct{}
+}{regions: make(map[*StalledRegion]struct{})}
+
+func markRegionStalled(region *StalledRegion) {
+	stalledRegions.Lock()
+	defer stalledRegions.Unlock()
+	stalledRegions.regions[region] = struct{}{}
+	stalledRegionGauge.WithLabelValues(region.CaptureID).Inc()
+}
+
+func unmarkRegionStalled(region *StalledRegion) {
+	stalledRegions.Lock()
+	defer stalledRegions.Unlock()
+	if _, ok := stalledRegions.regions[region]; !ok {
+		return
+	}
+	delete(stalledRegions.regions, region)
+	stalledRegionGauge.WithLabelValues(region.CaptureID).Dec()
+}
+
+// StalledRegions returns the stalled regions in the process, sorted by the resolved ts
+func StalledRegions() []StalledRegion {
+	stalledRegions.Lock()
+	regions := make([]StalledRegion, 0, len(stalledRegions.regions))
+	for region := range stalledRegions.regions {
+		regions = append(regions, *region)
+	}
+	stalledRegions.Unlock()
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].ResolvedTs != regions[j].ResolvedTs {
+			return regions[i].ResolvedTs < regions[j].ResolvedTs
+		}
+		return regions[i].RegionID < regions[j].RegionID
+	})
+	return regions
+}
+
+// regionWatchdog checks whether the resolved ts of a region stops advancing
+type regionWatchdog struct {
+	cfg         *ClientConfig
+	region      StalledRegion
+	lastAdvance time.Time
+	stalled     *StalledRegion
+}
+
+func newRegionWatchdog(cfg *ClientConfig, region StalledRegion) *regionWatchdog {
+	return &regionWatchdog{cfg: cfg, region: region, lastAdvance: time.Now()}
+}
+
+// check updates the resolved ts of the region, it returns true if the region
+// becomes stalled and the action should be taken.
+func (w *regionWatchdog) check(now time.Time, initialized bool, resolvedTs uint64) bool {
+	// the resolved ts doesn't advance during the initialization
+	if !initialized || resolvedTs > w.region.ResolvedTs {
+		w.region.ResolvedTs = resolvedTs
+		w.lastAdvance = now
+		w.reset()
+		return false
+	}
+	if now.Sub(w.lastAdvance) < w.cfg.RegionStallDuration {
+		return false
+	}
+	// the action is taken again if the region is still stalled after another stall duration
+	w.lastAdvance = now
+	if w.stalled == nil {
+		region := w.region
+		region.StalledSince = now.Add(-w.cfg.RegionStallDuration)
+		w.stalled = &region
+		markRegionStalled(w.stalled)
+	}
+	return true
+}
+
+func (w *regionWatchdog) reset() {
+	if w.stalled != nil {
+		unmarkRegionStalled(w.stalled)
+		w.stalled = nil
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/kv/mockkv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+)
+
+type watchdogSuite struct{}
+
+var _ = check.Suite(&watchdogSuite{})
+
+func (s *watchdogSuite) TestClientConfig(c *check.C) {
+	cfg := NewClientConfig()
+	c.Assert(cfg.Validate(), check.IsNil)
+	cfg.RegionStallAction = "restart"
+	c.Assert(cfg.Validate(), check.NotNil)
+	cfg.RegionStallAction = RegionStallActionReconnect
+	cfg.RegionStallDuration = -time.Second
+	c.Assert(cfg.Validate(), check.NotNil)
+	cfg.RegionStallDuration = time.Second
+	cfg.ResolveLockRate = 0
+	c.Assert(cfg.Validate(), check.NotNil)
+	cfg.ResolveLockRate = defaultResolveLockRate
+	c.Assert(cfg.Validate(), check.IsNil)
+	cfg.MaxInitializingRegionsPerStore = -1
+	c.Assert(cfg.Validate(), check.NotNil)
+	cfg.MaxInitializingRegionsPerStore = 0
+	cfg.RegionScanRate = -1
+	c.Assert(cfg.Validate(), check.NotNil)
+	cfg.RegionScanRate = 0
+	cfg.RegionInitTimeout = -time.Second
+	c.Assert(cfg.Validate(), check.NotNil)
+	cfg.RegionInitTimeout = 0
+	c.Assert(cfg.Validate(), check.IsNil)
+
+	cfg.RegionStallDuration = time.Minute
+	c.Assert(cfg.stallCheckInterval(), check.Equals, 6*time.Second)
+	cfg.RegionStallDuration = time.Second
+	c.Assert(cfg.stallCheckInterval(), check.Equals, minRegionStallCheckInterval)
+}
+
+func (s *watchdogSuite) TestRegionWatchdog(c *check.C) {
+	cfg := &ClientConfig{RegionStallDuration: time.Minute}
+	start := time.Now()
+	w := newRegionWatchdog(cfg, StalledRegion{RegionID: 1, ResolvedTs: 100})
+	w.lastAdvance = start
+	defer w.reset()
+
+	// the region is not stalled during the initialization
+	c.Assert(w.check(start.Add(2*time.Minute), false, 100), check.IsFalse)
+	c.Assert(w.check(start.Add(150*time.Second), true, 100), check.IsFalse)
+	c.Assert(w.check(start.Add(3*time.Minute), true, 110), check.IsFalse)
+	c.Assert(w.check(start.Add(230*time.Second), true, 110), check.IsFalse)
+	c.Assert(StalledRegions(), check.HasLen, 0)
+
+	c.Assert(w.check(start.Add(4*time.Minute), true, 110), check.IsTrue)
+	regions := StalledRegions()
+	c.Assert(regions, check.HasLen, 1)
+	c.Assert(regions[0].RegionID, check.Equals, uint64(1))
+	c.Assert(regions[0].ResolvedTs, check.Equals, uint64(110))
+	c.Assert(regions[0].StalledSince, check.Equals, start.Add(3*time.Minute))
+
+	// the action is taken again after another stall duration
+	c.Assert(w.check(start.Add(270*time.Second), true, 110), check.IsFalse)
+	c.Assert(w.check(start.Add(5*time.Minute), true, 110), check.IsTrue)
+	c.Assert(StalledRegions(), check.HasLen, 1)
+
+	c.Assert(w.check(start.Add(6*time.Minute), true, 120), check.IsFalse)
+	c.Assert(StalledRegions(), check.HasLen, 0)
+}
+
+func (s *etcdSuite) TestReconnectStalledRegion(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cluster, region := mockkv.NewCluster(c, 1)
+	defer cluster.Close()
+	cfg := &ClientConfig{RegionStallDuration: 100 * time.Millisecond, RegionStallAction: RegionStallActionReconnect}
+	cli := newMockClient(c, cluster, cfg)
+	defer func() {
+		c.Assert(cli.Close(), check.IsNil)
+	}()
+
+	eventCh := make(chan *model.RegionFeedEvent, 64)
+	go func() {
+		_ = cli.EventFeed(ctx, util.Span{Start: []byte("a"), End: []byte("z")}, 100, eventCh)
+	}()
+	cluster.ExpectRequest(region, "a", "z", 100)
+	cluster.Send(mockkv.InitializedEvent(region), mockkv.ResolvedEvent(region, 120))
+	expectResolved(c, eventCh, "a", "z", 120)
+
+	// the region is resubscribed from its resolved ts after it stalls
+	cluster.ExpectRequest(region, "a", "z", 120)
+	cluster.Send(mockkv.InitializedEvent(region), mockkv.ResolvedEvent(region, 130))
+	expectResolved(c, eventCh, "a", "z", 130)
+}
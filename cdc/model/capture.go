@@ -19,9 +19,20 @@ import (
 	"github.com/pingcap/errors"
 )
 
+// Well-known labels of a capture
+const (
+	CaptureLabelZone = "zone"
+	CaptureLabelHost = "host"
+)
+
 // CaptureInfo store in etcd.
 type CaptureInfo struct {
 	ID string `json:"id"`
+	// Labels describe the location and other properties of the capture,
+	// they are used to schedule tables according to the placement constraints.
+	Labels map[string]string `json:"labels,omitempty"`
+	// AdvertiseAddr is the address of the status server, the CLI sends the admin jobs to the owner by it.
+	AdvertiseAddr string `json:"address,omitempty"`
 }
 
 // Marshal using json.Marshal.
@@ -24,6 +24,18 @@ import (
 	"github.com/pingcap/tidb/store/tikv/oracle"
 )
 
+// FeedState represents the running state of a changefeed
+type FeedState string
+
+// All FeedStates
+const (
+	StateNormal   FeedState = "normal"
+	StateStopped  FeedState = "stopped"
+	StateRemoved  FeedState = "removed"
+	StateFinished FeedState = "finished"
+	StateFailed   FeedState = "failed"
+)
+
 // ChangeFeedInfo describes the detail of a ChangeFeed
 type ChangeFeedInfo struct {
 	SinkURI    string            `json:"sink-uri"`
@@ -35,10 +47,27 @@ type ChangeFeedInfo struct {
 	TargetTs uint64 `json:"target-ts"`
 	// used for admin job notification, trigger watch event in capture
 	AdminJobType AdminJobType `json:"admin-job-type"`
+	// State records the running state of the changefeed, it is updated by owner
+	State FeedState `json:"state"`
+	// Error records the reason why the changefeed failed
+	Error string `json:"error,omitempty"`
+	// FailedDDLTs is the finished ts of the DDL which failed to execute and stopped the changefeed,
+	// the DDL is executed again when the changefeed is resumed.
+	FailedDDLTs uint64 `json:"failed-ddl-ts,omitempty"`
+	// DDLOverrides are the DDLs skipped or replaced by the admin
+	DDLOverrides []*DDLOverride `json:"ddl-overrides,omitempty"`
 
 	Config *util.ReplicaConfig `json:"config"`
 }
 
+// DDLOverride overrides the DDL finished at Ts, the DDL is skipped if Skip is true,
+// otherwise Query is executed instead of the query of the DDL.
+type DDLOverride struct {
+	Ts    uint64 `json:"ts"`
+	Skip  bool   `json:"skip,omitempty"`
+	Query string `json:"query,omitempty"`
+}
+
 // GetConfig returns ReplicaConfig.
 func (info *ChangeFeedInfo) GetConfig() *util.ReplicaConfig {
 	if info.Config == nil {
@@ -47,6 +76,14 @@ func (info *ChangeFeedInfo) GetConfig() *util.ReplicaConfig {
 	return info.Config
 }
 
+// GetState returns State if it's specified, otherwise StateNormal is returned.
+func (info *ChangeFeedInfo) GetState() FeedState {
+	if len(info.State) == 0 {
+		return StateNormal
+	}
+	return info.State
+}
+
 // GetStartTs returns StartTs if it's  specified or using the CreateTime of changefeed.
 func (info *ChangeFeedInfo) GetStartTs() uint64 {
 	if info.StartTs > 0 {
@@ -72,6 +109,49 @@ func (info *ChangeFeedInfo) GetTargetTs() uint64 {
 	return uint64(math.MaxUint64)
 }
 
+// GetDDLOverride returns the override of the DDL finished at ts, nil is returned if there is no such override.
+func (info *ChangeFeedInfo) GetDDLOverride(ts uint64) *DDLOverride {
+	for _, override := range info.DDLOverrides {
+		if override.Ts == ts {
+			return override
+		}
+	}
+	return nil
+}
+
+// SetDDLOverride adds the override or replaces the one of the same DDL, the overrides
+// of the DDLs finished before checkpointTs are removed since they are executed.
+func (info *ChangeFeedInfo) SetDDLOverride(override *DDLOverride, checkpointTs uint64) {
+	overrides := make([]*DDLOverride, 0, len(info.DDLOverrides)+1)
+	for _, o := range info.DDLOverrides {
+		if o.Ts >= checkpointTs && o.Ts != override.Ts {
+			overrides = append(overrides, o)
+		}
+	}
+	info.DDLOverrides = append(overrides, override)
+}
+
+// OverrideDDL records the override of the DDL in the info of the stopped changefeed, the changefeed is
+// resumed by the info and status if it's stopped by the failure of the DDL, it continues from the DDL.
+func (info *ChangeFeedInfo) OverrideDDL(status *ChangeFeedStatus, override *DDLOverride) (resumed bool, err error) {
+	if status == nil || !status.AdminJobType.IsStopState() {
+		return false, errors.New("the changefeed is not stopped")
+	}
+	checkpointTs := info.GetCheckpointTs(status)
+	if override.Ts < checkpointTs {
+		return false, errors.Errorf("the DDL at %d is executed, the checkpoint ts is %d", override.Ts, checkpointTs)
+	}
+	info.SetDDLOverride(override, checkpointTs)
+	if info.FailedDDLTs != override.Ts || status.AdminJobType != AdminStop {
+		return false, nil
+	}
+	info.AdminJobType = AdminResume
+	info.State = StateNormal
+	info.Error = ""
+	status.AdminJobType = AdminResume
+	return true, nil
+}
+
 // Marshal returns the json marshal format of a ChangeFeedInfo
 func (info *ChangeFeedInfo) Marshal() (string, error) {
 	data, err := json.Marshal(info)
@@ -20,14 +20,17 @@ import (
 // common errors
 // use a language builtin error type without error trace stack
 var (
-	ErrWriteTsConflict        = errors.New("write ts conflict")
-	ErrChangeFeedNotExists    = errors.New("changefeed not exists")
-	ErrTaskStatusNotExists    = errors.New("task status not exists")
-	ErrTaskPositionNotExists  = errors.New("task position not exists")
-	ErrWriteTaskStatusConlict = errors.New("write task status conflict")
-	ErrFindPLockNotCommit     = errors.New("task status has p-lock not committed")
-	ErrAdminStopProcessor     = errors.New("stop processor by admin command")
-	ErrExecDDLFailed          = errors.New("exec DDL failed")
-	ErrCaptureNotExist        = errors.New("capture not exists")
-	ErrUnresolved             = errors.New("the ts more than resolvedTs")
+	ErrWriteTsConflict         = errors.New("write ts conflict")
+	ErrChangeFeedNotExists     = errors.New("changefeed not exists")
+	ErrTaskStatusNotExists     = errors.New("task status not exists")
+	ErrTaskPositionNotExists   = errors.New("task position not exists")
+	ErrWriteTaskStatusConlict  = errors.New("write task status conflict")
+	ErrFindPLockNotCommit      = errors.New("task status has p-lock not committed")
+	ErrAdminStopProcessor      = errors.New("stop processor by admin command")
+	ErrExecDDLFailed           = errors.New("exec DDL failed")
+	ErrCaptureNotExist         = errors.New("capture not exists")
+	ErrUnresolved              = errors.New("the ts more than resolvedTs")
+	ErrStartTsBeforeGC         = errors.New("fail to create changefeed because start-ts is earlier than gc safepoint")
+	ErrCheckpointBeforeGC      = errors.New("the checkpoint ts of changefeed is earlier than gc safepoint")
+	ErrSchemaSnapshotNotExists = errors.New("schema snapshot not exists")
 )
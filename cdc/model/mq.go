@@ -29,6 +29,8 @@ type MqMessageKey struct {
 	Schema string        `json:"schema,omitempty"`
 	Table  string        `json:"table,omitempty"`
 	Type   MqMessageType `json:"type"`
+	// SchemaVersion is the version of the table schema of the row, it's set only if it's enabled in the sink
+	SchemaVersion uint64 `json:"schema_version,omitempty"`
 }
 
 // Encode encodes the message to the json bytes
@@ -72,8 +74,9 @@ func (m *MqMessageRow) Decode(data []byte) error {
 
 // MqMessageDDL represents the DDL message value
 type MqMessageDDL struct {
-	Query string           `json:"query"`
-	Type  model.ActionType `json:"type"`
+	Query       string           `json:"query"`
+	Type        model.ActionType `json:"type"`
+	TableSchema *TableSchema     `json:"table_schema,omitempty"`
 }
 
 // Encode encodes the message to the json bytes
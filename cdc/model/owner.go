@@ -57,6 +57,11 @@ type AdminJobType int
 type AdminJob struct {
 	CfID string
 	Type AdminJobType
+	// Error is set when the job is issued by owner because the changefeed
+	// can't go on, the changefeed will be marked as failed.
+	Error string
+	// DDLOverride is the override recorded by the AdminOverrideDDL job
+	DDLOverride *DDLOverride
 }
 
 // All AdminJob types
@@ -65,6 +70,8 @@ const (
 	AdminStop
 	AdminResume
 	AdminRemove
+	AdminFinish
+	AdminOverrideDDL
 )
 
 // String implements fmt.Stringer interface.
@@ -78,10 +85,23 @@ func (t AdminJobType) String() string {
 		return "resume changefeed"
 	case AdminRemove:
 		return "remove changefeed"
+	case AdminFinish:
+		return "finish changefeed"
+	case AdminOverrideDDL:
+		return "override DDL of changefeed"
 	}
 	return "unknown"
 }
 
+// IsStopState returns whether the admin job stops the changefeed.
+func (t AdminJobType) IsStopState() bool {
+	switch t {
+	case AdminStop, AdminRemove, AdminFinish:
+		return true
+	}
+	return false
+}
+
 // TaskPosition records the process information of a capture
 type TaskPosition struct {
 	// The maximum event CommitTs that has been synchronized. This is updated by corresponding processor.
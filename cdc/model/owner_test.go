@@ -93,3 +93,17 @@ func (s *removeTableSuite) TestShouldHandleTableNotFoundCorrectly(c *check.C) {
 	c.Assert(found, check.IsFalse)
 	c.Assert(t, check.IsNil)
 }
+
+type adminJobTypeSuite struct{}
+
+var _ = check.Suite(&adminJobTypeSuite{})
+
+func (s *adminJobTypeSuite) TestIsStopState(c *check.C) {
+	c.Assert(AdminNone.IsStopState(), check.IsFalse)
+	c.Assert(AdminResume.IsStopState(), check.IsFalse)
+	c.Assert(AdminStop.IsStopState(), check.IsTrue)
+	c.Assert(AdminRemove.IsStopState(), check.IsTrue)
+	c.Assert(AdminFinish.IsStopState(), check.IsTrue)
+	c.Assert(AdminFinish.String(), check.Equals, "finish changefeed")
+	c.Assert(AdminOverrideDDL.IsStopState(), check.IsFalse)
+}
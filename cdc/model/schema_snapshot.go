@@ -0,0 +1,43 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+)
+
+// SchemaSnapshot is the persisted schemas and tables of a changefeed at Ts, they're stored
+// as the DDL jobs creating them. SchemaVersion is the version of the last DDL job finished
+// before Ts, so only the newer DDL jobs are loaded when the snapshot is used.
+type SchemaSnapshot struct {
+	Ts            uint64         `json:"ts"`
+	SchemaVersion int64          `json:"schema-version"`
+	Jobs          []*timodel.Job `json:"jobs"`
+}
+
+// Marshal returns the json marshal format of a SchemaSnapshot
+func (s *SchemaSnapshot) Marshal() ([]byte, error) {
+	data, err := json.Marshal(s)
+	return data, errors.Trace(err)
+}
+
+// Unmarshal unmarshals into *SchemaSnapshot from json marshal byte slice
+func (s *SchemaSnapshot) Unmarshal(data []byte) error {
+	// the data of a snapshot is too large to be printed in the error
+	err := json.Unmarshal(data, s)
+	return errors.Annotate(err, "Unmarshal schema snapshot")
+}
@@ -20,6 +20,9 @@ type RowChangedEvent struct {
 
 	Delete bool
 
+	// SchemaVersion is the version of the table schema which the row is mounted with
+	SchemaVersion uint64
+
 	// if the table of this row only has one unique index(includes primary key),
 	// IndieMarkCol will be set to the name of the unique index
 	IndieMarkCol string
@@ -29,10 +32,11 @@ type RowChangedEvent struct {
 // ToMqMessage transforms to message key and value
 func (e *RowChangedEvent) ToMqMessage() (*MqMessageKey, *MqMessageRow) {
 	key := &MqMessageKey{
-		Ts:     e.Ts,
-		Schema: e.Schema,
-		Table:  e.Table,
-		Type:   MqMessageTypeRow,
+		Ts:            e.Ts,
+		Schema:        e.Schema,
+		Table:         e.Table,
+		Type:          MqMessageTypeRow,
+		SchemaVersion: e.SchemaVersion,
 	}
 	value := &MqMessageRow{}
 	if e.Delete {
@@ -49,6 +53,7 @@ func (e *RowChangedEvent) FromMqMessage(key *MqMessageKey, value *MqMessageRow)
 	e.Resolved = false
 	e.Table = key.Table
 	e.Schema = key.Schema
+	e.SchemaVersion = key.SchemaVersion
 
 	if len(value.Delete) != 0 {
 		e.Delete = true
@@ -90,6 +95,30 @@ func (c *Column) formatVal() {
 	}
 }
 
+// TableSchema represents the structured schema of a table, the columns are the ones in the row changed events
+type TableSchema struct {
+	ID      int64           `json:"id"`
+	Version uint64          `json:"version"`
+	Columns []*ColumnSchema `json:"columns"`
+	Keys    []*KeySchema    `json:"keys,omitempty"`
+}
+
+// ColumnSchema represents the schema of a column, FullType is the type shown in information_schema, e.g. `int(11) unsigned`
+type ColumnSchema struct {
+	Name     string `json:"name"`
+	Type     byte   `json:"type"`
+	FullType string `json:"full_type"`
+	Nullable bool   `json:"nullable"`
+}
+
+// KeySchema represents the schema of a primary key, an unique key or an index
+type KeySchema struct {
+	Name    string   `json:"name"`
+	Primary bool     `json:"primary,omitempty"`
+	Unique  bool     `json:"unique,omitempty"`
+	Columns []string `json:"columns"`
+}
+
 // DDLEvent represents a DDL event
 type DDLEvent struct {
 	Ts     uint64
@@ -97,6 +126,9 @@ type DDLEvent struct {
 	Table  string
 	Query  string
 	Type   model.ActionType
+	// TableSchema is the schema of the table after the DDL is executed,
+	// it's nil if the DDL doesn't change a table or the table is dropped
+	TableSchema *TableSchema
 }
 
 // ToMqMessage transforms to message key and value
@@ -108,8 +140,9 @@ func (e *DDLEvent) ToMqMessage() (*MqMessageKey, *MqMessageDDL) {
 		Type:   MqMessageTypeDDL,
 	}
 	value := &MqMessageDDL{
-		Query: e.Query,
-		Type:  e.Type,
+		Query:       e.Query,
+		Type:        e.Type,
+		TableSchema: e.TableSchema,
 	}
 	return key, value
 }
@@ -121,6 +154,7 @@ func (e *DDLEvent) FromMqMessage(key *MqMessageKey, value *MqMessageDDL) {
 	e.Schema = key.Schema
 	e.Type = value.Type
 	e.Query = value.Query
+	e.TableSchema = value.TableSchema
 }
 
 // FromJob fills the values of DDLEvent from DDL job
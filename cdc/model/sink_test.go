@@ -2,6 +2,7 @@ package model
 
 import (
 	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
 	"github.com/pingcap/parser/mysql"
 )
 
@@ -21,3 +22,50 @@ func (s *columnSuite) TestFormatCol(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(row2, check.DeepEquals, row)
 }
+
+func (s *columnSuite) TestMessageWithSchema(c *check.C) {
+	row := &RowChangedEvent{
+		Ts:            1,
+		Schema:        "test",
+		Table:         "t1",
+		SchemaVersion: 2,
+		Columns:       map[string]*Column{"id": {Type: mysql.TypeLonglong, WhereHandle: true, Value: int64(1)}},
+	}
+	key, _ := row.ToMqMessage()
+	keyEncode, err := key.Encode()
+	c.Assert(err, check.IsNil)
+	key2 := new(MqMessageKey)
+	c.Assert(key2.Decode(keyEncode), check.IsNil)
+	c.Assert(key2.SchemaVersion, check.Equals, uint64(2))
+
+	// the schema version is omitted if it isn't set
+	key.SchemaVersion = 0
+	keyEncode, err = key.Encode()
+	c.Assert(err, check.IsNil)
+	c.Assert(string(keyEncode), check.Equals, `{"ts":1,"schema":"test","table":"t1","type":1}`)
+
+	ddl := &DDLEvent{
+		Ts:     3,
+		Schema: "test",
+		Table:  "t1",
+		Query:  "alter table t1 add column c int",
+		Type:   timodel.ActionAddColumn,
+		TableSchema: &TableSchema{
+			ID:      50,
+			Version: 3,
+			Columns: []*ColumnSchema{
+				{Name: "id", Type: mysql.TypeLonglong, FullType: "bigint(20)"},
+				{Name: "c", Type: mysql.TypeLong, FullType: "int(11)", Nullable: true},
+			},
+			Keys: []*KeySchema{{Name: "PRIMARY", Primary: true, Unique: true, Columns: []string{"id"}}},
+		},
+	}
+	ddlKey, value := ddl.ToMqMessage()
+	valueEncode, err := value.Encode()
+	c.Assert(err, check.IsNil)
+	value2 := new(MqMessageDDL)
+	c.Assert(value2.Decode(valueEncode), check.IsNil)
+	ddl2 := new(DDLEvent)
+	ddl2.FromMqMessage(ddlKey, value2)
+	c.Assert(ddl2, check.DeepEquals, ddl)
+}
@@ -32,17 +32,37 @@ import (
 	"github.com/pingcap/ticdc/cdc/roles"
 	"github.com/pingcap/ticdc/cdc/roles/storage"
 	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
+	tidbkv "github.com/pingcap/tidb/kv"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/clientv3/concurrency"
 	"go.etcd.io/etcd/mvcc"
 	"go.etcd.io/etcd/mvcc/mvccpb"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
 )
 
 const (
 	captureInfoWatchRetryDelay = time.Millisecond * 500
+	ownerEventWatchRetryDelay  = time.Millisecond * 500
+
+	// ownerSafetyRunInterval is the max interval between two rounds of owner work
+	// when no event is received, it prevents the owner from stalling if some events are missed.
+	ownerSafetyRunInterval = 5 * time.Second
+	// ownerMinRunInterval is the min interval between two rounds of owner work,
+	// the events received in this interval are handled in one round.
+	ownerMinRunInterval = 50 * time.Millisecond
+	// maxOwnerEvents is the max number of the watched events kept for the next round of owner work,
+	// all the changefeeds are reloaded if more events are received.
+	maxOwnerEvents = 10240
+
+	gcSafePointUpdateInterval = time.Minute
+	// sequenceSyncInterval is the interval of synchronizing the values of the sequences to the sink
+	sequenceSyncInterval = 30 * time.Second
+	// defaultGCTTL is the TTL of the service safepoint registered by CDC, in seconds.
+	defaultGCTTL = 24 * 60 * 60
 )
 
 type tableIDMap = map[uint64]struct{}
@@ -89,6 +109,14 @@ type changeFeed struct {
 	taskPositions map[string]*model.TaskPosition
 	filter        *util.Filter
 	sink          sink.Sink
+	kvStore       tidbkv.Storage
+
+	// creatingSafePointRemoved is set after the service safepoint registered when creating the changefeed is removed
+	creatingSafePointRemoved bool
+
+	// sequenceValues are the values of the sequences synchronized to the sink
+	sequenceValues   map[uint64]int64
+	lastSequenceSync time.Time
 
 	ddlHandler    OwnerDDLHandler
 	ddlResolvedTs uint64
@@ -99,7 +127,9 @@ type changeFeed struct {
 	tables        map[uint64]entry.TableName
 	orphanTables  map[uint64]model.ProcessTableInfo
 	toCleanTables map[uint64]struct{}
-	infoWriter    *storage.OwnerTaskStatusEtcdWriter
+	// movingTables are the tables in toCleanTables which will be re-added after cleaned
+	movingTables map[uint64]struct{}
+	infoWriter   *storage.OwnerTaskStatusEtcdWriter
 }
 
 // String implements fmt.Stringer interface.
@@ -178,10 +208,40 @@ func (c *changeFeed) removeTable(sid, tid uint64) {
 	} else {
 		c.toCleanTables[tid] = struct{}{}
 	}
+	delete(c.movingTables, tid)
+}
+
+// placementCandidates returns the captures which satisfy the required labels
+// and match the most preferred labels of the changefeed, and the number of
+// the matched preferred labels.
+func (c *changeFeed) placementCandidates(captures map[string]*model.CaptureInfo) (map[string]*model.CaptureInfo, int) {
+	placement := c.info.GetConfig().Placement
+	candidates := make(map[string]*model.CaptureInfo, len(captures))
+	maxScore := -1
+	for id, capture := range captures {
+		if !placement.Satisfied(capture.Labels) {
+			continue
+		}
+		score := placement.Score(capture.Labels)
+		if score > maxScore {
+			maxScore = score
+			candidates = make(map[string]*model.CaptureInfo, len(captures))
+		}
+		if score == maxScore {
+			candidates[id] = capture
+		}
+	}
+	return candidates, maxScore
 }
 
 func (c *changeFeed) selectCapture(captures map[string]*model.CaptureInfo) string {
-	return c.minimumTablesCapture(captures)
+	candidates, _ := c.placementCandidates(captures)
+	if len(candidates) == 0 && len(captures) > 0 {
+		log.Warn("no capture satisfies the placement constraints",
+			zap.String("changefeed", c.id),
+			zap.Reflect("placement", c.info.GetConfig().Placement))
+	}
+	return c.minimumTablesCapture(candidates)
 }
 
 func (c *changeFeed) minimumTablesCapture(captures map[string]*model.CaptureInfo) string {
@@ -200,6 +260,9 @@ func (c *changeFeed) minimumTablesCapture(captures map[string]*model.CaptureInfo
 	var minID string
 
 	for id, pinfo := range c.taskStatus {
+		if _, ok := captures[id]; !ok {
+			continue
+		}
 		if len(pinfo.TableInfos) < minCount {
 			minID = id
 			minCount = len(pinfo.TableInfos)
@@ -209,7 +272,43 @@ func (c *changeFeed) minimumTablesCapture(captures map[string]*model.CaptureInfo
 	return minID
 }
 
+// balancePlacement moves the tables away from the captures which don't satisfy the
+// required labels, or match fewer preferred labels than the best alive captures.
+// The moved tables are re-added from the checkpoint ts of the changefeed after
+// they are removed from the original captures.
+func (c *changeFeed) balancePlacement(captures map[string]*model.CaptureInfo) {
+	if c.info.GetConfig().Placement == nil {
+		return
+	}
+	candidates, maxScore := c.placementCandidates(captures)
+	if len(candidates) == 0 {
+		return
+	}
+	placement := c.info.GetConfig().Placement
+	for captureID, status := range c.taskStatus {
+		capture, ok := captures[captureID]
+		if !ok {
+			continue
+		}
+		if placement.Satisfied(capture.Labels) && placement.Score(capture.Labels) >= maxScore {
+			continue
+		}
+		for _, table := range status.TableInfos {
+			if _, ok := c.toCleanTables[table.ID]; ok {
+				continue
+			}
+			log.Info("move table for placement constraints",
+				zap.String("changefeed", c.id),
+				zap.Uint64("table id", table.ID),
+				zap.String("capture", captureID))
+			c.toCleanTables[table.ID] = struct{}{}
+			c.movingTables[table.ID] = struct{}{}
+		}
+	}
+}
+
 func (c *changeFeed) tryBalance(ctx context.Context, captures map[string]*model.CaptureInfo) {
+	c.balancePlacement(captures)
 	c.cleanTables(ctx)
 	c.banlanceOrphanTables(ctx, captures)
 }
@@ -227,6 +326,11 @@ cleanLoop:
 		if !ok {
 			log.Warn("ignore clean table id", zap.Uint64("id", id))
 			cleanIDs = append(cleanIDs, id)
+			if _, ok := c.movingTables[id]; ok {
+				if _, ok := c.orphanTables[id]; !ok {
+					c.reAddTable(id, c.status.CheckpointTs)
+				}
+			}
 			continue
 		}
 
@@ -249,6 +353,9 @@ cleanLoop:
 				zap.String("capture id", captureID))
 			log.Debug("after remove", zap.Stringer("task status", taskStatus))
 			cleanIDs = append(cleanIDs, id)
+			if _, ok := c.movingTables[id]; ok {
+				c.reAddTable(id, c.status.CheckpointTs)
+			}
 		default:
 			c.restoreTableInfos(infoClone, captureID)
 			log.Error("fail to put sub changefeed info", zap.Error(err))
@@ -258,6 +365,7 @@ cleanLoop:
 
 	for _, id := range cleanIDs {
 		delete(c.toCleanTables, id)
+		delete(c.movingTables, id)
 	}
 }
 
@@ -321,19 +429,36 @@ func (c *changeFeed) banlanceOrphanTables(ctx context.Context, captures map[stri
 func (c *changeFeed) applyJob(job *timodel.Job) (skip bool, err error) {
 	log.Info("apply job", zap.String("sql", job.Query), zap.Stringer("job", job))
 
+	// the tables are scheduled by the physical table IDs, which are the partition IDs for the partitioned tables
+	oldPhysicalIDs := []int64{job.TableID}
+	if table, ok := c.schema.TableByID(job.TableID); ok {
+		oldPhysicalIDs = table.GetPhysicalTableIDs()
+	}
+
 	schamaName, tableName, _, err := c.schema.HandleDDL(job)
 	if err != nil {
 		return false, errors.Trace(err)
 	}
 
 	schemaID := uint64(job.SchemaID)
-	if job.BinlogInfo != nil && job.BinlogInfo.TableInfo != nil && c.schema.IsIneligibleTableID(job.BinlogInfo.TableInfo.ID) {
-		tableID := uint64(job.BinlogInfo.TableInfo.ID)
-		if _, exist := c.tables[tableID]; exist {
-			c.removeTable(schemaID, tableID)
+	var newPhysicalIDs []int64
+	if job.BinlogInfo != nil && job.BinlogInfo.TableInfo != nil {
+		newPhysicalIDs = entry.WrapTableInfo(job.BinlogInfo.TableInfo).GetPhysicalTableIDs()
+		if c.schema.IsIneligibleTableID(job.BinlogInfo.TableInfo.ID) && c.info.GetConfig().ForceReplicate {
+			log.Warn("replicate the table without a primary key or unique index by all the columns, "+
+				"one of the duplicate rows is changed when a row changes", zap.Int64("tid", job.BinlogInfo.TableInfo.ID),
+				zap.String("schema", schamaName), zap.String("table", tableName))
+		} else if c.schema.IsIneligibleTableID(job.BinlogInfo.TableInfo.ID) {
+			for _, id := range newPhysicalIDs {
+				tableID := uint64(id)
+				if _, exist := c.tables[tableID]; exist {
+					c.removeTable(schemaID, tableID)
+				}
+			}
+			return true, nil
 		}
-		return true, nil
 	}
+	name := entry.TableName{Schema: schamaName, Table: tableName}
 
 	// case table id set may change
 	switch job.Type {
@@ -342,25 +467,53 @@ func (c *changeFeed) applyJob(job *timodel.Job) (skip bool, err error) {
 	case timodel.ActionDropSchema:
 		c.dropSchema(schemaID)
 	case timodel.ActionCreateTable, timodel.ActionRecoverTable:
-		addID := uint64(job.BinlogInfo.TableInfo.ID)
-		c.addTable(schemaID, addID, job.BinlogInfo.FinishedTS, entry.TableName{Schema: schamaName, Table: tableName})
+		for _, id := range newPhysicalIDs {
+			c.addTable(schemaID, uint64(id), job.BinlogInfo.FinishedTS, name)
+		}
 	case timodel.ActionDropTable:
-		dropID := uint64(job.TableID)
-		c.removeTable(schemaID, dropID)
+		for _, id := range oldPhysicalIDs {
+			c.removeTable(schemaID, uint64(id))
+		}
 	case timodel.ActionRenameTable:
 		// no id change just update name
-		c.tables[uint64(job.TableID)] = entry.TableName{Schema: schamaName, Table: tableName}
+		for _, id := range newPhysicalIDs {
+			c.tables[uint64(id)] = name
+		}
 	case timodel.ActionTruncateTable:
-		dropID := uint64(job.TableID)
-		c.removeTable(schemaID, dropID)
-
-		addID := uint64(job.BinlogInfo.TableInfo.ID)
-		c.addTable(schemaID, addID, job.BinlogInfo.FinishedTS, entry.TableName{Schema: schamaName, Table: tableName})
+		for _, id := range oldPhysicalIDs {
+			c.removeTable(schemaID, uint64(id))
+		}
+		for _, id := range newPhysicalIDs {
+			c.addTable(schemaID, uint64(id), job.BinlogInfo.FinishedTS, name)
+		}
+	case timodel.ActionAddTablePartition, timodel.ActionDropTablePartition, timodel.ActionTruncateTablePartition:
+		c.applyPartitionJob(schemaID, oldPhysicalIDs, newPhysicalIDs, job.BinlogInfo.FinishedTS, name)
 	}
 
 	return false, nil
 }
 
+// applyPartitionJob removes the dropped partitions and adds the new partitions of a partitioned table
+func (c *changeFeed) applyPartitionJob(schemaID uint64, oldPhysicalIDs, newPhysicalIDs []int64, startTs uint64, table entry.TableName) {
+	oldIDs := make(map[int64]struct{}, len(oldPhysicalIDs))
+	for _, id := range oldPhysicalIDs {
+		oldIDs[id] = struct{}{}
+	}
+	for _, id := range newPhysicalIDs {
+		if _, ok := oldIDs[id]; ok {
+			delete(oldIDs, id)
+			continue
+		}
+		c.addTable(schemaID, uint64(id), startTs, table)
+	}
+	for id := range oldIDs {
+		// the table may be filtered
+		if _, ok := c.tables[uint64(id)]; ok {
+			c.removeTable(schemaID, uint64(id))
+		}
+	}
+}
+
 type ownerImpl struct {
 	changeFeeds map[model.ChangeFeedID]*changeFeed
 
@@ -374,6 +527,7 @@ type ownerImpl struct {
 	l sync.RWMutex
 
 	pdEndpoints []string
+	credential  *security.Credential
 	pdClient    pd.Client
 	etcdClient  kv.CDCEtcdClient
 	manager     roles.Manager
@@ -384,10 +538,27 @@ type ownerImpl struct {
 
 	adminJobs     []model.AdminJob
 	adminJobsLock sync.Mutex
+
+	gcManager             kv.GCSafePointManager
+	gcSafePointLastUpdate time.Time
+
+	// eventCh notifies the owner to run a round of work
+	eventCh           chan struct{}
+	lastFlushedStatus map[model.ChangeFeedID]model.ChangeFeedStatus
+
+	// ownerEvents are the watched changes of the changefeeds, task statuses and task positions,
+	// they're applied incrementally, and all of them are reloaded every `ownerSafetyRunInterval`.
+	ownerEventsMu sync.Mutex
+	ownerEvents   []*clientv3.Event
+	// needFullReload is set if some events may be missed
+	needFullReload bool
+	lastFullReload time.Time
+	// loadedRevision is the revision of the changefeeds loaded lastly, the earlier events are skipped
+	loadedRevision int64
 }
 
 // NewOwner creates a new ownerImpl instance
-func NewOwner(pdEndpoints []string, cli kv.CDCEtcdClient, manager roles.Manager) (*ownerImpl, error) {
+func NewOwner(pdEndpoints []string, credential *security.Credential, cli kv.CDCEtcdClient, manager roles.Manager) (*ownerImpl, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	infos, watchC, err := newCaptureInfoWatch(ctx, cli)
 	if err != nil {
@@ -400,7 +571,7 @@ func NewOwner(pdEndpoints []string, cli kv.CDCEtcdClient, manager roles.Manager)
 		captures[info.ID] = info
 	}
 
-	pdClient, err := pd.NewClient(pdEndpoints, pd.SecurityOption{})
+	pdClient, err := pd.NewClient(pdEndpoints, credential.PDSecurityOption())
 	if err != nil {
 		cancel()
 		return nil, errors.Trace(err)
@@ -408,6 +579,7 @@ func NewOwner(pdEndpoints []string, cli kv.CDCEtcdClient, manager roles.Manager)
 
 	owner := &ownerImpl{
 		pdEndpoints:        pdEndpoints,
+		credential:         credential,
 		pdClient:           pdClient,
 		changeFeeds:        make(map[model.ChangeFeedID]*changeFeed),
 		activeProcessors:   make(map[string]*model.ProcessorInfo),
@@ -417,6 +589,9 @@ func NewOwner(pdEndpoints []string, cli kv.CDCEtcdClient, manager roles.Manager)
 		captureWatchC:      watchC,
 		captures:           captures,
 		cancelWatchCapture: cancel,
+		gcManager:          kv.NewGCSafePointManager(cli),
+		eventCh:            make(chan struct{}, 1),
+		lastFlushedStatus:  make(map[model.ChangeFeedID]model.ChangeFeedStatus),
 	}
 
 	return owner, nil
@@ -426,6 +601,16 @@ func (o *ownerImpl) addCapture(info *model.CaptureInfo) {
 	o.l.Lock()
 	o.captures[info.ID] = info
 	o.l.Unlock()
+	o.notify()
+}
+
+// notify triggers a round of owner work, the notifications are merged
+// if the owner is busy.
+func (o *ownerImpl) notify() {
+	select {
+	case o.eventCh <- struct{}{}:
+	default:
+	}
 }
 
 func (o *ownerImpl) handleMarkdownProcessor(ctx context.Context) {
@@ -482,9 +667,9 @@ func (o *ownerImpl) handleMarkdownProcessor(ctx context.Context) {
 
 func (o *ownerImpl) removeCapture(info *model.CaptureInfo) {
 	o.l.Lock()
-	defer o.l.Unlock()
-
 	delete(o.captures, info.ID)
+	o.l.Unlock()
+	o.notify()
 }
 
 func (o *ownerImpl) resetCaptureInfoWatcher(ctx context.Context) error {
@@ -527,7 +712,7 @@ func (o *ownerImpl) newChangeFeed(
 		zap.String("id", id), zap.Uint64("checkpoint ts", checkpointTs))
 
 	// TODO here we create another pb client,we should reuse them
-	kvStore, err := kv.CreateTiStore(strings.Join(o.pdEndpoints, ","))
+	kvStore, err := kv.CreateTiStore(strings.Join(o.pdEndpoints, ","), o.credential)
 	if err != nil {
 		return nil, err
 	}
@@ -536,10 +721,15 @@ func (o *ownerImpl) newChangeFeed(
 		return nil, errors.Trace(err)
 	}
 
+	ddlExecutedTs := resumedDDLExecutedTs(info, checkpointTs)
+	if ddlExecutedTs < info.FailedDDLTs {
+		log.Info("execute the failed DDL again", zap.String("changefeed", id), zap.Uint64("ts", info.FailedDDLTs))
+	}
+
 	schemaStorage := entry.NewSingleStorage()
 
 	for _, job := range jobs {
-		if job.BinlogInfo.FinishedTS > checkpointTs {
+		if job.BinlogInfo.FinishedTS > ddlExecutedTs {
 			break
 		}
 		_, _, _, err := schemaStorage.HandleDDL(job)
@@ -548,7 +738,7 @@ func (o *ownerImpl) newChangeFeed(
 		}
 	}
 
-	ddlHandler := newDDLHandler(o.pdClient, checkpointTs)
+	ddlHandler := newDDLHandler(o.pdClient, o.credential, checkpointTs)
 
 	existingTables := make(map[uint64]uint64)
 	for captureID, taskStatus := range processorsInfos {
@@ -572,29 +762,46 @@ func (o *ownerImpl) newChangeFeed(
 	schemas := make(map[uint64]tableIDMap)
 	tables := make(map[uint64]entry.TableName)
 	orphanTables := make(map[uint64]model.ProcessTableInfo)
-	for tid, table := range schemaStorage.CloneTables() {
+	for logicalID, table := range schemaStorage.CloneTables() {
 		if filter.ShouldIgnoreTable(table.Schema, table.Table) {
 			continue
 		}
-
-		tables[tid] = table
-		if ts, ok := existingTables[tid]; ok {
-			log.Debug("ignore known table", zap.Uint64("tid", tid), zap.Stringer("table", table), zap.Uint64("ts", ts))
+		tableInfo, ok := schemaStorage.TableByID(int64(logicalID))
+		if !ok {
+			log.Warn("table info not found", zap.Uint64("tid", logicalID))
 			continue
 		}
-		schema, ok := schemaStorage.SchemaByTableID(int64(tid))
-		if !ok {
-			log.Warn("schema not found for table", zap.Uint64("tid", tid))
-		} else {
-			sid := uint64(schema.ID)
-			if _, ok := schemas[sid]; !ok {
-				schemas[sid] = make(tableIDMap)
+		if schemaStorage.IsIneligibleTableID(int64(logicalID)) {
+			if !info.GetConfig().ForceReplicate {
+				log.Warn("skip the ineligible table", zap.Uint64("tid", logicalID), zap.Stringer("table", table))
+				continue
 			}
-			schemas[sid][tid] = struct{}{}
+			log.Warn("replicate the table without a primary key or unique index by all the columns, "+
+				"one of the duplicate rows is changed when a row changes", zap.Uint64("tid", logicalID), zap.Stringer("table", table))
 		}
-		orphanTables[tid] = model.ProcessTableInfo{
-			ID:      tid,
-			StartTs: checkpointTs,
+
+		// the partitions of a partitioned table are scheduled independently
+		for _, id := range tableInfo.GetPhysicalTableIDs() {
+			tid := uint64(id)
+			tables[tid] = table
+			if ts, ok := existingTables[tid]; ok {
+				log.Debug("ignore known table", zap.Uint64("tid", tid), zap.Stringer("table", table), zap.Uint64("ts", ts))
+				continue
+			}
+			schema, ok := schemaStorage.SchemaByTableID(int64(tid))
+			if !ok {
+				log.Warn("schema not found for table", zap.Uint64("tid", tid))
+			} else {
+				sid := uint64(schema.ID)
+				if _, ok := schemas[sid]; !ok {
+					schemas[sid] = make(tableIDMap)
+				}
+				schemas[sid][tid] = struct{}{}
+			}
+			orphanTables[tid] = model.ProcessTableInfo{
+				ID:      tid,
+				StartTs: checkpointTs,
+			}
 		}
 	}
 
@@ -612,25 +819,67 @@ func (o *ownerImpl) newChangeFeed(
 		tables:        tables,
 		orphanTables:  orphanTables,
 		toCleanTables: make(map[uint64]struct{}),
+		movingTables:  make(map[uint64]struct{}),
 		status: &model.ChangeFeedStatus{
 			ResolvedTs:   0,
 			CheckpointTs: checkpointTs,
 		},
 		ddlState:      model.ChangeFeedSyncDML,
 		ddlJobHistory: jobs,
-		ddlExecutedTs: checkpointTs,
+		ddlExecutedTs: ddlExecutedTs,
 		targetTs:      info.GetTargetTs(),
 		taskStatus:    processorsInfos,
 		taskPositions: taskPositions,
 		infoWriter:    storage.NewOwnerTaskStatusEtcdWriter(o.etcdClient),
 		filter:        filter,
 		sink:          sink,
+		kvStore:       kvStore,
 	}
 	return cf, nil
 }
 
+// resumedDDLExecutedTs returns the ts before which the DDLs are executed when the changefeed starts from checkpointTs.
+// The checkpoint ts is held below the DDLs which are not executed, so the DDL which failed and stopped the
+// changefeed is executed again, or skipped or replaced by its override, after the changefeed is resumed.
+// It's kept even if the checkpoint ts has reached the finished ts of the failed DDL.
+func resumedDDLExecutedTs(info *model.ChangeFeedInfo, checkpointTs uint64) uint64 {
+	if info.FailedDDLTs != 0 && info.FailedDDLTs <= checkpointTs {
+		return info.FailedDDLTs - 1
+	}
+	return checkpointTs
+}
+
+// updateChangeFeeds applies the watched changes of the changefeeds, all of them are reloaded
+// every `ownerSafetyRunInterval` or if some changes may be missed.
+func (o *ownerImpl) updateChangeFeeds(ctx context.Context) error {
+	o.ownerEventsMu.Lock()
+	events, fullReload := o.ownerEvents, o.needFullReload
+	o.ownerEvents, o.needFullReload = nil, false
+	o.ownerEventsMu.Unlock()
+
+	var err error
+	if fullReload || time.Since(o.lastFullReload) >= ownerSafetyRunInterval {
+		err = o.loadChangeFeeds(ctx)
+	} else {
+		err = o.applyOwnerEvents(ctx, events)
+	}
+	if err != nil {
+		if fullReload {
+			o.ownerEventsMu.Lock()
+			o.needFullReload = true
+			o.ownerEventsMu.Unlock()
+		}
+		return errors.Trace(err)
+	}
+
+	for _, changefeed := range o.changeFeeds {
+		changefeed.tryBalance(ctx, o.captures)
+	}
+	return nil
+}
+
 func (o *ownerImpl) loadChangeFeeds(ctx context.Context) error {
-	_, details, err := o.cfRWriter.GetChangeFeeds(ctx)
+	revision, details, err := o.cfRWriter.GetChangeFeeds(ctx)
 	if err != nil {
 		return err
 	}
@@ -647,43 +896,148 @@ func (o *ownerImpl) loadChangeFeeds(ctx context.Context) error {
 			cf.updateProcessorInfos(taskStatus, taskPositions)
 			continue
 		}
-
-		// we find a new changefeed, init changefeed info here.
-		status, err := o.cfRWriter.GetChangeFeedStatus(ctx, changeFeedID)
-		if err != nil && errors.Cause(err) != model.ErrChangeFeedNotExists {
-			return err
-		}
-		if status != nil && (status.AdminJobType == model.AdminStop || status.AdminJobType == model.AdminRemove) {
-			continue
-		}
-
-		cfInfo := &model.ChangeFeedInfo{}
-		err = cfInfo.Unmarshal(cfInfoRawValue.Value)
+		err = o.addChangeFeed(ctx, changeFeedID, cfInfoRawValue.Value, taskStatus, taskPositions)
 		if err != nil {
 			return err
 		}
-		checkpointTs := cfInfo.GetCheckpointTs(status)
+	}
+	o.loadedRevision = revision
+	o.lastFullReload = time.Now()
+	return nil
+}
 
-		newCf, err := o.newChangeFeed(changeFeedID, taskStatus, taskPositions, cfInfo, checkpointTs)
-		if err != nil {
-			return errors.Annotatef(err, "create change feed %s", changeFeedID)
-		}
-		o.changeFeeds[changeFeedID] = newCf
+// addChangeFeed initializes a new changefeed unless it has been stopped.
+func (o *ownerImpl) addChangeFeed(
+	ctx context.Context, changeFeedID model.ChangeFeedID, rawInfo []byte,
+	taskStatus model.ProcessorsInfos, taskPositions map[string]*model.TaskPosition,
+) error {
+	status, err := o.cfRWriter.GetChangeFeedStatus(ctx, changeFeedID)
+	if err != nil && errors.Cause(err) != model.ErrChangeFeedNotExists {
+		return err
+	}
+	if status != nil && status.AdminJobType.IsStopState() {
+		return nil
 	}
 
-	for _, changefeed := range o.changeFeeds {
-		changefeed.tryBalance(ctx, o.captures)
+	cfInfo := &model.ChangeFeedInfo{}
+	err = cfInfo.Unmarshal(rawInfo)
+	if err != nil {
+		return err
 	}
+	checkpointTs := cfInfo.GetCheckpointTs(status)
 
+	newCf, err := o.newChangeFeed(changeFeedID, taskStatus, taskPositions, cfInfo, checkpointTs)
+	if err != nil {
+		return errors.Annotatef(err, "create change feed %s", changeFeedID)
+	}
+	o.changeFeeds[changeFeedID] = newCf
 	return nil
 }
 
+// applyOwnerEvents applies the watched changes, like loadChangeFeeds, the new changefeeds are
+// initialized and the task statuses and positions of the loaded changefeeds are updated.
+func (o *ownerImpl) applyOwnerEvents(ctx context.Context, events []*clientv3.Event) error {
+	changeFeedPrefix := kv.GetEtcdKeyChangeFeedList() + "/"
+	for _, event := range events {
+		if event.Kv.ModRevision <= o.loadedRevision {
+			continue
+		}
+		key := string(event.Kv.Key)
+		switch {
+		case strings.HasPrefix(key, changeFeedPrefix):
+			changeFeedID := key[len(changeFeedPrefix):]
+			if _, exist := o.changeFeeds[changeFeedID]; exist || event.Type != mvccpb.PUT {
+				continue
+			}
+			taskStatus, err := o.cfRWriter.GetAllTaskStatus(ctx, changeFeedID)
+			if err != nil {
+				return err
+			}
+			taskPositions, err := o.cfRWriter.GetAllTaskPositions(ctx, changeFeedID)
+			if err != nil {
+				return err
+			}
+			err = o.addChangeFeed(ctx, changeFeedID, event.Kv.Value, taskStatus, taskPositions)
+			if err != nil {
+				return err
+			}
+		case strings.HasPrefix(key, kv.TaskPositionKeyPrefix+"/"):
+			captureID, changeFeedID := splitTaskKey(key, kv.TaskPositionKeyPrefix)
+			cf, exist := o.changeFeeds[changeFeedID]
+			if !exist {
+				continue
+			}
+			if event.Type == mvccpb.DELETE {
+				delete(cf.taskPositions, captureID)
+				continue
+			}
+			position := &model.TaskPosition{}
+			if err := position.Unmarshal(event.Kv.Value); err != nil {
+				return err
+			}
+			if cf.taskPositions == nil {
+				cf.taskPositions = make(map[string]*model.TaskPosition)
+			}
+			cf.taskPositions[captureID] = position
+		case strings.HasPrefix(key, kv.TaskStatusKeyPrefix+"/"):
+			captureID, changeFeedID := splitTaskKey(key, kv.TaskStatusKeyPrefix)
+			cf, exist := o.changeFeeds[changeFeedID]
+			if !exist {
+				continue
+			}
+			if event.Type == mvccpb.DELETE {
+				delete(cf.taskStatus, captureID)
+				continue
+			}
+			status := &model.TaskStatus{}
+			if err := status.Unmarshal(event.Kv.Value); err != nil {
+				return err
+			}
+			status.ModRevision = event.Kv.ModRevision
+			if cf.taskStatus == nil {
+				cf.taskStatus = make(model.ProcessorsInfos)
+			}
+			cf.taskStatus[captureID] = status
+		}
+	}
+	return nil
+}
+
+// splitTaskKey splits the key of a task status or position into the capture ID and the changefeed ID.
+func splitTaskKey(key, prefix string) (captureID string, changeFeedID string) {
+	suffix := key[len(prefix)+1:]
+	i := strings.Index(suffix, "/")
+	if i < 0 {
+		return suffix, ""
+	}
+	return suffix[:i], suffix[i+1:]
+}
+
+// flushChangeFeedInfos writes the changefeed statuses which are changed since last flush into storage.
 func (o *ownerImpl) flushChangeFeedInfos(ctx context.Context) error {
+	for id := range o.lastFlushedStatus {
+		if _, ok := o.changeFeeds[id]; !ok {
+			delete(o.lastFlushedStatus, id)
+		}
+	}
 	snapshot := make(map[model.ChangeFeedID]*model.ChangeFeedStatus, len(o.changeFeeds))
 	for id, changefeed := range o.changeFeeds {
+		if status, ok := o.lastFlushedStatus[id]; ok && status == *changefeed.status {
+			continue
+		}
 		snapshot[id] = changefeed.status
 	}
-	return errors.Trace(o.cfRWriter.PutAllChangeFeedStatus(ctx, snapshot))
+	if len(snapshot) == 0 {
+		return nil
+	}
+	err := o.cfRWriter.PutAllChangeFeedStatus(ctx, snapshot)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for id, status := range snapshot {
+		o.lastFlushedStatus[id] = *status
+	}
+	return nil
 }
 
 func (c *changeFeed) pullDDLJob() error {
@@ -755,6 +1109,19 @@ func (c *changeFeed) calcResolvedTs(ctx context.Context) error {
 	if minCheckpointTs > minResolvedTs {
 		minCheckpointTs = minResolvedTs
 	}
+	// the checkpoint ts is held below the DDLs which are not executed or are being executed asynchronously
+	// by the sink, so they're executed again if the changefeed restarts, the DDL at the target ts isn't executed
+	if len(c.ddlJobHistory) > 0 && c.ddlJobHistory[0].BinlogInfo.FinishedTS < c.targetTs &&
+		minCheckpointTs >= c.ddlJobHistory[0].BinlogInfo.FinishedTS {
+		minCheckpointTs = c.ddlJobHistory[0].BinlogInfo.FinishedTS - 1
+	}
+	pendingTs, err := c.checkAsyncDDLs()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if pendingTs != 0 && minCheckpointTs >= pendingTs {
+		minCheckpointTs = pendingTs - 1
+	}
 
 	var tsUpdated bool
 
@@ -784,12 +1151,137 @@ func (c *changeFeed) calcResolvedTs(ctx context.Context) error {
 func (o *ownerImpl) calcResolvedTs(ctx context.Context) error {
 	for _, cf := range o.changeFeeds {
 		if err := cf.calcResolvedTs(ctx); err != nil {
+			if errors.Cause(err) != model.ErrExecDDLFailed {
+				return errors.Trace(err)
+			}
+			if err := o.stopOnDDLFailure(cf, err); err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
+		if cf.status.CheckpointTs >= cf.targetTs {
+			log.Info("changefeed reaches the target ts, finish it",
+				zap.String("changefeed", cf.id),
+				zap.Uint64("checkpoint ts", cf.status.CheckpointTs),
+				zap.Uint64("target ts", cf.targetTs))
+			err := o.EnqueueJob(model.AdminJob{
+				CfID: cf.id,
+				Type: model.AdminFinish,
+			})
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// updateGCSafePoint registers the minimum checkpoint ts of all changefeeds as the
+// service safepoint of CDC, and fails the changefeeds which have fallen behind GC.
+func (o *ownerImpl) updateGCSafePoint(ctx context.Context) error {
+	if time.Since(o.gcSafePointLastUpdate) < gcSafePointUpdateInterval {
+		return nil
+	}
+	gcSafePoint, err := o.gcManager.GetGCSafePoint(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	minCheckpointTs := uint64(math.MaxUint64)
+	var protected []*changeFeed
+	for id, cf := range o.changeFeeds {
+		checkpointTs := cf.status.CheckpointTs
+		if checkpointTs < gcSafePoint {
+			log.Error("changefeed falls behind gc safepoint, stop it",
+				zap.String("changefeed", id),
+				zap.Uint64("checkpoint ts", checkpointTs),
+				zap.Uint64("gc safepoint", gcSafePoint))
+			err := o.EnqueueJob(model.AdminJob{
+				CfID:  id,
+				Type:  model.AdminStop,
+				Error: fmt.Sprintf("%s, checkpoint ts: %d, gc safepoint: %d", model.ErrCheckpointBeforeGC, checkpointTs, gcSafePoint),
+			})
+			if err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
+		if checkpointTs < minCheckpointTs {
+			minCheckpointTs = checkpointTs
+		}
+		protected = append(protected, cf)
+	}
+	// the stopped changefeeds can be resumed, so they're protected even if no changefeed is running
+	stoppedCheckpoints, err := o.loadStoppedCheckpoints(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for id, checkpointTs := range stoppedCheckpoints {
+		if checkpointTs < gcSafePoint {
+			log.Warn("stopped changefeed falls behind gc safepoint, it can't be resumed",
+				zap.String("changefeed", id),
+				zap.Uint64("checkpoint ts", checkpointTs),
+				zap.Uint64("gc safepoint", gcSafePoint))
+			continue
+		}
+		if checkpointTs < minCheckpointTs {
+			minCheckpointTs = checkpointTs
+		}
+	}
+
+	if minCheckpointTs != math.MaxUint64 {
+		err = o.gcManager.UpdateServiceSafePoint(ctx, kv.CDCServiceSafePointID, defaultGCTTL, minCheckpointTs)
+		if err != nil {
 			return errors.Trace(err)
 		}
+		log.Debug("update service safepoint", zap.Uint64("safepoint", minCheckpointTs))
 	}
+	// the service safepoints registered when creating the changefeeds are not needed any more
+	for _, cf := range protected {
+		if cf.creatingSafePointRemoved {
+			continue
+		}
+		err = o.gcManager.RemoveServiceSafePoint(ctx, kv.CreatingServiceSafePointID(cf.id))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		cf.creatingSafePointRemoved = true
+	}
+	o.gcSafePointLastUpdate = time.Now()
 	return nil
 }
 
+// loadStoppedCheckpoints returns the checkpoint ts of the changefeeds which are stopped but not removed,
+// including the ones stopped by a failed DDL. They're not loaded by the owner.
+func (o *ownerImpl) loadStoppedCheckpoints(ctx context.Context) (map[model.ChangeFeedID]uint64, error) {
+	_, details, err := o.cfRWriter.GetChangeFeeds(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	checkpoints := make(map[model.ChangeFeedID]uint64)
+	for id, rawInfo := range details {
+		if _, exist := o.changeFeeds[id]; exist {
+			continue
+		}
+		status, err := o.cfRWriter.GetChangeFeedStatus(ctx, id)
+		if err != nil {
+			if errors.Cause(err) == model.ErrChangeFeedNotExists {
+				continue
+			}
+			return nil, errors.Trace(err)
+		}
+		if status.AdminJobType != model.AdminStop {
+			continue
+		}
+		info := &model.ChangeFeedInfo{}
+		if err := info.Unmarshal(rawInfo.Value); err != nil {
+			return nil, errors.Trace(err)
+		}
+		checkpoints[id] = info.GetCheckpointTs(status)
+	}
+	return checkpoints, nil
+}
+
 // handleDDL call handleDDL of every changefeeds
 func (o *ownerImpl) handleDDL(ctx context.Context) error {
 	for _, cf := range o.changeFeeds {
@@ -798,11 +1290,7 @@ func (o *ownerImpl) handleDDL(ctx context.Context) error {
 		case nil:
 			continue
 		case model.ErrExecDDLFailed:
-			err = o.EnqueueJob(model.AdminJob{
-				CfID: cf.id,
-				Type: model.AdminStop,
-			})
-			if err != nil {
+			if err := o.stopOnDDLFailure(cf, err); err != nil {
 				return errors.Trace(err)
 			}
 		default:
@@ -812,6 +1300,16 @@ func (o *ownerImpl) handleDDL(ctx context.Context) error {
 	return nil
 }
 
+// stopOnDDLFailure stops the changefeed whose DDL failed,
+// the DDL can be skipped or replaced by `cdc cli changefeed ddl`.
+func (o *ownerImpl) stopOnDDLFailure(cf *changeFeed, err error) error {
+	return errors.Trace(o.EnqueueJob(model.AdminJob{
+		CfID:  cf.id,
+		Type:  model.AdminStop,
+		Error: err.Error(),
+	}))
+}
+
 // handleDDL check if we can change the status to be `ChangeFeedExecDDL` and execute the DDL asynchronously
 // if the status is in ChangeFeedWaitToExecDDL.
 // After executing the DDL successfully, the status will be changed to be ChangeFeedSyncDML.
@@ -839,6 +1337,12 @@ func (c *changeFeed) handleDDL(ctx context.Context, captures map[string]*model.C
 	}
 	ddlEvent := new(model.DDLEvent)
 	ddlEvent.FromJob(todoDDLJob)
+	override := c.info.GetDDLOverride(todoDDLJob.BinlogInfo.FinishedTS)
+	if override != nil && !override.Skip {
+		log.Info("replace the query of DDL", zap.String("changefeed", c.id),
+			zap.String("query", ddlEvent.Query), zap.String("replacement", override.Query))
+		ddlEvent.Query = override.Query
+	}
 	// Execute DDL Job asynchronously
 	c.ddlState = model.ChangeFeedExecDDL
 	log.Debug("apply job", zap.Stringer("job", todoDDLJob),
@@ -846,30 +1350,55 @@ func (c *changeFeed) handleDDL(ctx context.Context, captures map[string]*model.C
 		zap.String("query", todoDDLJob.Query),
 		zap.Uint64("ts", todoDDLJob.BinlogInfo.FinishedTS))
 
-	// TODO consider some newly added DDL types such as `ActionCreateSequence`
 	skip, err := c.applyJob(todoDDLJob)
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if override != nil && override.Skip {
+		log.Info("skip DDL", zap.String("changefeed", c.id), zap.Stringer("job", todoDDLJob))
+		skip = true
+	}
+	if !skip {
+		discard, err := c.filter.ShouldDiscardDDL(ddlEvent.Type, ddlEvent.Schema, ddlEvent.Table, ddlEvent.Query)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if discard {
+			log.Info("DDL is discarded by the filter", zap.String("changefeed", c.id), zap.Stringer("job", todoDDLJob))
+			skip = true
+		}
+	}
 	if skip {
 		c.ddlJobHistory = c.ddlJobHistory[1:]
 		c.ddlExecutedTs = todoDDLJob.BinlogInfo.FinishedTS
 		c.ddlState = model.ChangeFeedSyncDML
+		c.info.FailedDDLTs = 0
 		return nil
 	}
 
 	c.banlanceOrphanTables(ctx, captures)
 
+	if tableInfo := todoDDLJob.BinlogInfo.TableInfo; tableInfo != nil {
+		if table, ok := c.schema.TableByID(tableInfo.ID); ok {
+			ddlEvent.TableSchema = table.ToTableSchema()
+		}
+	}
 	err = c.sink.EmitDDLEvent(ctx, ddlEvent)
 	// If DDL executing failed, pause the changefeed and print log, rather
 	// than return an error and break the running of this owner.
 	if err != nil {
+		// the DDL isn't executed if a DDL executed asynchronously failed, which is reported with its own ts
+		if _, asyncErr := c.checkAsyncDDLs(); asyncErr != nil {
+			return errors.Trace(asyncErr)
+		}
 		c.ddlState = model.ChangeFeedDDLExecuteFailed
+		c.info.FailedDDLTs = todoDDLJob.BinlogInfo.FinishedTS
 		log.Error("Execute DDL failed",
 			zap.String("ChangeFeedID", c.id),
 			zap.Error(err),
 			zap.Reflect("ddlJob", todoDDLJob))
-		return errors.Trace(model.ErrExecDDLFailed)
+		return errors.Annotatef(model.ErrExecDDLFailed, "ts: %d, query: %s, error: %s",
+			todoDDLJob.BinlogInfo.FinishedTS, ddlEvent.Query, err)
 	}
 	log.Info("Execute DDL succeeded",
 		zap.String("ChangeFeedID", c.id),
@@ -883,9 +1412,91 @@ func (c *changeFeed) handleDDL(ctx context.Context, captures map[string]*model.C
 	c.ddlJobHistory = c.ddlJobHistory[1:]
 	c.ddlExecutedTs = todoDDLJob.BinlogInfo.FinishedTS
 	c.ddlState = model.ChangeFeedSyncDML
+	c.info.FailedDDLTs = 0
 	return nil
 }
 
+// checkAsyncDDLs returns the min ts of the DDLs being executed asynchronously by the sink, the
+// changefeed is stopped with the ts of the failed one, so it can be executed again or overridden.
+func (c *changeFeed) checkAsyncDDLs() (uint64, error) {
+	asyncSink, ok := c.sink.(sink.AsyncDDLSink)
+	if !ok {
+		return 0, nil
+	}
+	pendingTs, failed, err := asyncSink.AsyncDDLStatus()
+	if err == nil {
+		return pendingTs, nil
+	}
+	c.ddlState = model.ChangeFeedDDLExecuteFailed
+	c.info.FailedDDLTs = failed.Ts
+	log.Error("Execute DDL asynchronously failed",
+		zap.String("ChangeFeedID", c.id),
+		zap.Error(err),
+		zap.Uint64("ts", failed.Ts),
+		zap.String("query", failed.Query))
+	return 0, errors.Annotatef(model.ErrExecDDLFailed, "ts: %d, query: %s, error: %s", failed.Ts, failed.Query, err)
+}
+
+// syncSequences synchronizes the values of the sequences at the checkpoint ts to the sink periodically.
+// The views and sequences are replicated by DDLs, but the sequences are not scheduled since their values
+// are not written as rows.
+func (c *changeFeed) syncSequences(ctx context.Context) error {
+	syncer, ok := c.sink.(sink.SequenceSyncer)
+	if !ok || c.kvStore == nil || time.Since(c.lastSequenceSync) < sequenceSyncInterval {
+		return nil
+	}
+	c.lastSequenceSync = time.Now()
+	if c.sequenceValues == nil {
+		c.sequenceValues = make(map[uint64]int64)
+	}
+
+	names := c.schema.CloneSequences()
+	seqs := make(map[int64]int64, len(names))
+	for id, name := range names {
+		if c.filter.ShouldIgnoreTable(name.Schema, name.Table) {
+			continue
+		}
+		schema, ok := c.schema.SchemaByTableID(int64(id))
+		if !ok {
+			continue
+		}
+		seqs[int64(id)] = schema.ID
+	}
+	for id := range c.sequenceValues {
+		if _, ok := seqs[int64(id)]; !ok {
+			delete(c.sequenceValues, id)
+		}
+	}
+	if len(seqs) == 0 {
+		return nil
+	}
+	values, err := kv.LoadSequenceValues(c.kvStore, c.status.CheckpointTs, seqs)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for id, value := range values {
+		if synced, ok := c.sequenceValues[uint64(id)]; ok && synced == value {
+			continue
+		}
+		name := names[uint64(id)]
+		if err := syncer.SyncSequence(ctx, name.Schema, name.Table, value); err != nil {
+			return errors.Annotatef(err, "sync the value of sequence %s", name)
+		}
+		c.sequenceValues[uint64(id)] = value
+	}
+	return nil
+}
+
+// syncSequences calls syncSequences of every changefeed, the errors are only logged
+// since the values are synchronized again later.
+func (o *ownerImpl) syncSequences(ctx context.Context) {
+	for _, cf := range o.changeFeeds {
+		if err := cf.syncSequences(ctx); err != nil {
+			log.Warn("sync the values of the sequences failed", zap.String("changefeed", cf.id), zap.Error(err))
+		}
+	}
+}
+
 // dispatchJob dispatches job to processors
 func (o *ownerImpl) dispatchJob(ctx context.Context, job model.AdminJob) error {
 	cf, ok := o.changeFeeds[job.CfID]
@@ -910,6 +1521,8 @@ func (o *ownerImpl) dispatchJob(ctx context.Context, job model.AdminJob) error {
 	}
 	err = cf.ddlHandler.Close()
 	log.Info("stop changefeed ddl handler", zap.String("changefeed id", job.CfID), util.ZapErrorFilter(err, context.Canceled))
+	err = cf.sink.Close()
+	log.Info("close changefeed sink", zap.String("changefeed id", job.CfID), zap.Error(err))
 	delete(o.changeFeeds, job.CfID)
 	return nil
 }
@@ -924,13 +1537,24 @@ func (o *ownerImpl) handleAdminJob(ctx context.Context) error {
 	for i, job := range o.adminJobs {
 		log.Info("handle admin job", zap.String("changefeed", job.CfID), zap.Stringer("type", job.Type))
 		switch job.Type {
-		case model.AdminStop:
+		case model.AdminStop, model.AdminFinish:
 			// update ChangeFeedDetail to tell capture ChangeFeedDetail watcher to cleanup
 			cf, ok := o.changeFeeds[job.CfID]
 			if !ok {
-				return errors.Errorf("changefeed %s not found in owner cache", job.CfID)
+				// the changefeed has been stopped or removed by another job
+				log.Warn("changefeed not found in owner cache, skip the admin job",
+					zap.String("changefeed", job.CfID), zap.Stringer("type", job.Type))
+				break
+			}
+			cf.info.AdminJobType = job.Type
+			cf.info.State = model.StateStopped
+			if job.Type == model.AdminFinish {
+				cf.info.State = model.StateFinished
+			}
+			if len(job.Error) > 0 {
+				cf.info.State = model.StateFailed
+				cf.info.Error = job.Error
 			}
-			cf.info.AdminJobType = model.AdminStop
 			err := o.etcdClient.SaveChangeFeedInfo(ctx, cf.info, job.CfID)
 			if err != nil {
 				return errors.Trace(err)
@@ -941,13 +1565,20 @@ func (o *ownerImpl) handleAdminJob(ctx context.Context) error {
 				return errors.Trace(err)
 			}
 		case model.AdminRemove:
-			err := o.dispatchJob(ctx, job)
-			if err != nil {
-				return errors.Trace(err)
+			// the processors of a stopped changefeed have exited
+			if _, ok := o.changeFeeds[job.CfID]; ok {
+				err := o.dispatchJob(ctx, job)
+				if err != nil {
+					return errors.Trace(err)
+				}
 			}
 
 			// remove changefeed info
-			err = o.etcdClient.DeleteChangeFeedInfo(ctx, job.CfID)
+			err := o.etcdClient.DeleteChangeFeedInfo(ctx, job.CfID)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			err = o.etcdClient.DeleteSchemaSnapshots(ctx, job.CfID, math.MaxUint64)
 			if err != nil {
 				return errors.Trace(err)
 			}
@@ -970,17 +1601,65 @@ func (o *ownerImpl) handleAdminJob(ctx context.Context) error {
 
 			// set admin job in changefeed cfInfo to trigger each capture's changefeed list watch event
 			cfInfo.AdminJobType = model.AdminResume
+			cfInfo.State = model.StateNormal
+			cfInfo.Error = ""
 			err = o.etcdClient.SaveChangeFeedInfo(ctx, cfInfo, job.CfID)
 			if err != nil {
 				return errors.Trace(err)
 			}
+		case model.AdminOverrideDDL:
+			err := o.overrideDDL(ctx, job)
+			if err != nil {
+				return errors.Trace(err)
+			}
 		}
 		removeIdx = i + 1
 	}
 	return nil
 }
 
-// TODO avoid this tick style, this means we get `tickTime` latency here.
+// overrideDDL records the override of the DDL in the info of the stopped changefeed, the changefeed is resumed
+// if it's stopped by the failure of the DDL. The override is checked before it's enqueued, it's dropped if
+// it becomes invalid, e.g. the changefeed is resumed, before it's handled.
+func (o *ownerImpl) overrideDDL(ctx context.Context, job model.AdminJob) error {
+	if _, ok := o.changeFeeds[job.CfID]; ok {
+		log.Warn("changefeed is running, skip the DDL override", zap.String("changefeed", job.CfID))
+		return nil
+	}
+	cfStatus, err := o.etcdClient.GetChangeFeedStatus(ctx, job.CfID)
+	if err != nil && errors.Cause(err) != model.ErrChangeFeedNotExists {
+		return errors.Trace(err)
+	}
+	cfInfo, err := o.etcdClient.GetChangeFeedInfo(ctx, job.CfID)
+	if err != nil {
+		if errors.Cause(err) == model.ErrChangeFeedNotExists {
+			log.Warn("changefeed not found, skip the DDL override", zap.String("changefeed", job.CfID))
+			return nil
+		}
+		return errors.Trace(err)
+	}
+	resumed, err := cfInfo.OverrideDDL(cfStatus, job.DDLOverride)
+	if err != nil {
+		log.Warn("skip the invalid DDL override", zap.String("changefeed", job.CfID),
+			zap.Reflect("override", job.DDLOverride), zap.Error(err))
+		return nil
+	}
+	log.Info("override DDL", zap.String("changefeed", job.CfID),
+		zap.Reflect("override", job.DDLOverride), zap.Bool("resumed", resumed))
+	// like the resume job, the status is saved before the info which triggers the watchers of the captures
+	if resumed {
+		err = o.etcdClient.PutChangeFeedStatus(ctx, job.CfID, cfStatus)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(o.etcdClient.SaveChangeFeedInfo(ctx, cfInfo, job.CfID))
+}
+
+// Run runs the owner. The owner works whenever the changefeeds, task positions,
+// task statuses, captures or processors change, it also works every
+// `ownerSafetyRunInterval` in case any event is missed. `tickTime` is the
+// interval of checking whether this capture is the owner.
 func (o *ownerImpl) Run(ctx context.Context, tickTime time.Duration) error {
 	defer o.cancelWatchCapture()
 	handleWatchCaptureC := make(chan error, 1)
@@ -1010,38 +1689,65 @@ func (o *ownerImpl) Run(ctx context.Context, tickTime time.Duration) error {
 
 	// ownerChanged
 	ownerChanged := true
+	// cancelOwnerWatchers stops the watchers which are valid only when the
+	// capture is the owner.
+	cancelOwnerWatchers := func() {}
+	defer func() {
+		cancelOwnerWatchers()
+	}()
+	runLimiter := rate.NewLimiter(rate.Every(ownerMinRunInterval), 1)
+	var lastRunTime time.Time
 	for {
+		notified := false
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case err := <-handleWatchCaptureC:
 			return errors.Annotate(err, "handleWatchCapture failed")
+		case <-o.eventCh:
+			notified = true
 		case <-time.After(tickTime):
-			if !o.IsOwner(ctx) {
-				ownerChanged = true
-				continue
+		}
+		if !o.IsOwner(ctx) {
+			cancelOwnerWatchers()
+			cancelOwnerWatchers = func() {}
+			ownerChanged = true
+			continue
+		}
+		if ownerChanged {
+			// Do something initialize when the capture becomes an owner.
+			ownerChanged = false
+
+			// When an owner crashed, its processors crashed too,
+			// clean up the tasks for these processors.
+			if err := o.cleanUpStaleTasks(ctx); err != nil {
+				log.Error("clean up stale tasks failed",
+					zap.Error(err))
 			}
-			if ownerChanged {
-				// Do something initialize when the capture becomes an owner.
-				ownerChanged = false
-
-				// When an owner crashed, its processors crashed too,
-				// clean up the tasks for these processors.
-				if err := o.cleanUpStaleTasks(ctx); err != nil {
-					log.Error("clean up stale tasks failed",
-						zap.Error(err))
-				}
 
-				// Start a routine to keep watching on the liveness of
-				// processors.
-				o.startProcessorInfoWatcher(ctx)
-			}
+			// ownerCtx is valid only when the server is an owner, when
+			// the owner steps down, the ownerCtx would be canceled.
+			ownerCtx, cancel := context.WithCancel(ctx)
+			cancelOwnerWatchers = cancel
+
+			// Start a routine to keep watching on the liveness of
+			// processors.
+			o.startProcessorInfoWatcher(ownerCtx)
+			// Start a routine to keep watching on the changes of
+			// changefeeds and tasks.
+			o.startOwnerEventWatcher(ownerCtx)
+		} else if !notified && time.Since(lastRunTime) < ownerSafetyRunInterval {
+			continue
+		}
 
-			err := o.run(ctx)
-			// owner may be evicted during running, ignore the context canceled error directly
-			if err != nil && errors.Cause(err) != context.Canceled {
-				return err
-			}
+		if err := runLimiter.Wait(ctx); err != nil {
+			return errors.Trace(err)
+		}
+		err := o.run(ctx)
+		lastRunTime = time.Now()
+		// owner may be evicted during running, ignore the context canceled error directly
+		if err != nil && errors.Cause(err) != context.Canceled {
+			return err
 		}
 	}
 }
@@ -1060,7 +1766,7 @@ func (o *ownerImpl) run(ctx context.Context) error {
 	o.l.Lock()
 	defer o.l.Unlock()
 
-	err := o.loadChangeFeeds(cctx)
+	err := o.updateChangeFeeds(cctx)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -1079,11 +1785,18 @@ func (o *ownerImpl) run(ctx context.Context) error {
 		return errors.Trace(err)
 	}
 
+	err = o.updateGCSafePoint(cctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
 	err = o.handleDDL(cctx)
 	if err != nil {
 		return errors.Trace(err)
 	}
 
+	o.syncSequences(cctx)
+
 	err = o.handleAdminJob(cctx)
 	if err != nil {
 		return errors.Trace(err)
@@ -1106,17 +1819,32 @@ func (o *ownerImpl) EnqueueJob(job model.AdminJob) error {
 	}
 	switch job.Type {
 	case model.AdminResume:
-	case model.AdminStop, model.AdminRemove:
+	case model.AdminStop, model.AdminRemove, model.AdminFinish:
 		_, ok := o.changeFeeds[job.CfID]
 		if !ok {
 			return errors.Errorf("changefeed [%s] not found", job.CfID)
 		}
+	case model.AdminOverrideDDL:
+		if job.DDLOverride == nil || job.DDLOverride.Ts == 0 {
+			return errors.Errorf("invalid DDL override of changefeed [%s]", job.CfID)
+		}
+		if _, ok := o.changeFeeds[job.CfID]; ok {
+			return errors.Errorf("changefeed [%s] is running, stop it before overriding its DDL", job.CfID)
+		}
 	default:
 		return errors.Errorf("invalid admin job type: %d", job.Type)
 	}
 	o.adminJobsLock.Lock()
+	defer o.adminJobsLock.Unlock()
+	// the jobs are enqueued by users and by the owner itself repeatedly until they're handled
+	for _, queued := range o.adminJobs {
+		if queued.CfID == job.CfID && queued.Type == job.Type && job.Type != model.AdminOverrideDDL {
+			log.Info("admin job is already queued", zap.String("changefeed", job.CfID), zap.Stringer("type", job.Type))
+			return nil
+		}
+	}
 	o.adminJobs = append(o.adminJobs, job)
-	o.adminJobsLock.Unlock()
+	o.notify()
 	return nil
 }
 
@@ -1159,6 +1887,7 @@ func (o *ownerImpl) markProcessorDown(ctx context.Context,
 	o.markDownProcessor = append(o.markDownProcessor, snap)
 	delete(o.activeProcessors, p.ID)
 	o.processorLock.Unlock()
+	o.notify()
 	return nil
 }
 
@@ -1247,14 +1976,7 @@ func (o *ownerImpl) watchProcessorInfo(ctx context.Context) error {
 	}
 	return nil
 }
-func (o *ownerImpl) startProcessorInfoWatcher(ctx context.Context) {
-	// ownerCtx is valid only when the server is an owner, when
-	// the owner steps down, the ownerCtx would be canceled.
-	ownerCtx, cancel := context.WithCancel(ctx)
-	go func() {
-		<-o.manager.RetireNotify()
-		cancel()
-	}()
+func (o *ownerImpl) startProcessorInfoWatcher(ownerCtx context.Context) {
 	log.Info("start to watch processors")
 	go func() {
 		for {
@@ -1264,7 +1986,7 @@ func (o *ownerImpl) startProcessorInfoWatcher(ctx context.Context) {
 				// error(ownerCtx.Err())
 				if ownerCtx.Err() != nil {
 					// The context error indicates the termination of the owner
-					log.Error("watch processor failed", zap.Error(ctx.Err()))
+					log.Error("watch processor failed", zap.Error(ownerCtx.Err()))
 					return
 				}
 				log.Warn("watch processor returned", zap.Error(err))
@@ -1275,6 +1997,66 @@ func (o *ownerImpl) startProcessorInfoWatcher(ctx context.Context) {
 	}()
 }
 
+// watchOwnerEvents watches the changes of changefeed infos, task positions and
+// task statuses, and notifies the owner to run once any of them changes.
+func (o *ownerImpl) watchOwnerEvents(ctx context.Context) error {
+	ctx = clientv3.WithRequireLeader(ctx)
+	prefixes := []string{
+		kv.GetEtcdKeyChangeFeedList(),
+		kv.TaskPositionKeyPrefix,
+		kv.TaskStatusKeyPrefix,
+	}
+	errg, cctx := errgroup.WithContext(ctx)
+	for _, prefix := range prefixes {
+		prefix := prefix
+		errg.Go(func() error {
+			ch := o.etcdClient.Client.Watch(cctx, prefix, clientv3.WithPrefix())
+			for resp := range ch {
+				if resp.Err() != nil {
+					return errors.Trace(resp.Err())
+				}
+				if len(resp.Events) > 0 {
+					o.ownerEventsMu.Lock()
+					if len(o.ownerEvents) < maxOwnerEvents {
+						o.ownerEvents = append(o.ownerEvents, resp.Events...)
+					} else {
+						// the owner falls behind, reload all the changefeeds rather than keeping the events
+						o.ownerEvents = nil
+						o.needFullReload = true
+					}
+					o.ownerEventsMu.Unlock()
+					o.notify()
+				}
+			}
+			return errors.Trace(cctx.Err())
+		})
+	}
+	return errg.Wait()
+}
+
+func (o *ownerImpl) startOwnerEventWatcher(ownerCtx context.Context) {
+	log.Info("start to watch owner events")
+	go func() {
+		for {
+			// Some events may be missed before the watcher starts, reload all the changefeeds.
+			o.ownerEventsMu.Lock()
+			o.ownerEvents = nil
+			o.needFullReload = true
+			o.ownerEventsMu.Unlock()
+			err := o.watchOwnerEvents(ownerCtx)
+			if ownerCtx.Err() != nil {
+				log.Info("watch owner events stopped", zap.Error(ownerCtx.Err()))
+				return
+			}
+			log.Warn("watch owner events returned", zap.Error(err))
+			// Some events may be missed during restarting, run the owner
+			// once to catch up with them.
+			o.notify()
+			time.Sleep(ownerEventWatchRetryDelay)
+		}
+	}()
+}
+
 // cleanUpStaleTasks cleans up the task status which does not associated
 // with an active processor.
 //
@@ -714,3 +714,945 @@ func (s *changefeedInfoSuite) TestMinimumTables(c *check.C) {
 	c.Assert(cf.minimumTablesCapture(captures), check.Equals, "c4")
 }
 */
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/roles"
+	"github.com/pingcap/ticdc/cdc/roles/storage"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/etcd"
+	"github.com/pingcap/ticdc/pkg/util"
+	tidbkv "github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/store/mockstore"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/mvcc/mvccpb"
+)
+
+type ownerGCSuite struct{}
+
+var _ = check.Suite(&ownerGCSuite{})
+
+func (s *ownerGCSuite) TestUpdateGCSafePoint(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager := roles.NewMockManager(uuid.New().String(), cancel)
+	err := manager.CampaignOwner(ctx)
+	c.Assert(err, check.IsNil)
+
+	gcManager := kv.NewMockGCSafePointManager(100)
+	owner := &ownerImpl{
+		manager:   manager,
+		gcManager: gcManager,
+		cfRWriter: &stoppedChangeFeeds{},
+		changeFeeds: map[model.ChangeFeedID]*changeFeed{
+			"cf1": {id: "cf1", status: &model.ChangeFeedStatus{CheckpointTs: 120}, targetTs: math.MaxUint64},
+			"cf2": {id: "cf2", status: &model.ChangeFeedStatus{CheckpointTs: 110}, targetTs: math.MaxUint64},
+			"cf3": {id: "cf3", status: &model.ChangeFeedStatus{CheckpointTs: 90}, targetTs: math.MaxUint64},
+		},
+	}
+	for id, cf := range owner.changeFeeds {
+		err = gcManager.UpdateServiceSafePoint(ctx, kv.CreatingServiceSafePointID(id), 600, cf.status.CheckpointTs)
+		c.Assert(err, check.IsNil)
+	}
+
+	err = owner.updateGCSafePoint(ctx)
+	c.Assert(err, check.IsNil)
+	safePoint, ttl, ok := gcManager.ServiceSafePoint(kv.CDCServiceSafePointID)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(safePoint, check.Equals, uint64(110))
+	c.Assert(ttl, check.Equals, int64(defaultGCTTL))
+
+	// the service safepoints registered when creating the changefeeds are removed
+	// after the changefeeds are protected by the service safepoint of CDC
+	_, _, ok = gcManager.ServiceSafePoint(kv.CreatingServiceSafePointID("cf1"))
+	c.Assert(ok, check.IsFalse)
+	c.Assert(owner.changeFeeds["cf1"].creatingSafePointRemoved, check.IsTrue)
+	_, _, ok = gcManager.ServiceSafePoint(kv.CreatingServiceSafePointID("cf3"))
+	c.Assert(ok, check.IsTrue)
+	c.Assert(owner.changeFeeds["cf3"].creatingSafePointRemoved, check.IsFalse)
+
+	// the changefeed which falls behind gc safepoint is stopped as failed
+	c.Assert(owner.adminJobs, check.HasLen, 1)
+	c.Assert(owner.adminJobs[0].CfID, check.Equals, "cf3")
+	c.Assert(owner.adminJobs[0].Type, check.Equals, model.AdminStop)
+	c.Assert(owner.adminJobs[0].Error, check.Matches, ".*gc safepoint.*")
+
+	// the service safepoint is not updated until the interval is reached
+	owner.changeFeeds["cf2"].status.CheckpointTs = 130
+	err = owner.updateGCSafePoint(ctx)
+	c.Assert(err, check.IsNil)
+	safePoint, _, _ = gcManager.ServiceSafePoint(kv.CDCServiceSafePointID)
+	c.Assert(safePoint, check.Equals, uint64(110))
+}
+
+// stoppedChangeFeeds serves the infos and statuses of the changefeeds not loaded by the owner
+type stoppedChangeFeeds struct {
+	ChangeFeedRWriter
+	statuses map[model.ChangeFeedID]*model.ChangeFeedStatus
+}
+
+func (s *stoppedChangeFeeds) GetChangeFeeds(ctx context.Context) (int64, map[string]*mvccpb.KeyValue, error) {
+	details := make(map[string]*mvccpb.KeyValue)
+	for id := range s.statuses {
+		info, err := (&model.ChangeFeedInfo{SinkURI: "blackhole://"}).Marshal()
+		if err != nil {
+			return 0, nil, err
+		}
+		details[id] = &mvccpb.KeyValue{Value: []byte(info)}
+	}
+	return 0, details, nil
+}
+
+func (s *stoppedChangeFeeds) GetChangeFeedStatus(ctx context.Context, id string) (*model.ChangeFeedStatus, error) {
+	status, ok := s.statuses[id]
+	if !ok {
+		return nil, model.ErrChangeFeedNotExists
+	}
+	return status, nil
+}
+
+func (s *ownerGCSuite) TestProtectStoppedChangeFeeds(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager := roles.NewMockManager(uuid.New().String(), cancel)
+	err := manager.CampaignOwner(ctx)
+	c.Assert(err, check.IsNil)
+
+	gcManager := kv.NewMockGCSafePointManager(100)
+	stopped := &stoppedChangeFeeds{statuses: map[model.ChangeFeedID]*model.ChangeFeedStatus{
+		"paused":   {CheckpointTs: 105, AdminJobType: model.AdminStop},
+		"failed":   {CheckpointTs: 90, AdminJobType: model.AdminStop},
+		"finished": {CheckpointTs: 102, AdminJobType: model.AdminFinish},
+		"running":  {CheckpointTs: 101, AdminJobType: model.AdminResume},
+	}}
+	owner := &ownerImpl{
+		manager:     manager,
+		gcManager:   gcManager,
+		cfRWriter:   stopped,
+		changeFeeds: make(map[model.ChangeFeedID]*changeFeed),
+	}
+	// the service safepoint is kept for the paused changefeed while no changefeed is running,
+	// the finished changefeed and the one behind the gc safepoint are not protected
+	err = owner.updateGCSafePoint(ctx)
+	c.Assert(err, check.IsNil)
+	safePoint, _, ok := gcManager.ServiceSafePoint(kv.CDCServiceSafePointID)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(safePoint, check.Equals, uint64(105))
+	c.Assert(owner.adminJobs, check.HasLen, 0)
+
+	// the loaded changefeed is protected by its own checkpoint
+	owner.changeFeeds["running"] = &changeFeed{
+		id: "running", status: &model.ChangeFeedStatus{CheckpointTs: 120}, targetTs: math.MaxUint64,
+	}
+	stopped.statuses["paused"].CheckpointTs = 110
+	owner.gcSafePointLastUpdate = time.Time{}
+	err = owner.updateGCSafePoint(ctx)
+	c.Assert(err, check.IsNil)
+	safePoint, _, _ = gcManager.ServiceSafePoint(kv.CDCServiceSafePointID)
+	c.Assert(safePoint, check.Equals, uint64(110))
+}
+
+func (s *ownerGCSuite) TestDeduplicateAdminJobs(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager := roles.NewMockManager(uuid.New().String(), cancel)
+	c.Assert(manager.CampaignOwner(ctx), check.IsNil)
+	owner := &ownerImpl{
+		manager: manager,
+		eventCh: make(chan struct{}, 1),
+		changeFeeds: map[model.ChangeFeedID]*changeFeed{
+			"cf1": {id: "cf1", status: &model.ChangeFeedStatus{}},
+		},
+	}
+	// the changefeed falls behind the gc safepoint repeatedly before the job is handled
+	for i := 0; i < 3; i++ {
+		c.Assert(owner.EnqueueJob(model.AdminJob{CfID: "cf1", Type: model.AdminStop, Error: "gc"}), check.IsNil)
+	}
+	c.Assert(owner.EnqueueJob(model.AdminJob{CfID: "cf1", Type: model.AdminFinish}), check.IsNil)
+	c.Assert(owner.adminJobs, check.HasLen, 2)
+
+	// the jobs of the changefeeds which have been stopped are skipped
+	delete(owner.changeFeeds, "cf1")
+	c.Assert(owner.handleAdminJob(ctx), check.IsNil)
+	c.Assert(owner.adminJobs, check.HasLen, 0)
+}
+
+type ownerEventSuite struct{}
+
+var _ = check.Suite(&ownerEventSuite{})
+
+type statusRecorder struct {
+	ChangeFeedRWriter
+	flushed []map[model.ChangeFeedID]*model.ChangeFeedStatus
+}
+
+func (r *statusRecorder) GetChangeFeeds(ctx context.Context) (int64, map[string]*mvccpb.KeyValue, error) {
+	return 0, nil, nil
+}
+
+func (r *statusRecorder) PutAllChangeFeedStatus(ctx context.Context, infos map[model.ChangeFeedID]*model.ChangeFeedStatus) error {
+	snapshot := make(map[model.ChangeFeedID]*model.ChangeFeedStatus, len(infos))
+	for id, info := range infos {
+		status := *info
+		snapshot[id] = &status
+	}
+	r.flushed = append(r.flushed, snapshot)
+	return nil
+}
+
+func (s *ownerEventSuite) TestFlushChangedStatusOnly(c *check.C) {
+	recorder := &statusRecorder{}
+	owner := &ownerImpl{
+		cfRWriter: recorder,
+		changeFeeds: map[model.ChangeFeedID]*changeFeed{
+			"cf1": {id: "cf1", status: &model.ChangeFeedStatus{CheckpointTs: 10, ResolvedTs: 20}},
+			"cf2": {id: "cf2", status: &model.ChangeFeedStatus{CheckpointTs: 15, ResolvedTs: 20}},
+		},
+		lastFlushedStatus: make(map[model.ChangeFeedID]model.ChangeFeedStatus),
+	}
+	ctx := context.Background()
+
+	c.Assert(owner.flushChangeFeedInfos(ctx), check.IsNil)
+	c.Assert(recorder.flushed, check.HasLen, 1)
+	c.Assert(recorder.flushed[0], check.HasLen, 2)
+
+	// nothing changed, nothing is written
+	c.Assert(owner.flushChangeFeedInfos(ctx), check.IsNil)
+	c.Assert(recorder.flushed, check.HasLen, 1)
+
+	owner.changeFeeds["cf2"].status.CheckpointTs = 18
+	c.Assert(owner.flushChangeFeedInfos(ctx), check.IsNil)
+	c.Assert(recorder.flushed, check.HasLen, 2)
+	c.Assert(recorder.flushed[1], check.DeepEquals, map[model.ChangeFeedID]*model.ChangeFeedStatus{
+		"cf2": {CheckpointTs: 18, ResolvedTs: 20},
+	})
+
+	// the removed changefeed is forgotten
+	delete(owner.changeFeeds, "cf1")
+	c.Assert(owner.flushChangeFeedInfos(ctx), check.IsNil)
+	c.Assert(owner.lastFlushedStatus, check.HasLen, 1)
+}
+
+func (s *ownerEventSuite) TestEnqueueJobNotifiesOwner(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager := roles.NewMockManager(uuid.New().String(), cancel)
+	c.Assert(manager.CampaignOwner(ctx), check.IsNil)
+	owner := &ownerImpl{
+		manager:     manager,
+		changeFeeds: map[model.ChangeFeedID]*changeFeed{"cf1": {id: "cf1"}},
+		eventCh:     make(chan struct{}, 1),
+	}
+
+	c.Assert(owner.EnqueueJob(model.AdminJob{CfID: "cf1", Type: model.AdminStop}), check.IsNil)
+	// notifications are merged when the owner is busy
+	c.Assert(owner.EnqueueJob(model.AdminJob{CfID: "cf1", Type: model.AdminRemove}), check.IsNil)
+	c.Assert(owner.eventCh, check.HasLen, 1)
+	<-owner.eventCh
+	c.Assert(owner.adminJobs, check.HasLen, 2)
+}
+
+// changeFeedLoader counts the full reloads of the changefeeds
+type changeFeedLoader struct {
+	ChangeFeedRWriter
+	loads    int
+	revision int64
+}
+
+func (l *changeFeedLoader) GetChangeFeeds(ctx context.Context) (int64, map[string]*mvccpb.KeyValue, error) {
+	l.loads++
+	return l.revision, nil, nil
+}
+
+func (s *ownerEventSuite) TestApplyOwnerEvents(c *check.C) {
+	ctx := context.Background()
+	loader := &changeFeedLoader{revision: 10}
+	owner := &ownerImpl{
+		cfRWriter:   loader,
+		changeFeeds: make(map[model.ChangeFeedID]*changeFeed),
+	}
+	// all the changefeeds are loaded in the first round
+	c.Assert(owner.updateChangeFeeds(ctx), check.IsNil)
+	c.Assert(loader.loads, check.Equals, 1)
+	c.Assert(owner.loadedRevision, check.Equals, int64(10))
+
+	cf := &changeFeed{id: "cf1"}
+	owner.changeFeeds["cf1"] = cf
+	newEvent := func(tp mvccpb.Event_EventType, key string, value interface{ Marshal() (string, error) }, revision int64) *clientv3.Event {
+		kv := &mvccpb.KeyValue{Key: []byte(key), ModRevision: revision}
+		if value != nil {
+			data, err := value.Marshal()
+			c.Assert(err, check.IsNil)
+			kv.Value = []byte(data)
+		}
+		return &clientv3.Event{Type: tp, Kv: kv}
+	}
+	events := []*clientv3.Event{
+		// the events before the loaded revision are skipped
+		newEvent(mvccpb.PUT, kv.GetEtcdKeyTaskPosition("cf1", "capture1"), &model.TaskPosition{CheckPointTs: 1}, 9),
+		newEvent(mvccpb.PUT, kv.GetEtcdKeyTaskPosition("cf1", "capture1"), &model.TaskPosition{CheckPointTs: 100}, 11),
+		newEvent(mvccpb.PUT, kv.GetEtcdKeyTaskPosition("cf1", "capture2"), &model.TaskPosition{CheckPointTs: 110}, 12),
+		newEvent(mvccpb.PUT, kv.GetEtcdKeyTaskStatus("cf1", "capture1"), &model.TaskStatus{}, 13),
+		newEvent(mvccpb.PUT, kv.GetEtcdKeyTaskPosition("cf2", "capture1"), &model.TaskPosition{CheckPointTs: 100}, 14),
+		newEvent(mvccpb.DELETE, kv.GetEtcdKeyTaskPosition("cf1", "capture2"), nil, 15),
+	}
+	c.Assert(owner.applyOwnerEvents(ctx, events), check.IsNil)
+	c.Assert(cf.taskPositions, check.DeepEquals, map[string]*model.TaskPosition{"capture1": {CheckPointTs: 100}})
+	c.Assert(cf.taskStatus, check.HasLen, 1)
+	c.Assert(cf.taskStatus["capture1"].ModRevision, check.Equals, int64(13))
+
+	// the events are applied incrementally
+	delete(owner.changeFeeds, "cf1")
+	owner.ownerEvents = events
+	c.Assert(owner.updateChangeFeeds(ctx), check.IsNil)
+	c.Assert(loader.loads, check.Equals, 1)
+	c.Assert(owner.ownerEvents, check.HasLen, 0)
+
+	// all the changefeeds are reloaded if some events may be missed
+	owner.needFullReload = true
+	c.Assert(owner.updateChangeFeeds(ctx), check.IsNil)
+	c.Assert(loader.loads, check.Equals, 2)
+	// or the safety interval is reached
+	owner.lastFullReload = time.Now().Add(-ownerSafetyRunInterval)
+	c.Assert(owner.updateChangeFeeds(ctx), check.IsNil)
+	c.Assert(loader.loads, check.Equals, 3)
+}
+
+type placementSuite struct{}
+
+var _ = check.Suite(&placementSuite{})
+
+func newPlacementChangeFeed(placement *util.PlacementConfig, taskStatus model.ProcessorsInfos) *changeFeed {
+	return &changeFeed{
+		id:            "cf1",
+		info:          &model.ChangeFeedInfo{Config: &util.ReplicaConfig{Placement: placement}},
+		status:        &model.ChangeFeedStatus{CheckpointTs: 100},
+		taskStatus:    taskStatus,
+		orphanTables:  make(map[uint64]model.ProcessTableInfo),
+		toCleanTables: make(map[uint64]struct{}),
+		movingTables:  make(map[uint64]struct{}),
+	}
+}
+
+func (s *placementSuite) TestSelectCapture(c *check.C) {
+	captures := map[string]*model.CaptureInfo{
+		"c1": {ID: "c1", Labels: map[string]string{"zone": "az1", "host": "h1"}},
+		"c2": {ID: "c2", Labels: map[string]string{"zone": "az1", "host": "h2"}},
+		"c3": {ID: "c3", Labels: map[string]string{"zone": "az2", "host": "h3"}},
+	}
+	taskStatus := model.ProcessorsInfos{
+		"c1": {TableInfos: make([]*model.ProcessTableInfo, 2)},
+		"c2": {TableInfos: make([]*model.ProcessTableInfo, 1)},
+		"c3": {TableInfos: make([]*model.ProcessTableInfo, 0)},
+	}
+
+	// without constraints the capture with the minimum tables is selected
+	cf := newPlacementChangeFeed(nil, taskStatus)
+	c.Assert(cf.selectCapture(captures), check.Equals, "c3")
+
+	cf = newPlacementChangeFeed(&util.PlacementConfig{
+		Required: map[string]string{"zone": "az1"},
+	}, taskStatus)
+	c.Assert(cf.selectCapture(captures), check.Equals, "c2")
+
+	cf = newPlacementChangeFeed(&util.PlacementConfig{
+		Required:  map[string]string{"zone": "az1"},
+		Preferred: map[string]string{"host": "h1"},
+	}, taskStatus)
+	c.Assert(cf.selectCapture(captures), check.Equals, "c1")
+
+	cf = newPlacementChangeFeed(&util.PlacementConfig{
+		Required: map[string]string{"zone": "az3"},
+	}, taskStatus)
+	c.Assert(cf.selectCapture(captures), check.Equals, "")
+}
+
+func (s *placementSuite) TestBalancePlacement(c *check.C) {
+	captures := map[string]*model.CaptureInfo{
+		"c1": {ID: "c1", Labels: map[string]string{"zone": "az1"}},
+		"c2": {ID: "c2", Labels: map[string]string{"zone": "az2"}},
+	}
+	taskStatus := model.ProcessorsInfos{
+		"c1": {TableInfos: []*model.ProcessTableInfo{{ID: 1}}},
+		"c2": {TableInfos: []*model.ProcessTableInfo{{ID: 2}, {ID: 3}}},
+	}
+
+	cf := newPlacementChangeFeed(nil, taskStatus)
+	cf.balancePlacement(captures)
+	c.Assert(cf.toCleanTables, check.HasLen, 0)
+
+	cf = newPlacementChangeFeed(&util.PlacementConfig{
+		Required: map[string]string{"zone": "az1"},
+	}, taskStatus)
+	cf.balancePlacement(captures)
+	c.Assert(cf.toCleanTables, check.DeepEquals, map[uint64]struct{}{2: {}, 3: {}})
+	c.Assert(cf.movingTables, check.DeepEquals, map[uint64]struct{}{2: {}, 3: {}})
+
+	// a dropped table is not re-added
+	cf.removeTable(0, 3)
+	c.Assert(cf.movingTables, check.DeepEquals, map[uint64]struct{}{2: {}})
+
+	// tables stay where they are if no capture satisfies the constraints
+	cf = newPlacementChangeFeed(&util.PlacementConfig{
+		Required: map[string]string{"zone": "az3"},
+	}, taskStatus)
+	cf.balancePlacement(captures)
+	c.Assert(cf.toCleanTables, check.HasLen, 0)
+
+	cf = newPlacementChangeFeed(&util.PlacementConfig{
+		Preferred: map[string]string{"zone": "az2"},
+	}, taskStatus)
+	cf.balancePlacement(captures)
+	c.Assert(cf.toCleanTables, check.DeepEquals, map[uint64]struct{}{1: {}})
+}
+
+type partitionSuite struct{}
+
+var _ = check.Suite(&partitionSuite{})
+
+func newPartitionTableInfo(id int64, name string, partitionIDs ...int64) *timodel.TableInfo {
+	pi := &timodel.PartitionInfo{Type: timodel.PartitionTypeRange, Enable: true}
+	for _, pid := range partitionIDs {
+		pi.Definitions = append(pi.Definitions, timodel.PartitionDefinition{ID: pid, Name: timodel.NewCIStr(fmt.Sprintf("p%d", pid))})
+	}
+	col := &timodel.ColumnInfo{ID: 1, Name: timodel.NewCIStr("id"), State: timodel.StatePublic}
+	col.Tp = mysql.TypeLonglong
+	col.Flag = mysql.PriKeyFlag
+	return &timodel.TableInfo{
+		ID:         id,
+		Name:       timodel.NewCIStr(name),
+		State:      timodel.StatePublic,
+		PKIsHandle: true,
+		Columns:    []*timodel.ColumnInfo{col},
+		Partition:  pi,
+	}
+}
+
+func (s *partitionSuite) TestApplyPartitionJobs(c *check.C) {
+	newJob := func(tp timodel.ActionType, tableID int64, table *timodel.TableInfo, query string) *timodel.Job {
+		return &timodel.Job{
+			SchemaID:   1,
+			TableID:    tableID,
+			Type:       tp,
+			State:      timodel.JobStateSynced,
+			Query:      query,
+			BinlogInfo: &timodel.HistoryInfo{TableInfo: table, FinishedTS: 100},
+		}
+	}
+	jobs := []*timodel.Job{{
+		SchemaID: 1,
+		Type:     timodel.ActionCreateSchema,
+		State:    timodel.JobStateSynced,
+		Query:    "create database test",
+		BinlogInfo: &timodel.HistoryInfo{
+			DBInfo: &timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test"), State: timodel.StatePublic},
+		},
+	},
+		newJob(timodel.ActionCreateTable, 60, newPartitionTableInfo(60, "t1", 61, 62), "create table t1"),
+		newJob(timodel.ActionAddTablePartition, 60, newPartitionTableInfo(60, "t1", 61, 62, 63), "alter table t1 add partition"),
+		newJob(timodel.ActionTruncateTablePartition, 60, newPartitionTableInfo(60, "t1", 61, 64, 63), "alter table t1 truncate partition p62"),
+		newJob(timodel.ActionDropTablePartition, 60, newPartitionTableInfo(60, "t1", 64, 63), "alter table t1 drop partition p61"),
+		newJob(timodel.ActionRenameTable, 60, newPartitionTableInfo(60, "t2", 64, 63), "rename table t1 to t2"),
+		newJob(timodel.ActionTruncateTable, 60, newPartitionTableInfo(70, "t2", 71, 72), "truncate table t2"),
+		newJob(timodel.ActionDropTable, 70, nil, "drop table t2"),
+	}
+	expectTables := [][]uint64{{}, {61, 62}, {61, 62, 63}, {61, 63, 64}, {63, 64}, {63, 64}, {71, 72}, {}}
+	expectCleaned := [][]uint64{{}, {}, {}, {62}, {61, 62}, {61, 62}, {61, 62, 63, 64}, {61, 62, 63, 64, 71, 72}}
+
+	filter, err := util.NewFilter(&util.ReplicaConfig{})
+	c.Assert(err, check.IsNil)
+	cf := &changeFeed{
+		info:          &model.ChangeFeedInfo{},
+		schema:        entry.NewSingleStorage(),
+		schemas:       make(map[uint64]tableIDMap),
+		tables:        make(map[uint64]entry.TableName),
+		orphanTables:  make(map[uint64]model.ProcessTableInfo),
+		toCleanTables: make(map[uint64]struct{}),
+		movingTables:  make(map[uint64]struct{}),
+		filter:        filter,
+	}
+	// the tables are cleaned after they are dispatched
+	dispatch := func() {
+		for id := range cf.orphanTables {
+			delete(cf.orphanTables, id)
+		}
+	}
+	for i, job := range jobs {
+		_, err := cf.applyJob(job)
+		c.Assert(err, check.IsNil)
+		dispatch()
+
+		tables := make(map[uint64]entry.TableName)
+		for _, id := range expectTables[i] {
+			tables[id] = entry.TableName{Schema: "test", Table: "t1"}
+			if job.Type == timodel.ActionRenameTable || job.Type == timodel.ActionTruncateTable {
+				tables[id] = entry.TableName{Schema: "test", Table: "t2"}
+			}
+		}
+		c.Assert(cf.tables, check.DeepEquals, tables, check.Commentf("job %s", job.Query))
+		cleaned := make(map[uint64]struct{})
+		for _, id := range expectCleaned[i] {
+			cleaned[id] = struct{}{}
+		}
+		c.Assert(cf.toCleanTables, check.DeepEquals, cleaned, check.Commentf("job %s", job.Query))
+	}
+}
+
+type viewSequenceSuite struct{}
+
+var _ = check.Suite(&viewSequenceSuite{})
+
+// sequenceSink records the values of the sequences synchronized to it
+type sequenceSink struct {
+	sink.Sink
+	values map[string]int64
+}
+
+func (s *sequenceSink) SyncSequence(ctx context.Context, schema, name string, value int64) error {
+	s.values[schema+"."+name] = value
+	return nil
+}
+
+func (s *viewSequenceSuite) TestApplyViewAndSequenceJobs(c *check.C) {
+	ctx := context.Background()
+	newJob := func(tp timodel.ActionType, table *timodel.TableInfo, query string) *timodel.Job {
+		return &timodel.Job{
+			SchemaID:   1,
+			TableID:    table.ID,
+			Type:       tp,
+			State:      timodel.JobStateSynced,
+			Query:      query,
+			BinlogInfo: &timodel.HistoryInfo{TableInfo: table, FinishedTS: 100},
+		}
+	}
+	db := &timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test"), State: timodel.StatePublic}
+	table := newPartitionTableInfo(50, "t1")
+	table.Partition = nil
+	view := &timodel.TableInfo{
+		ID:      51,
+		Name:    timodel.NewCIStr("v1"),
+		State:   timodel.StatePublic,
+		Columns: table.Columns,
+		View:    &timodel.ViewInfo{SelectStmt: "SELECT id FROM test.t1"},
+	}
+	sequence := &timodel.TableInfo{
+		ID:       52,
+		Name:     timodel.NewCIStr("s1"),
+		State:    timodel.StatePublic,
+		Sequence: &timodel.SequenceInfo{Start: 1, Increment: 1, Cache: true, CacheValue: 1000},
+	}
+	jobs := []*timodel.Job{{
+		SchemaID:   1,
+		Type:       timodel.ActionCreateSchema,
+		State:      timodel.JobStateSynced,
+		Query:      "create database test",
+		BinlogInfo: &timodel.HistoryInfo{DBInfo: db},
+	},
+		newJob(timodel.ActionCreateTable, table, "create table t1 (id bigint primary key)"),
+		newJob(timodel.ActionCreateView, view, "create view v1 as select id from t1"),
+		newJob(timodel.ActionCreateSequence, sequence, "create sequence s1"),
+	}
+
+	filter, err := util.NewFilter(&util.ReplicaConfig{})
+	c.Assert(err, check.IsNil)
+	blackHole, err := sink.NewSink("blackhole://", filter, nil)
+	c.Assert(err, check.IsNil)
+	seqSink := &sequenceSink{Sink: blackHole, values: make(map[string]int64)}
+	cf := &changeFeed{
+		info:          &model.ChangeFeedInfo{},
+		schema:        entry.NewSingleStorage(),
+		schemas:       make(map[uint64]tableIDMap),
+		tables:        make(map[uint64]entry.TableName),
+		orphanTables:  make(map[uint64]model.ProcessTableInfo),
+		toCleanTables: make(map[uint64]struct{}),
+		movingTables:  make(map[uint64]struct{}),
+		filter:        filter,
+		sink:          seqSink,
+	}
+	// the views and sequences are replicated by DDLs, but they're not scheduled
+	for _, job := range jobs {
+		skip, err := cf.applyJob(job)
+		c.Assert(err, check.IsNil)
+		c.Assert(skip, check.IsFalse, check.Commentf("job %s", job.Query))
+	}
+	c.Assert(cf.tables, check.DeepEquals, map[uint64]entry.TableName{50: {Schema: "test", Table: "t1"}})
+	c.Assert(cf.orphanTables, check.HasLen, 1)
+
+	store, err := mockstore.NewMockTikvStore()
+	c.Assert(err, check.IsNil)
+	defer store.Close()
+	setSequenceValue := func(create bool, value int64) {
+		c.Assert(tidbkv.RunInNewTxn(store, false, func(txn tidbkv.Transaction) error {
+			m := meta.NewMeta(txn)
+			if create {
+				if err := m.CreateDatabase(db); err != nil {
+					return err
+				}
+				return m.CreateSequenceAndSetSeqValue(db.ID, sequence, value)
+			}
+			_, err := m.GenSequenceValue(db.ID, sequence.ID, value)
+			return err
+		}), check.IsNil)
+		version, err := store.CurrentVersion()
+		c.Assert(err, check.IsNil)
+		cf.status = &model.ChangeFeedStatus{CheckpointTs: version.Ver}
+	}
+	cf.kvStore = store
+	setSequenceValue(true, 1000)
+	c.Assert(cf.syncSequences(ctx), check.IsNil)
+	c.Assert(seqSink.values, check.DeepEquals, map[string]int64{"test.s1": 1000})
+
+	// the values are synchronized periodically, and only the changed values are synchronized
+	setSequenceValue(false, 1000)
+	c.Assert(cf.syncSequences(ctx), check.IsNil)
+	c.Assert(seqSink.values["test.s1"], check.Equals, int64(1000))
+	cf.lastSequenceSync = time.Time{}
+	c.Assert(cf.syncSequences(ctx), check.IsNil)
+	c.Assert(seqSink.values["test.s1"], check.Equals, int64(2000))
+	delete(seqSink.values, "test.s1")
+	cf.lastSequenceSync = time.Time{}
+	c.Assert(cf.syncSequences(ctx), check.IsNil)
+	c.Assert(seqSink.values, check.HasLen, 0)
+
+	// the dropped view and sequence don't change the scheduled tables
+	for _, job := range []*timodel.Job{
+		newJob(timodel.ActionDropView, view, "drop view v1"),
+		newJob(timodel.ActionDropSequence, sequence, "drop sequence s1"),
+	} {
+		skip, err := cf.applyJob(job)
+		c.Assert(err, check.IsNil)
+		c.Assert(skip, check.IsFalse)
+	}
+	c.Assert(cf.tables, check.HasLen, 1)
+	c.Assert(cf.schema.CloneSequences(), check.HasLen, 0)
+}
+
+type asyncDDLSuite struct{}
+
+var _ = check.Suite(&asyncDDLSuite{})
+
+// asyncDDLSink reports the status of the DDLs executed asynchronously
+type asyncDDLSink struct {
+	sink.Sink
+	pendingTs uint64
+	failed    *model.DDLEvent
+	err       error
+}
+
+func (s *asyncDDLSink) AsyncDDLStatus() (uint64, *model.DDLEvent, error) {
+	return s.pendingTs, s.failed, s.err
+}
+
+func (s *asyncDDLSuite) TestHoldCheckpointBelowAsyncDDLs(c *check.C) {
+	ctx := context.Background()
+	filter, err := util.NewFilter(&util.ReplicaConfig{})
+	c.Assert(err, check.IsNil)
+	blackHole, err := sink.NewSink("blackhole://", filter, nil)
+	c.Assert(err, check.IsNil)
+	asyncSink := &asyncDDLSink{Sink: blackHole}
+	cf := &changeFeed{
+		id:            "cf",
+		info:          &model.ChangeFeedInfo{},
+		status:        &model.ChangeFeedStatus{ResolvedTs: 90, CheckpointTs: 90},
+		targetTs:      math.MaxUint64,
+		ddlState:      model.ChangeFeedSyncDML,
+		ddlResolvedTs: 1000,
+		ddlExecutedTs: 90,
+		taskStatus:    model.ProcessorsInfos{"capture": {}},
+		taskPositions: map[model.CaptureID]*model.TaskPosition{"capture": {ResolvedTs: 120, CheckPointTs: 120}},
+		ddlJobHistory: []*timodel.Job{{ID: 1, BinlogInfo: &timodel.HistoryInfo{FinishedTS: 100}}},
+		sink:          asyncSink,
+	}
+	// the checkpoint ts is held below the DDL which is not executed
+	c.Assert(cf.calcResolvedTs(ctx), check.IsNil)
+	c.Assert(cf.ddlState, check.Equals, model.ChangeFeedWaitToExecDDL)
+	c.Assert(cf.status.ResolvedTs, check.Equals, uint64(100))
+	c.Assert(cf.status.CheckpointTs, check.Equals, uint64(99))
+
+	// and below the DDL being executed asynchronously
+	cf.ddlState = model.ChangeFeedSyncDML
+	cf.ddlExecutedTs = 100
+	cf.ddlJobHistory = nil
+	asyncSink.pendingTs = 100
+	c.Assert(cf.calcResolvedTs(ctx), check.IsNil)
+	c.Assert(cf.status.ResolvedTs, check.Equals, uint64(120))
+	c.Assert(cf.status.CheckpointTs, check.Equals, uint64(99))
+
+	asyncSink.pendingTs = 0
+	c.Assert(cf.calcResolvedTs(ctx), check.IsNil)
+	c.Assert(cf.status.CheckpointTs, check.Equals, uint64(120))
+
+	// the failed DDL stops the changefeed with its own ts
+	cf.taskPositions["capture"] = &model.TaskPosition{ResolvedTs: 150, CheckPointTs: 150}
+	asyncSink.failed = &model.DDLEvent{Ts: 130, Query: "alter table t add index idx(a)"}
+	asyncSink.err = fmt.Errorf("duplicate entry")
+	err = cf.calcResolvedTs(ctx)
+	c.Assert(err, check.ErrorMatches, ".*ts: 130.*duplicate entry.*")
+	c.Assert(cf.ddlState, check.Equals, model.ChangeFeedDDLExecuteFailed)
+	c.Assert(cf.info.FailedDDLTs, check.Equals, uint64(130))
+	c.Assert(cf.status.CheckpointTs, check.Equals, uint64(120))
+}
+
+// ddlSink records the queries of the executed DDLs
+type ddlSink struct {
+	sink.Sink
+	queries []string
+}
+
+func (s *ddlSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	s.queries = append(s.queries, ddl.Query)
+	return nil
+}
+
+func (s *asyncDDLSuite) TestResumeFailedDDL(c *check.C) {
+	ctx := context.Background()
+	filter, err := util.NewFilter(&util.ReplicaConfig{})
+	c.Assert(err, check.IsNil)
+	blackHole, err := sink.NewSink("blackhole://", filter, nil)
+	c.Assert(err, check.IsNil)
+
+	info := &model.ChangeFeedInfo{FailedDDLTs: 130}
+	c.Assert(resumedDDLExecutedTs(info, 129), check.Equals, uint64(129))
+	c.Assert(resumedDDLExecutedTs(info, 130), check.Equals, uint64(129))
+	c.Assert(resumedDDLExecutedTs(&model.ChangeFeedInfo{}, 130), check.Equals, uint64(130))
+
+	newResumedChangeFeed := func(info *model.ChangeFeedInfo, checkpointTs uint64) (*changeFeed, *ddlSink) {
+		ddlSink := &ddlSink{Sink: blackHole}
+		return &changeFeed{
+			id:            "cf",
+			info:          info,
+			schema:        entry.NewSingleStorage(),
+			schemas:       make(map[uint64]tableIDMap),
+			tables:        make(map[uint64]entry.TableName),
+			orphanTables:  make(map[uint64]model.ProcessTableInfo),
+			toCleanTables: make(map[uint64]struct{}),
+			movingTables:  make(map[uint64]struct{}),
+			status:        &model.ChangeFeedStatus{CheckpointTs: checkpointTs},
+			targetTs:      math.MaxUint64,
+			ddlState:      model.ChangeFeedSyncDML,
+			ddlResolvedTs: 1000,
+			ddlExecutedTs: resumedDDLExecutedTs(info, checkpointTs),
+			taskStatus:    model.ProcessorsInfos{"capture": {}},
+			taskPositions: map[model.CaptureID]*model.TaskPosition{"capture": {ResolvedTs: 150, CheckPointTs: checkpointTs}},
+			ddlJobHistory: []*timodel.Job{{
+				ID:       1,
+				SchemaID: 1,
+				Type:     timodel.ActionCreateSchema,
+				State:    timodel.JobStateSynced,
+				Query:    "create database test",
+				BinlogInfo: &timodel.HistoryInfo{
+					SchemaVersion: 1,
+					DBInfo:        &timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test")},
+					FinishedTS:    130,
+				},
+			}},
+			filter: filter,
+			sink:   ddlSink,
+		}, ddlSink
+	}
+	resume := func(cf *changeFeed) {
+		checkpointTs := cf.status.CheckpointTs
+		c.Assert(cf.calcResolvedTs(ctx), check.IsNil)
+		c.Assert(cf.ddlState, check.Equals, model.ChangeFeedWaitToExecDDL)
+		c.Assert(cf.status.ResolvedTs, check.Equals, uint64(130))
+		c.Assert(cf.status.CheckpointTs, check.Equals, checkpointTs)
+		cf.taskPositions["capture"] = &model.TaskPosition{ResolvedTs: 130, CheckPointTs: 130}
+		c.Assert(cf.handleDDL(ctx, nil), check.IsNil)
+		c.Assert(cf.ddlState, check.Equals, model.ChangeFeedSyncDML)
+		c.Assert(cf.ddlExecutedTs, check.Equals, uint64(130))
+		c.Assert(cf.info.FailedDDLTs, check.Equals, uint64(0))
+	}
+
+	// the failed DDL is executed again after the changefeed is resumed
+	for _, checkpointTs := range []uint64{129, 130} {
+		cf, ddlSink := newResumedChangeFeed(&model.ChangeFeedInfo{FailedDDLTs: 130}, checkpointTs)
+		resume(cf)
+		c.Assert(ddlSink.queries, check.DeepEquals, []string{"create database test"})
+	}
+
+	// or it's replaced or skipped by the override
+	cf, ddlSink := newResumedChangeFeed(&model.ChangeFeedInfo{
+		FailedDDLTs:  130,
+		DDLOverrides: []*model.DDLOverride{{Ts: 130, Query: "create database if not exists test"}},
+	}, 129)
+	resume(cf)
+	c.Assert(ddlSink.queries, check.DeepEquals, []string{"create database if not exists test"})
+
+	cf, ddlSink = newResumedChangeFeed(&model.ChangeFeedInfo{
+		FailedDDLTs:  130,
+		DDLOverrides: []*model.DDLOverride{{Ts: 130, Skip: true}},
+	}, 129)
+	resume(cf)
+	c.Assert(ddlSink.queries, check.HasLen, 0)
+}
+
+type overrideDDLSuite struct{}
+
+var _ = check.Suite(&overrideDDLSuite{})
+
+func (s *overrideDDLSuite) TestOverrideDDLJob(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clientURL, e, err := etcd.SetupEmbedEtcd(c.MkDir())
+	c.Assert(err, check.IsNil)
+	defer e.Close()
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{clientURL.String()},
+		DialTimeout: 3 * time.Second,
+	})
+	c.Assert(err, check.IsNil)
+	defer client.Close()
+	cli := kv.NewCDCEtcdClient(client)
+
+	manager := roles.NewMockManager(uuid.New().String(), cancel)
+	c.Assert(manager.CampaignOwner(ctx), check.IsNil)
+	owner := &ownerImpl{
+		cancelWatchCapture: cancel,
+		manager:            manager,
+		etcdClient:         cli,
+		cfRWriter:          cli,
+		changeFeeds:        map[model.ChangeFeedID]*changeFeed{"running": {}},
+	}
+	info := &model.ChangeFeedInfo{SinkURI: "blackhole://", State: model.StateFailed, Error: "exec DDL failed", FailedDDLTs: 100}
+	c.Assert(cli.SaveChangeFeedInfo(ctx, info, "cf"), check.IsNil)
+	status := &model.ChangeFeedStatus{CheckpointTs: 99, AdminJobType: model.AdminStop}
+	c.Assert(cli.PutChangeFeedStatus(ctx, "cf", status), check.IsNil)
+
+	overrideJob := func(id string, override *model.DDLOverride) model.AdminJob {
+		return model.AdminJob{CfID: id, Type: model.AdminOverrideDDL, DDLOverride: override}
+	}
+	err = owner.EnqueueJob(overrideJob("cf", nil))
+	c.Assert(err, check.ErrorMatches, ".*invalid DDL override.*")
+	err = owner.EnqueueJob(overrideJob("running", &model.DDLOverride{Ts: 100, Skip: true}))
+	c.Assert(err, check.ErrorMatches, ".*is running.*")
+
+	// the invalid override is dropped, the overrides of different DDLs are all applied
+	c.Assert(owner.EnqueueJob(overrideJob("cf", &model.DDLOverride{Ts: 90, Skip: true})), check.IsNil)
+	c.Assert(owner.EnqueueJob(overrideJob("cf", &model.DDLOverride{Ts: 200, Query: "create table t (id int primary key)"})), check.IsNil)
+	c.Assert(owner.EnqueueJob(overrideJob("cf", &model.DDLOverride{Ts: 100, Skip: true})), check.IsNil)
+	c.Assert(owner.handleAdminJob(ctx), check.IsNil)
+	c.Assert(owner.adminJobs, check.HasLen, 0)
+
+	info, err = cli.GetChangeFeedInfo(ctx, "cf")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.GetState(), check.Equals, model.StateNormal)
+	c.Assert(info.Error, check.Equals, "")
+	c.Assert(info.AdminJobType, check.Equals, model.AdminResume)
+	c.Assert(info.DDLOverrides, check.HasLen, 2)
+	c.Assert(info.GetDDLOverride(90), check.IsNil)
+	c.Assert(info.GetDDLOverride(100).Skip, check.IsTrue)
+	c.Assert(info.GetDDLOverride(200).Query, check.Equals, "create table t (id int primary key)")
+	status, err = cli.GetChangeFeedStatus(ctx, "cf")
+	c.Assert(err, check.IsNil)
+	c.Assert(status.AdminJobType, check.Equals, model.AdminResume)
+
+	// the override of the resumed changefeed is dropped
+	c.Assert(owner.EnqueueJob(overrideJob("cf", &model.DDLOverride{Ts: 300, Skip: true})), check.IsNil)
+	c.Assert(owner.handleAdminJob(ctx), check.IsNil)
+	info, err = cli.GetChangeFeedInfo(ctx, "cf")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.GetDDLOverride(300), check.IsNil)
+}
+
+type finishSuite struct{}
+
+var _ = check.Suite(&finishSuite{})
+
+// closedDDLHandler records whether the DDL handler is closed
+type closedDDLHandler struct {
+	OwnerDDLHandler
+	closed bool
+}
+
+func (h *closedDDLHandler) Close() error {
+	h.closed = true
+	return nil
+}
+
+func (s *finishSuite) TestFinishChangeFeed(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clientURL, e, err := etcd.SetupEmbedEtcd(c.MkDir())
+	c.Assert(err, check.IsNil)
+	defer e.Close()
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{clientURL.String()},
+		DialTimeout: 3 * time.Second,
+	})
+	c.Assert(err, check.IsNil)
+	defer client.Close()
+	cli := kv.NewCDCEtcdClient(client)
+
+	filter, err := util.NewFilter(&util.ReplicaConfig{})
+	c.Assert(err, check.IsNil)
+	blackHole, err := sink.NewSink("blackhole://", filter, nil)
+	c.Assert(err, check.IsNil)
+	ddlHandler := &closedDDLHandler{}
+	cf := &changeFeed{
+		id:            "cf",
+		info:          &model.ChangeFeedInfo{SinkURI: "blackhole://", TargetTs: 120},
+		status:        &model.ChangeFeedStatus{ResolvedTs: 100, CheckpointTs: 100},
+		targetTs:      120,
+		ddlState:      model.ChangeFeedSyncDML,
+		ddlResolvedTs: 1000,
+		ddlExecutedTs: 100,
+		taskStatus:    model.ProcessorsInfos{"capture": {}},
+		taskPositions: map[model.CaptureID]*model.TaskPosition{"capture": {ResolvedTs: 110, CheckPointTs: 110}},
+		infoWriter:    storage.NewOwnerTaskStatusEtcdWriter(cli),
+		ddlHandler:    ddlHandler,
+		filter:        filter,
+		sink:          blackHole,
+	}
+	manager := roles.NewMockManager(uuid.New().String(), cancel)
+	c.Assert(manager.CampaignOwner(ctx), check.IsNil)
+	owner := &ownerImpl{
+		cancelWatchCapture: cancel,
+		manager:            manager,
+		etcdClient:         cli,
+		cfRWriter:          cli,
+		changeFeeds:        map[model.ChangeFeedID]*changeFeed{"cf": cf},
+	}
+
+	// the changefeed isn't finished before the checkpoint ts reaches the target ts
+	c.Assert(owner.calcResolvedTs(ctx), check.IsNil)
+	c.Assert(cf.status.CheckpointTs, check.Equals, uint64(110))
+	c.Assert(owner.adminJobs, check.HasLen, 0)
+
+	cf.taskPositions["capture"] = &model.TaskPosition{ResolvedTs: 130, CheckPointTs: 130}
+	c.Assert(owner.calcResolvedTs(ctx), check.IsNil)
+	c.Assert(cf.status.ResolvedTs, check.Equals, uint64(120))
+	c.Assert(cf.status.CheckpointTs, check.Equals, uint64(120))
+	c.Assert(owner.adminJobs, check.DeepEquals, []model.AdminJob{{CfID: "cf", Type: model.AdminFinish}})
+	// the job is enqueued once before it's handled
+	c.Assert(owner.calcResolvedTs(ctx), check.IsNil)
+	c.Assert(owner.adminJobs, check.HasLen, 1)
+
+	c.Assert(owner.handleAdminJob(ctx), check.IsNil)
+	c.Assert(owner.adminJobs, check.HasLen, 0)
+	c.Assert(owner.changeFeeds, check.HasLen, 0)
+	c.Assert(ddlHandler.closed, check.IsTrue)
+
+	info, err := cli.GetChangeFeedInfo(ctx, "cf")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.GetState(), check.Equals, model.StateFinished)
+	c.Assert(info.AdminJobType, check.Equals, model.AdminFinish)
+	status, err := cli.GetChangeFeedStatus(ctx, "cf")
+	c.Assert(err, check.IsNil)
+	c.Assert(status.AdminJobType, check.Equals, model.AdminFinish)
+	c.Assert(status.CheckpointTs, check.Equals, uint64(120))
+	_, taskStatus, err := cli.GetTaskStatus(ctx, "cf", "capture")
+	c.Assert(err, check.IsNil)
+	c.Assert(taskStatus.AdminJobType, check.Equals, model.AdminFinish)
+}
@@ -36,7 +36,9 @@ import (
 	"github.com/pingcap/ticdc/cdc/roles/storage"
 	"github.com/pingcap/ticdc/cdc/sink"
 	"github.com/pingcap/ticdc/pkg/retry"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
+	tidbkv "github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/store/tikv/oracle"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/clientv3/concurrency"
@@ -72,14 +74,28 @@ type processor struct {
 	changefeed   model.ChangeFeedInfo
 	limitter     *puller.BlurResourceLimitter
 
-	pdCli   pd.Client
-	etcdCli kv.CDCEtcdClient
-	session *concurrency.Session
+	pdCli          pd.Client
+	pullerRegistry *puller.Registry
+	sorterConfig   *puller.SorterConfig
+	mounterPool    *entry.MounterWorkerPool
+	etcdCli        kv.CDCEtcdClient
+	session        *concurrency.Session
 
 	sink sink.Sink
-
-	ddlPuller     puller.Puller
-	schemaBuilder *entry.StorageBuilder
+	// oldValues reads the old values of the rows of the keyless tables, it's nil if the changefeed isn't force replicated
+	oldValues entry.OldValueReader
+	// oldValueStore is the store read by oldValues, it's closed once the tables are stopped
+	oldValueStore     tidbkv.Storage
+	closeOldValueOnce sync.Once
+
+	// ddlPuller is nil if the processor uses the schema store shared by the capture
+	ddlPuller   puller.Puller
+	schemaStore *entry.SchemaStore
+	// recorder records the events of the DDL puller and the selected tables if it's not nil
+	recorder *puller.Recorder
+	// schemaSnapshots persists the schemas if it's not nil
+	schemaSnapshots        entry.SchemaSnapshotStorage
+	lastSchemaSnapshotTime time.Time
 
 	tsRWriter storage.ProcessorTsRWriter
 	output    chan *model.RowChangedEvent
@@ -114,17 +130,26 @@ func (t *tableInfo) storeResolvedTS(ts uint64) {
 func NewProcessor(
 	ctx context.Context,
 	pdEndpoints []string,
+	credential *security.Credential,
+	pullerRegistry *puller.Registry,
+	mounterPool *entry.MounterWorkerPool,
+	sharedSchemas *sharedSchemaStore,
 	changefeed model.ChangeFeedInfo,
 	sink sink.Sink,
 	changefeedID, captureID string,
 	checkpointTs uint64) (*processor, error) {
-	pdCli, err := fNewPDCli(pdEndpoints, pd.SecurityOption{})
+	pdCli, err := fNewPDCli(pdEndpoints, credential.PDSecurityOption())
 	if err != nil {
 		return nil, errors.Annotatef(err, "create pd client failed, addr: %v", pdEndpoints)
 	}
 
+	tlsConfig, err := credential.ToTLSConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	etcdCli, err := clientv3.New(clientv3.Config{
 		Endpoints:   pdEndpoints,
+		TLS:         tlsConfig,
 		DialTimeout: 5 * time.Second,
 		DialOptions: []grpc.DialOption{
 			grpc.WithConnectParams(grpc.ConnectParams{
@@ -155,27 +180,101 @@ func NewProcessor(
 
 	limitter := puller.NewBlurResourceLimmter(defaultMemBufferCapacity)
 
-	// The key in DDL kv pair returned from TiKV is already memcompariable encoded,
-	// so we set `needEncode` to false.
-	ddlPuller := puller.NewPuller(pdCli, checkpointTs, []util.Span{util.GetDDLSpan(), util.GetAddIndexDDLSpan()}, false, limitter)
-	ddlEventCh := ddlPuller.SortedOutput(ctx)
-	schemaBuilder, err := createSchemaBuilder(pdEndpoints, ddlEventCh)
+	sorterConfig, err := puller.NewSorterConfig(changefeed.GetConfig().SortEngine, changefeed.GetConfig().SortDir)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 
+	var schemaSnapshots entry.SchemaSnapshotStorage
+	if snapshotCfg := changefeed.GetConfig().SchemaSnapshot; snapshotCfg.IsEnabled() {
+		schemaSnapshots, err = entry.NewSchemaSnapshotStorage(snapshotCfg, cdcEtcdCli, changefeedID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
+	// The recorder records the DDL events received by the DDL puller of the changefeed,
+	// and the shared schema store may have removed the schemas at the checkpoint ts,
+	// the processor creates its own DDL puller and schema store in these cases.
+	recorderCfg := changefeed.GetConfig().Recorder
+	processorID := uuid.New().String()
+	var schemaStore *entry.SchemaStore
+	if sharedSchemas != nil && !recorderCfg.IsEnabled() {
+		store, err := sharedSchemas.get(ctx, schemaSnapshots, checkpointTs)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if store.Register(processorID, checkpointTs) {
+			schemaStore = store
+		} else {
+			log.Info("the shared schema store can't serve the checkpoint ts, create a schema store for the changefeed",
+				zap.String("changefeed", changefeedID), zap.Uint64("checkpointTs", checkpointTs))
+		}
+	}
+	var ddlPuller puller.Puller
+	var recorder *puller.Recorder
+	if schemaStore == nil {
+		// The key in DDL kv pair returned from TiKV is already memcompariable encoded,
+		// so we set `needEncode` to false.
+		privateDDLPuller := puller.NewPuller(pdCli, credential, checkpointTs, []util.Span{util.GetDDLSpan(), util.GetAddIndexDDLSpan()}, false, limitter)
+		ddlEventCh := privateDDLPuller.SortedOutput(ctx)
+		schemaStore, err = createSchemaStore(ctx, pdEndpoints, credential, ddlEventCh, schemaSnapshots, checkpointTs)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		schemaStore.Register(processorID, checkpointTs)
+
+		if recorderCfg.IsEnabled() {
+			jobs, err := schemaStore.SnapshotJobs(checkpointTs)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			recorder, err = puller.NewRecorder(recorderCfg.Dir, &puller.RecordMeta{
+				ChangefeedID: changefeedID,
+				CaptureID:    captureID,
+				StartTs:      checkpointTs,
+				SchemaJobs:   jobs,
+			})
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			privateDDLPuller.SetRecorder(recorder)
+		}
+		ddlPuller = privateDDLPuller
+	}
+
+	// The store is created after all the fallible steps above, so it's only closed by the processor.
+	var oldValueStore tidbkv.Storage
+	var oldValues entry.OldValueReader
+	if changefeed.GetConfig().ForceReplicate {
+		oldValueStore, err = kv.CreateTiStore(strings.Join(pdEndpoints, ","), credential)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		oldValues = entry.NewOldValueReader(oldValueStore)
+	}
+
 	p := &processor{
-		id:            uuid.New().String(),
-		limitter:      limitter,
-		captureID:     captureID,
-		changefeedID:  changefeedID,
-		changefeed:    changefeed,
-		pdCli:         pdCli,
-		etcdCli:       cdcEtcdCli,
-		session:       sess,
-		sink:          sink,
-		ddlPuller:     ddlPuller,
-		schemaBuilder: schemaBuilder,
+		id:             processorID,
+		limitter:       limitter,
+		captureID:      captureID,
+		changefeedID:   changefeedID,
+		changefeed:     changefeed,
+		pdCli:          pdCli,
+		pullerRegistry: pullerRegistry,
+		sorterConfig:   sorterConfig,
+		mounterPool:    mounterPool,
+		oldValueStore:  oldValueStore,
+		oldValues:      oldValues,
+		etcdCli:        cdcEtcdCli,
+		session:        sess,
+		sink:           sink,
+		ddlPuller:      ddlPuller,
+		schemaStore:    schemaStore,
+		recorder:       recorder,
+
+		schemaSnapshots:        schemaSnapshots,
+		lastSchemaSnapshotTime: time.Now(),
 
 		tsRWriter: tsRWriter,
 		status:    tsRWriter.GetTaskStatus(),
@@ -209,13 +308,15 @@ func (p *processor) Run(ctx context.Context, errCh chan<- error) {
 		return p.syncResolved(cctx)
 	})
 
-	wg.Go(func() error {
-		return p.ddlPuller.Run(cctx)
-	})
+	if p.ddlPuller != nil {
+		wg.Go(func() error {
+			return p.ddlPuller.Run(cctx)
+		})
 
-	wg.Go(func() error {
-		return p.schemaBuilder.Run(cctx)
-	})
+		wg.Go(func() error {
+			return p.schemaStore.Run(cctx)
+		})
+	}
 
 	wg.Go(func() error {
 		return p.sink.PrintStatus(cctx)
@@ -228,6 +329,11 @@ func (p *processor) Run(ctx context.Context, errCh chan<- error) {
 		if err := wg.Wait(); err != nil {
 			errCh <- err
 		}
+		if err := p.recorder.Close(); err != nil {
+			log.Warn("close recorder failed", zap.Error(err))
+		}
+		p.closeOldValueStore()
+		p.schemaStore.Unregister(p.id)
 		_ = p.deregister(ctx)
 	}()
 }
@@ -300,7 +406,7 @@ func (p *processor) positionWorker(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-resolveTsTick.C:
-			minResolvedTs := p.schemaBuilder.GetResolvedTs()
+			minResolvedTs := p.schemaStore.ResolvedTs()
 			p.tablesMu.Lock()
 			for _, table := range p.tables {
 				ts := table.loadResolvedTS()
@@ -360,7 +466,7 @@ func (p *processor) updateInfo(ctx context.Context) error {
 	}
 	oldStatus := p.status
 	p.status = p.tsRWriter.GetTaskStatus()
-	if p.status.AdminJobType == model.AdminStop || p.status.AdminJobType == model.AdminRemove {
+	if p.status.AdminJobType.IsStopState() {
 		err = p.stop(ctx)
 		if err != nil {
 			return errors.Trace(err)
@@ -509,10 +615,11 @@ func (p *processor) globalStatusWorker(ctx context.Context) error {
 		}
 
 		if lastCheckPointTs < changefeedStatus.CheckpointTs {
-			err = p.schemaBuilder.DoGc(changefeedStatus.CheckpointTs)
+			err = p.schemaStore.DoGC(p.id, changefeedStatus.CheckpointTs)
 			if err != nil {
 				return errors.Trace(err)
 			}
+			p.persistSchemaSnapshot(ctx, changefeedStatus.CheckpointTs)
 			lastCheckPointTs = changefeedStatus.CheckpointTs
 		}
 
@@ -548,18 +655,78 @@ func (p *processor) syncResolved(ctx context.Context) error {
 	}
 }
 
-func createSchemaBuilder(pdEndpoints []string, ddlEventCh <-chan *model.RawKVEntry) (*entry.StorageBuilder, error) {
+// persistSchemaSnapshot persists the schema snapshot at the checkpoint ts at most once an interval,
+// and removes the snapshots which are not needed by the checkpoint ts. The snapshots only speed up
+// the start of the processors, so the replication goes on if they fail to be persisted.
+func (p *processor) persistSchemaSnapshot(ctx context.Context, checkpointTs uint64) {
+	if p.schemaSnapshots == nil ||
+		time.Since(p.lastSchemaSnapshotTime) < p.changefeed.GetConfig().SchemaSnapshot.GetInterval() {
+		return
+	}
+	p.lastSchemaSnapshotTime = time.Now()
+	snap, err := p.schemaStore.Snapshot(checkpointTs)
+	if err != nil {
+		log.Warn("failed to build the schema snapshot", zap.Uint64("ts", checkpointTs), zap.Error(err))
+		return
+	}
+	if err := p.schemaSnapshots.Put(ctx, snap); err != nil {
+		log.Warn("failed to persist the schema snapshot", zap.Uint64("ts", checkpointTs), zap.Error(err))
+		return
+	}
+	if err := p.schemaSnapshots.GC(ctx, checkpointTs); err != nil {
+		log.Warn("failed to remove the old schema snapshots", zap.Uint64("ts", checkpointTs), zap.Error(err))
+	}
+}
+
+// createSchemaStore creates the SchemaStore from the latest schema snapshot before checkpointTs if it
+// exists, otherwise the whole DDL history is loaded.
+func createSchemaStore(
+	ctx context.Context,
+	pdEndpoints []string,
+	credential *security.Credential,
+	ddlEventCh <-chan *model.RawKVEntry,
+	schemaSnapshots entry.SchemaSnapshotStorage,
+	checkpointTs uint64,
+) (*entry.SchemaStore, error) {
 	// TODO here we create another pb client,we should reuse them
-	kvStore, err := kv.CreateTiStore(strings.Join(pdEndpoints, ","))
+	kvStore, err := kv.CreateTiStore(strings.Join(pdEndpoints, ","), credential)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	return loadSchemaStore(ctx, kvStore, ddlEventCh, schemaSnapshots, checkpointTs, 0)
+}
+
+// loadSchemaStore creates a schema store from the latest schema snapshot whose ts is not greater than ts,
+// the whole DDL history is loaded if there is no such snapshot. resolvedTs is the ts before which the
+// DDL jobs are all loaded from kvStore, the newer jobs are received from ddlEventCh.
+func loadSchemaStore(
+	ctx context.Context,
+	kvStore tidbkv.Storage,
+	ddlEventCh <-chan *model.RawKVEntry,
+	schemaSnapshots entry.SchemaSnapshotStorage,
+	ts, resolvedTs uint64,
+) (*entry.SchemaStore, error) {
+	if schemaSnapshots != nil {
+		snap, err := schemaSnapshots.Get(ctx, ts)
+		switch errors.Cause(err) {
+		case nil:
+			jobs, err := kv.LoadHistoryDDLJobsAfter(kvStore, snap.SchemaVersion)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			log.Info("load the schema snapshot", zap.Uint64("ts", snap.Ts),
+				zap.Int64("schemaVersion", snap.SchemaVersion), zap.Int("newerJobs", len(jobs)))
+			return entry.NewSchemaStoreFromSnapshot(snap, jobs, resolvedTs, ddlEventCh), nil
+		case model.ErrSchemaSnapshotNotExists:
+		default:
+			log.Warn("failed to load the schema snapshot, the whole DDL history is loaded", zap.Error(err))
+		}
+	}
 	jobs, err := kv.LoadHistoryDDLJobs(kvStore)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	builder := entry.NewStorageBuilder(jobs, ddlEventCh)
-	return builder, nil
+	return entry.NewSchemaStore(jobs, resolvedTs, ddlEventCh), nil
 }
 
 func createTsRWriter(cli kv.CDCEtcdClient, changefeedID, captureID string) (storage.ProcessorTsRWriter, error) {
@@ -587,23 +754,32 @@ func (p *processor) addTable(ctx context.Context, tableID int64, startTs uint64)
 		cancel:     cancel,
 	}
 
-	// start table puller
+	// subscribe the table puller shared by the processors on this capture
 	// The key in DML kv pair returned from TiKV is not memcompariable encoded,
 	// so we set `needEncode` to true.
+	var recorder *puller.Recorder
+	if p.recorder != nil {
+		storage, err := p.schemaStore.WaitSnapshot(startTs)
+		if err != nil {
+			cancel()
+			p.errCh <- errors.Trace(err)
+			return
+		}
+		if name, ok := storage.GetTableNameByID(tableID); ok && p.changefeed.GetConfig().Recorder.ShouldRecord(name.Schema, name.Table) {
+			log.Info("record the events of table", zap.Int64("tableID", tableID), zap.Stringer("table", name))
+			recorder = p.recorder
+		}
+	}
 	span := util.GetTableSpan(tableID, true)
-	puller := puller.NewPuller(p.pdCli, startTs, []util.Span{span}, true, p.limitter)
+	sub := p.pullerRegistry.Subscribe(ctx, span, startTs, true, p.sorterConfig, recorder)
 	go func() {
-		err := puller.Run(ctx)
+		err := sub.Run(ctx)
 		if errors.Cause(err) != context.Canceled {
 			p.errCh <- err
 		}
 	}()
-	storage, err := p.schemaBuilder.Build(startTs)
-	if err != nil {
-		p.errCh <- errors.Trace(err)
-	}
 	// start mounter
-	mounter := entry.NewMounter(puller.SortedOutput(ctx), storage)
+	mounter := entry.NewMounter(sub.Output(), p.schemaStore, p.mounterPool, p.changefeed.GetConfig().ForceReplicate, p.oldValues)
 	go func() {
 		err := mounter.Run(ctx)
 		if errors.Cause(err) != context.Canceled {
@@ -645,6 +821,8 @@ func (p *processor) stop(ctx context.Context) error {
 		tbl.cancel()
 	}
 	p.tablesMu.Unlock()
+	p.closeOldValueStore()
+	p.schemaStore.Unregister(p.id)
 	p.session.Close()
 
 	if err := p.etcdCli.DeleteTaskPosition(ctx, p.changefeedID, p.captureID); err != nil {
@@ -657,6 +835,19 @@ func (p *processor) stop(ctx context.Context) error {
 	return errors.Trace(p.deregister(ctx))
 }
 
+// closeOldValueStore closes the store created for reading the old values of the keyless rows,
+// it's called after the tables, which read the old values, are stopped.
+func (p *processor) closeOldValueStore() {
+	p.closeOldValueOnce.Do(func() {
+		if p.oldValueStore == nil {
+			return
+		}
+		if err := p.oldValueStore.Close(); err != nil {
+			log.Warn("close old value store failed", zap.String("changefeedID", p.changefeedID), zap.Error(err))
+		}
+	})
+}
+
 func (p *processor) register(ctx context.Context) error {
 	info := &model.ProcessorInfo{
 		ID:           p.id,
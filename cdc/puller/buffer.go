@@ -132,7 +132,7 @@ var sizeOfResolve = unsafe.Sizeof(model.ResolvedSpan{})
 
 func entrySize(e model.RegionFeedEvent) int {
 	if e.Val != nil {
-		return int(sizeOfVal) + len(e.Val.Key) + len(e.Val.Value)
+		return rawEntrySize(e.Val)
 	} else if e.Resolved != nil {
 		return int(sizeOfResolve)
 	} else {
@@ -142,6 +142,10 @@ func entrySize(e model.RegionFeedEvent) int {
 	return 0
 }
 
+func rawEntrySize(e *model.RawKVEntry) int {
+	return int(sizeOfVal) + len(e.Key) + len(e.Value)
+}
+
 // BlurResourceLimitter limit resource use.
 type BlurResourceLimitter struct {
 	budget int64
@@ -155,8 +159,11 @@ func NewBlurResourceLimmter(budget int64) *BlurResourceLimitter {
 	}
 }
 
-// Add used resource into limmter
+// Add used resource into limmter, it does nothing if the limitter is nil
 func (rl *BlurResourceLimitter) Add(n int64) {
+	if rl == nil {
+		return
+	}
 	atomic.AddInt64(&rl.used, n)
 }
 
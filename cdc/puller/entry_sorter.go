@@ -29,7 +29,7 @@ func NewEntrySorter() *EntrySorter {
 }
 
 // Run runs EntrySorter
-func (es *EntrySorter) Run(ctx context.Context) {
+func (es *EntrySorter) Run(ctx context.Context, errCh chan<- error) {
 	lessFunc := func(i *model.RawKVEntry, j *model.RawKVEntry) bool {
 		if i.Ts == j.Ts {
 			return i.OpType == model.OpTypeDelete
@@ -62,7 +62,6 @@ func (es *EntrySorter) Run(ctx context.Context) {
 			case <-ctx.Done():
 				atomic.StoreInt32(&es.closed, 1)
 				close(es.output)
-				close(es.resolvedCh)
 				return
 			case resolvedTs := <-es.resolvedCh:
 				es.lock.Lock()
@@ -90,13 +89,16 @@ func (es *EntrySorter) Run(ctx context.Context) {
 }
 
 // AddEntry adds an RawKVEntry to the EntryGroup
-func (es *EntrySorter) AddEntry(entry *model.RawKVEntry) {
+func (es *EntrySorter) AddEntry(ctx context.Context, entry *model.RawKVEntry) {
 	if atomic.LoadInt32(&es.closed) != 0 {
 		return
 	}
 	if entry.OpType == model.OpTypeResolved {
 		atomic.StoreUint64(&es.resolvedTs, entry.Ts)
-		es.resolvedCh <- entry.Ts
+		select {
+		case <-ctx.Done():
+		case es.resolvedCh <- entry.Ts:
+		}
 		return
 	}
 	es.lock.Lock()
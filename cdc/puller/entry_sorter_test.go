@@ -89,12 +89,12 @@ func (s *mockEntrySorterSuite) TestEntrySorter(c *check.C) {
 	es := NewEntrySorter()
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	es.Run(ctx)
+	es.Run(ctx, nil)
 	for _, tc := range testCases {
 		for _, entry := range tc.input {
-			es.AddEntry(entry)
+			es.AddEntry(ctx, entry)
 		}
-		es.AddEntry(&model.RawKVEntry{Ts: tc.resolvedTs, OpType: model.OpTypeResolved})
+		es.AddEntry(ctx, &model.RawKVEntry{Ts: tc.resolvedTs, OpType: model.OpTypeResolved})
 		for i := 0; i < len(tc.expect); i++ {
 			e := <-es.Output()
 			c.Check(e, check.DeepEquals, tc.expect[i])
@@ -106,7 +106,7 @@ func (s *mockEntrySorterSuite) TestEntrySorterRandomly(c *check.C) {
 	es := NewEntrySorter()
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	es.Run(ctx)
+	es.Run(ctx, nil)
 
 	maxTs := uint64(100000)
 	go func() {
@@ -122,11 +122,11 @@ func (s *mockEntrySorterSuite) TestEntrySorterRandomly(c *check.C) {
 					Ts:     uint64(int64(resolvedTs) + rand.Int63n(int64(maxTs-resolvedTs))),
 					OpType: opType,
 				}
-				es.AddEntry(entry)
+				es.AddEntry(ctx, entry)
 			}
-			es.AddEntry(&model.RawKVEntry{Ts: resolvedTs, OpType: model.OpTypeResolved})
+			es.AddEntry(ctx, &model.RawKVEntry{Ts: resolvedTs, OpType: model.OpTypeResolved})
 		}
-		es.AddEntry(&model.RawKVEntry{Ts: maxTs, OpType: model.OpTypeResolved})
+		es.AddEntry(ctx, &model.RawKVEntry{Ts: maxTs, OpType: model.OpTypeResolved})
 	}()
 	var lastTs uint64
 	var resolvedTs uint64
@@ -0,0 +1,544 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"go.uber.org/zap"
+)
+
+const (
+	// SortInMemory sorts the entries in memory
+	SortInMemory = "memory"
+	// SortInFile sorts the entries in memory and spills them to local files when the memory is over limit
+	SortInFile = "file"
+
+	// defaultSorterMemLimit is the default memory limit of a file sorter
+	defaultSorterMemLimit int64 = 64 * 1024 * 1024 // 64M
+	// entryMemOverhead is the estimated memory used by a RawKVEntry except its key and value
+	entryMemOverhead  = 64
+	fileSorterBufSize = 64 * 1024
+)
+
+// Sorter accepts out-of-order raw kv entries and output sorted entries
+type Sorter interface {
+	// Run runs the sorter in background, the error stopping the sorter is sent to errCh
+	// and the output channel is closed after the sorter exits
+	Run(ctx context.Context, errCh chan<- error)
+	// AddEntry adds an entry to the sorter, the entries before a resolved entry are output in order
+	AddEntry(ctx context.Context, entry *model.RawKVEntry)
+	// Output returns the sorted raw kv output channel
+	Output() <-chan *model.RawKVEntry
+}
+
+var (
+	_ Sorter = &EntrySorter{}
+	_ Sorter = &FileSorter{}
+)
+
+// SorterConfig represents the config of the sorter used by a puller
+type SorterConfig struct {
+	Engine   string
+	Dir      string
+	MemLimit int64
+}
+
+// NewSorterConfig checks the sort engine and creates a SorterConfig
+func NewSorterConfig(engine, dir string) (*SorterConfig, error) {
+	switch engine {
+	case "", SortInMemory:
+		return &SorterConfig{Engine: SortInMemory}, nil
+	case SortInFile:
+		if len(dir) == 0 {
+			dir = filepath.Join(os.TempDir(), "cdc_sort")
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, errors.Annotatef(err, "create sort dir %s", dir)
+		}
+		return &SorterConfig{Engine: SortInFile, Dir: dir, MemLimit: defaultSorterMemLimit}, nil
+	default:
+		return nil, errors.Errorf("unknown sort engine %s", engine)
+	}
+}
+
+func (cfg *SorterConfig) newSorter() Sorter {
+	if cfg == nil || cfg.Engine != SortInFile {
+		return NewEntrySorter()
+	}
+	return NewFileSorter(cfg.Dir, cfg.MemLimit)
+}
+
+// FileSorter accepts out-of-order raw kv entries and output sorted entries.
+// The entries are sorted in memory, when the memory used by the unsorted entries
+// exceeds the limit, they are sorted and spilled to a local file as a sorted run.
+// When a resolved ts arrives, all the sorted runs are merged and the entries not
+// greater than the resolved ts are output.
+type FileSorter struct {
+	dir      string
+	memLimit int64
+
+	lock     sync.Mutex
+	unsorted []*model.RawKVEntry
+	memSize  int64
+	// spilled are the sorted runs in the local files not taken by the merger yet
+	spilled []*fileRun
+	err     error
+	fileID  int64
+
+	resolvedCh chan uint64
+	closed     int32
+	// done is closed when the sorter exits
+	done   chan struct{}
+	output chan *model.RawKVEntry
+}
+
+// NewFileSorter creates a new FileSorter which spills entries into a sub directory of dir
+func NewFileSorter(dir string, memLimit int64) *FileSorter {
+	return &FileSorter{
+		dir:        dir,
+		memLimit:   memLimit,
+		resolvedCh: make(chan uint64, 1024),
+		done:       make(chan struct{}),
+		output:     make(chan *model.RawKVEntry, 128),
+	}
+}
+
+func entryMemSize(entry *model.RawKVEntry) int64 {
+	return int64(len(entry.Key) + len(entry.Value) + entryMemOverhead)
+}
+
+func entryLess(i *model.RawKVEntry, j *model.RawKVEntry) bool {
+	if i.Ts == j.Ts {
+		return i.OpType == model.OpTypeDelete
+	}
+	return i.Ts < j.Ts
+}
+
+// Run runs FileSorter
+func (fs *FileSorter) Run(ctx context.Context, errCh chan<- error) {
+	// the entries are not spilled after the sort dir fails to be created,
+	// the error is reported by the sorting goroutine.
+	dir, err := ioutil.TempDir(fs.dir, "sorter")
+	fs.lock.Lock()
+	if err != nil {
+		fs.err = errors.Annotatef(err, "create sort dir in %s", fs.dir)
+	} else {
+		fs.dir = dir
+	}
+	fs.lock.Unlock()
+
+	go func() {
+		var runs []sortedRun
+		defer func() {
+			atomic.StoreInt32(&fs.closed, 1)
+			fs.lock.Lock()
+			for _, r := range fs.spilled {
+				runs = append(runs, r)
+			}
+			fs.spilled = nil
+			fs.lock.Unlock()
+			for _, r := range runs {
+				r.close()
+			}
+			if len(dir) != 0 {
+				if err := os.RemoveAll(dir); err != nil {
+					log.Warn("failed to remove sort dir", zap.String("dir", dir), zap.Error(err))
+				}
+			}
+			close(fs.done)
+			close(fs.output)
+		}()
+		fail := func(err error) {
+			log.Error("file sorter failed", zap.Error(err))
+			select {
+			case <-ctx.Done():
+			case errCh <- err:
+			}
+		}
+
+		fs.lock.Lock()
+		err := fs.err
+		fs.lock.Unlock()
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case resolvedTs := <-fs.resolvedCh:
+				fs.lock.Lock()
+				toSort := fs.unsorted
+				fs.unsorted = nil
+				fs.memSize = 0
+				spilled := fs.spilled
+				fs.spilled = nil
+				err := fs.err
+				fs.lock.Unlock()
+				for _, r := range spilled {
+					runs = append(runs, r)
+				}
+				if err != nil {
+					fail(err)
+					return
+				}
+
+				sort.Slice(toSort, func(i, j int) bool {
+					return entryLess(toSort[i], toSort[j])
+				})
+				if len(toSort) > 0 {
+					run := &memRun{entries: toSort}
+					for _, entry := range toSort {
+						run.size += entryMemSize(entry)
+					}
+					// the entries not resolved are kept in memory runs, spill them
+					// if they take too much memory.
+					var memSize int64
+					for _, r := range runs {
+						if mr, ok := r.(*memRun); ok {
+							memSize += mr.size
+						}
+					}
+					if memSize+run.size >= fs.memLimit {
+						fr, err := fs.writeRun(toSort)
+						if err != nil {
+							fail(err)
+							return
+						}
+						runs = append(runs, fr)
+					} else {
+						runs = append(runs, run)
+					}
+				}
+
+				remain, err := fs.merge(ctx, runs, resolvedTs)
+				if err != nil {
+					if errors.Cause(err) != context.Canceled {
+						fail(err)
+					}
+					return
+				}
+				runs = remain
+				select {
+				case <-ctx.Done():
+					return
+				case fs.output <- &model.RawKVEntry{Ts: resolvedTs, OpType: model.OpTypeResolved}:
+				}
+			}
+		}
+	}()
+}
+
+// merge outputs the entries not greater than resolvedTs in all the runs in order,
+// and returns the runs not exhausted.
+func (fs *FileSorter) merge(ctx context.Context, runs []sortedRun, resolvedTs uint64) ([]sortedRun, error) {
+	h := make(runHeap, 0, len(runs))
+	remain := make([]sortedRun, 0, len(runs))
+	for _, r := range runs {
+		entry, err := r.peek()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if entry == nil {
+			r.close()
+			continue
+		}
+		h = append(h, r)
+	}
+	heap.Init(&h)
+	for h.Len() > 0 {
+		r := h[0]
+		entry, _ := r.peek()
+		if entry.Ts > resolvedTs {
+			remain = append(remain, heap.Pop(&h).(sortedRun))
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case fs.output <- entry:
+		}
+		if err := r.next(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		next, err := r.peek()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if next == nil {
+			heap.Pop(&h)
+			r.close()
+			continue
+		}
+		heap.Fix(&h, 0)
+	}
+	return remain, nil
+}
+
+// AddEntry adds an RawKVEntry to the FileSorter
+func (fs *FileSorter) AddEntry(ctx context.Context, entry *model.RawKVEntry) {
+	if atomic.LoadInt32(&fs.closed) != 0 {
+		return
+	}
+	if entry.OpType == model.OpTypeResolved {
+		select {
+		case <-ctx.Done():
+		case <-fs.done:
+		case fs.resolvedCh <- entry.Ts:
+		}
+		return
+	}
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	fs.unsorted = append(fs.unsorted, entry)
+	fs.memSize += entryMemSize(entry)
+	if fs.memSize >= fs.memLimit && fs.err == nil {
+		fs.err = fs.spill()
+	}
+}
+
+// spill sorts the unsorted entries and writes them to a new file, it must be called with the lock held.
+func (fs *FileSorter) spill() error {
+	toSort := fs.unsorted
+	sort.Slice(toSort, func(i, j int) bool {
+		return entryLess(toSort[i], toSort[j])
+	})
+	run, err := fs.writeRun(toSort)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	fs.spilled = append(fs.spilled, run)
+	fs.unsorted = nil
+	fs.memSize = 0
+	return nil
+}
+
+// writeRun writes the sorted entries to a new file and opens it as a sorted run.
+func (fs *FileSorter) writeRun(entries []*model.RawKVEntry) (*fileRun, error) {
+	id := atomic.AddInt64(&fs.fileID, 1)
+	name := filepath.Join(fs.dir, "run-"+strconv.FormatInt(id, 10))
+	if err := writeRunFile(name, entries); err != nil {
+		return nil, errors.Trace(err)
+	}
+	log.Debug("spill entries to file", zap.String("file", name), zap.Int("count", len(entries)))
+	return openFileRun(name)
+}
+
+// Output returns the sorted raw kv output channel
+func (fs *FileSorter) Output() <-chan *model.RawKVEntry {
+	return fs.output
+}
+
+// sortedRun is a sequence of sorted entries
+type sortedRun interface {
+	// peek returns the current entry, nil is returned if the run is exhausted
+	peek() (*model.RawKVEntry, error)
+	// next moves to the next entry
+	next() error
+	close()
+}
+
+type memRun struct {
+	entries []*model.RawKVEntry
+	idx     int
+	// size is the memory used by the entries not consumed
+	size int64
+}
+
+func (r *memRun) peek() (*model.RawKVEntry, error) {
+	if r.idx >= len(r.entries) {
+		return nil, nil
+	}
+	return r.entries[r.idx], nil
+}
+
+func (r *memRun) next() error {
+	r.size -= entryMemSize(r.entries[r.idx])
+	r.entries[r.idx] = nil
+	r.idx++
+	return nil
+}
+
+func (r *memRun) close() {
+	r.entries = nil
+}
+
+type fileRun struct {
+	name    string
+	file    *os.File
+	reader  *bufio.Reader
+	current *model.RawKVEntry
+	eof     bool
+}
+
+func openFileRun(name string) (*fileRun, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &fileRun{
+		name:   name,
+		file:   f,
+		reader: bufio.NewReaderSize(f, fileSorterBufSize),
+	}, nil
+}
+
+func (r *fileRun) peek() (*model.RawKVEntry, error) {
+	if r.current == nil && !r.eof {
+		entry, err := readEntry(r.reader)
+		if err == io.EOF {
+			r.eof = true
+			return nil, nil
+		}
+		if err != nil {
+			return nil, errors.Annotatef(err, "read sort file %s", r.name)
+		}
+		r.current = entry
+	}
+	return r.current, nil
+}
+
+func (r *fileRun) next() error {
+	r.current = nil
+	return nil
+}
+
+func (r *fileRun) close() {
+	if r.file == nil {
+		return
+	}
+	defer func() { r.file = nil }()
+	if err := r.file.Close(); err != nil {
+		log.Warn("failed to close sort file", zap.String("file", r.name), zap.Error(err))
+	}
+	if err := os.Remove(r.name); err != nil {
+		log.Warn("failed to remove sort file", zap.String("file", r.name), zap.Error(err))
+	}
+}
+
+type runHeap []sortedRun
+
+func (h runHeap) Len() int { return len(h) }
+func (h runHeap) Less(i, j int) bool {
+	// the runs in the heap are never exhausted
+	ei, _ := h[i].peek()
+	ej, _ := h[j].peek()
+	return entryLess(ei, ej)
+}
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(sortedRun)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+func writeRunFile(name string, entries []*model.RawKVEntry) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	w := bufio.NewWriterSize(f, fileSorterBufSize)
+	for _, entry := range entries {
+		if err := writeEntry(w, entry); err != nil {
+			f.Close()
+			return errors.Trace(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return errors.Trace(err)
+	}
+	return errors.Trace(f.Close())
+}
+
+// writeEntry encodes an entry as: op type, ts, key length, key, value length, value
+func writeEntry(w *bufio.Writer, entry *model.RawKVEntry) error {
+	var buf [binary.MaxVarintLen64]byte
+	for _, v := range []uint64{uint64(entry.OpType), entry.Ts, uint64(len(entry.Key))} {
+		n := binary.PutUvarint(buf[:], v)
+		if _, err := w.Write(buf[:n]); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if _, err := w.Write(entry.Key); err != nil {
+		return errors.Trace(err)
+	}
+	n := binary.PutUvarint(buf[:], uint64(len(entry.Value)))
+	if _, err := w.Write(buf[:n]); err != nil {
+		return errors.Trace(err)
+	}
+	_, err := w.Write(entry.Value)
+	return errors.Trace(err)
+}
+
+func readEntry(r *bufio.Reader) (*model.RawKVEntry, error) {
+	opType, err := binary.ReadUvarint(r)
+	if err != nil {
+		// io.EOF is returned only if no byte is read
+		return nil, err
+	}
+	entry := &model.RawKVEntry{OpType: model.OpType(opType)}
+	if entry.Ts, err = binary.ReadUvarint(r); err != nil {
+		return nil, errors.Trace(noEOF(err))
+	}
+	if entry.Key, err = readBytes(r); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if entry.Value, err = readBytes(r); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return entry, nil
+}
+
+func readBytes(r *bufio.Reader) ([]byte, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if l == 0 {
+		return nil, nil
+	}
+	data := make([]byte, l)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, noEOF(err)
+	}
+	return data, nil
+}
+
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+type fileSorterSuite struct{}
+
+var _ = check.Suite(&fileSorterSuite{})
+
+func (s *fileSorterSuite) TestFileSorter(c *check.C) {
+	dir := c.MkDir()
+	// a small memory limit to spill entries frequently
+	fs := NewFileSorter(dir, 200)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fs.Run(ctx, nil)
+
+	input := []*model.RawKVEntry{
+		{Ts: 5, OpType: model.OpTypePut, Key: []byte("k5"), Value: []byte("v5")},
+		{Ts: 2, OpType: model.OpTypePut, Key: []byte("k2"), Value: []byte("v2")},
+		{Ts: 7, OpType: model.OpTypeDelete, Key: []byte("k7")},
+		{Ts: 3, OpType: model.OpTypePut, Key: []byte("k3"), Value: []byte("v3")},
+		{Ts: 2, OpType: model.OpTypeDelete, Key: []byte("k2")},
+		{Ts: 9, OpType: model.OpTypePut, Key: []byte("k9"), Value: []byte("v9")},
+	}
+	for _, entry := range input {
+		fs.AddEntry(ctx, entry)
+	}
+	fs.AddEntry(ctx, &model.RawKVEntry{Ts: 5, OpType: model.OpTypeResolved})
+	expect := []*model.RawKVEntry{
+		{Ts: 2, OpType: model.OpTypeDelete, Key: []byte("k2")},
+		{Ts: 2, OpType: model.OpTypePut, Key: []byte("k2"), Value: []byte("v2")},
+		{Ts: 3, OpType: model.OpTypePut, Key: []byte("k3"), Value: []byte("v3")},
+		{Ts: 5, OpType: model.OpTypePut, Key: []byte("k5"), Value: []byte("v5")},
+		{Ts: 5, OpType: model.OpTypeResolved},
+	}
+	for _, e := range expect {
+		c.Assert(<-fs.Output(), check.DeepEquals, e)
+	}
+
+	fs.AddEntry(ctx, &model.RawKVEntry{Ts: 8, OpType: model.OpTypePut, Key: []byte("k8"), Value: []byte("v8")})
+	fs.AddEntry(ctx, &model.RawKVEntry{Ts: 10, OpType: model.OpTypeResolved})
+	expect = []*model.RawKVEntry{
+		{Ts: 7, OpType: model.OpTypeDelete, Key: []byte("k7")},
+		{Ts: 8, OpType: model.OpTypePut, Key: []byte("k8"), Value: []byte("v8")},
+		{Ts: 9, OpType: model.OpTypePut, Key: []byte("k9"), Value: []byte("v9")},
+		{Ts: 10, OpType: model.OpTypeResolved},
+	}
+	for _, e := range expect {
+		c.Assert(<-fs.Output(), check.DeepEquals, e)
+	}
+
+	// the spilled files are removed after the sorter exits
+	cancel()
+	for range fs.Output() {
+	}
+	files, err := ioutil.ReadDir(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 0)
+}
+
+func (s *fileSorterSuite) TestFileSorterRandomly(c *check.C) {
+	fs := NewFileSorter(c.MkDir(), 16*1024)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fs.Run(ctx, nil)
+
+	maxTs := uint64(100000)
+	total := 0
+	go func() {
+		for resolvedTs := uint64(1); resolvedTs <= maxTs; resolvedTs += 4000 {
+			for i := 0; i < 1000; i++ {
+				ts := uint64(int64(resolvedTs) + rand.Int63n(int64(maxTs-resolvedTs)))
+				fs.AddEntry(ctx, &model.RawKVEntry{
+					Ts:     ts,
+					OpType: model.OpTypePut,
+					Key:    []byte(fmt.Sprintf("key-%d", ts)),
+				})
+			}
+			fs.AddEntry(ctx, &model.RawKVEntry{Ts: resolvedTs, OpType: model.OpTypeResolved})
+		}
+		fs.AddEntry(ctx, &model.RawKVEntry{Ts: maxTs, OpType: model.OpTypeResolved})
+	}()
+	var lastTs uint64
+	var resolvedTs uint64
+	for entry := range fs.Output() {
+		c.Assert(entry.Ts, check.GreaterEqual, lastTs)
+		lastTs = entry.Ts
+		if entry.OpType == model.OpTypeResolved {
+			resolvedTs = entry.Ts
+		} else {
+			c.Assert(entry.Key, check.DeepEquals, []byte(fmt.Sprintf("key-%d", entry.Ts)))
+			total++
+		}
+		if resolvedTs == maxTs {
+			break
+		}
+	}
+	c.Assert(total, check.Equals, 25*1000)
+}
+
+func (s *fileSorterSuite) TestFileSorterDirFailure(c *check.C) {
+	// the sort dir can't be created in a regular file
+	file := filepath.Join(c.MkDir(), "file")
+	c.Assert(ioutil.WriteFile(file, nil, 0644), check.IsNil)
+	fs := NewFileSorter(file, 16)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	fs.Run(ctx, errCh)
+
+	// the entries are not spilled and the resolved entries don't block after the sorter exits
+	fs.AddEntry(ctx, &model.RawKVEntry{Ts: 1, OpType: model.OpTypePut, Key: []byte("k1"), Value: []byte("v1")})
+	for i := 0; i < 2048; i++ {
+		fs.AddEntry(ctx, &model.RawKVEntry{Ts: uint64(i), OpType: model.OpTypeResolved})
+	}
+	c.Assert(<-errCh, check.ErrorMatches, "create sort dir in .*")
+	_, ok := <-fs.Output()
+	c.Assert(ok, check.IsFalse)
+}
+
+func (s *fileSorterSuite) TestNewSorterConfig(c *check.C) {
+	cfg, err := NewSorterConfig("", "")
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.Engine, check.Equals, SortInMemory)
+	_, ok := cfg.newSorter().(*EntrySorter)
+	c.Assert(ok, check.IsTrue)
+
+	dir := c.MkDir()
+	cfg, err = NewSorterConfig(SortInFile, dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.Dir, check.Equals, dir)
+	_, ok = cfg.newSorter().(*FileSorter)
+	c.Assert(ok, check.IsTrue)
+
+	_, err = NewSorterConfig("unknown", "")
+	c.Assert(err, check.ErrorMatches, "unknown sort engine.*")
+}
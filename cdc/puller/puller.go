@@ -23,6 +23,7 @@ import (
 	pd "github.com/pingcap/pd/client"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -49,6 +50,7 @@ type resolveTsTracker interface {
 
 type pullerImpl struct {
 	pdCli        pd.Client
+	credential   *security.Credential
 	checkpointTs uint64
 	spans        []util.Span
 	buffer       *memBuffer
@@ -57,6 +59,14 @@ type pullerImpl struct {
 	resolvedTs   uint64
 	// needEncode represents whether we need to encode a key when checking it is in span
 	needEncode bool
+	// sorterConfig decides the sorter of the sorted output, entries are sorted in memory if it's nil
+	sorterConfig *SorterConfig
+	// sorterErrCh receives the error stopping the sorter, which is returned by Run
+	sorterErrCh chan error
+	// kvClient is the kv client shared by the pullers, a new client is created if it's nil
+	kvClient *kv.CDCClient
+	// recorder records the received events if it's not nil
+	recorder *Recorder
 }
 
 // CancellablePuller is a puller that can be stopped with the Cancel function
@@ -70,6 +80,7 @@ type CancellablePuller struct {
 // and put into buf.
 func NewPuller(
 	pdCli pd.Client,
+	credential *security.Credential,
 	checkpointTs uint64,
 	spans []util.Span,
 	needEncode bool,
@@ -77,17 +88,29 @@ func NewPuller(
 ) *pullerImpl {
 	p := &pullerImpl{
 		pdCli:        pdCli,
+		credential:   credential,
 		checkpointTs: checkpointTs,
 		spans:        spans,
 		buffer:       makeMemBuffer(limitter),
 		chanBuffer:   makeChanBuffer(),
 		tsTracker:    makeSpanFrontier(spans...),
 		needEncode:   needEncode,
+		sorterErrCh:  make(chan error, 1),
 	}
 
 	return p
 }
 
+// SetClient sets the kv client shared by the pullers, it must be called before Run.
+func (p *pullerImpl) SetClient(cli *kv.CDCClient) {
+	p.kvClient = cli
+}
+
+// SetRecorder records the events received by the puller, it must be called before Run.
+func (p *pullerImpl) SetRecorder(recorder *Recorder) {
+	p.recorder = recorder
+}
+
 func (p *pullerImpl) Output() ChanBuffer {
 	return p.chanBuffer
 }
@@ -95,9 +118,9 @@ func (p *pullerImpl) Output() ChanBuffer {
 func (p *pullerImpl) SortedOutput(ctx context.Context) <-chan *model.RawKVEntry {
 	captureID := util.CaptureIDFromCtx(ctx)
 	changefeedID := util.ChangefeedIDFromCtx(ctx)
-	sorter := NewEntrySorter()
+	sorter := p.sorterConfig.newSorter()
 	go func() {
-		sorter.Run(ctx)
+		sorter.Run(ctx, p.sorterErrCh)
 		for {
 			be, err := p.chanBuffer.Get(ctx)
 			if err != nil {
@@ -108,20 +131,18 @@ func (p *pullerImpl) SortedOutput(ctx context.Context) <-chan *model.RawKVEntry
 			}
 			if be.Val != nil {
 				txnCollectCounter.WithLabelValues(captureID, changefeedID, "kv").Inc()
-				sorter.AddEntry(be.Val)
+				sorter.AddEntry(ctx, be.Val)
 			} else if be.Resolved != nil {
 				txnCollectCounter.WithLabelValues(captureID, changefeedID, "resolved").Inc()
-				resolvedTs := be.Resolved.ResolvedTs
-				// 1. Forward is called in a single thread
-				// 2. The only way the global minimum resolved Ts can be forwarded is that
-				// 	  the resolveTs we pass in replaces the original one
-				// Thus, we can just use resolvedTs here as the new global minimum resolved Ts.
-				forwarded := p.tsTracker.Forward(be.Resolved.Span, resolvedTs)
+				// The resolved ts of the span may be greater than the ones of the other spans,
+				// so the frontier is used as the global minimum resolved ts.
+				forwarded := p.tsTracker.Forward(be.Resolved.Span, be.Resolved.ResolvedTs)
 				if !forwarded {
 					continue
 				}
+				resolvedTs := p.tsTracker.Frontier()
 				atomic.StoreUint64(&p.resolvedTs, resolvedTs)
-				sorter.AddEntry(&model.RawKVEntry{Ts: resolvedTs, OpType: model.OpTypeResolved})
+				sorter.AddEntry(ctx, &model.RawKVEntry{Ts: resolvedTs, OpType: model.OpTypeResolved})
 			}
 		}
 	}()
@@ -130,13 +151,16 @@ func (p *pullerImpl) SortedOutput(ctx context.Context) <-chan *model.RawKVEntry
 
 // Run the puller, continually fetch event from TiKV and add event into buffer
 func (p *pullerImpl) Run(ctx context.Context) error {
-	cli, err := kv.NewCDCClient(p.pdCli)
-	if err != nil {
-		return errors.Annotate(err, "create cdc client failed")
+	cli := p.kvClient
+	if cli == nil {
+		var err error
+		cli, err = kv.NewCDCClient(p.pdCli, p.credential, nil)
+		if err != nil {
+			return errors.Annotate(err, "create cdc client failed")
+		}
+		defer cli.Close()
 	}
 
-	defer cli.Close()
-
 	g, ctx := errgroup.WithContext(ctx)
 
 	checkpointTs := p.checkpointTs
@@ -152,6 +176,7 @@ func (p *pullerImpl) Run(ctx context.Context) error {
 
 	captureID := util.CaptureIDFromCtx(ctx)
 	changefeedID := util.ChangefeedIDFromCtx(ctx)
+	recordStream := p.recorder.NewStream(p.spans, checkpointTs)
 
 	g.Go(func() error {
 		for {
@@ -182,11 +207,13 @@ func (p *pullerImpl) Run(ctx context.Context) error {
 						continue
 					}
 
+					recordStream.Record(e)
 					if err := p.buffer.AddEntry(ctx, *e); err != nil {
 						return errors.Trace(err)
 					}
 				} else if e.Resolved != nil {
 					kvEventCounter.WithLabelValues(captureID, changefeedID, "resolved").Inc()
+					recordStream.Record(e)
 					if err := p.buffer.AddEntry(ctx, *e); err != nil {
 						return errors.Trace(err)
 					}
@@ -211,6 +238,15 @@ func (p *pullerImpl) Run(ctx context.Context) error {
 		}
 	})
 
+	g.Go(func() error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-p.sorterErrCh:
+			return errors.Trace(err)
+		}
+	})
+
 	return g.Wait()
 }
 
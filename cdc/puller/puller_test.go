@@ -0,0 +1,149 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/kv/mockkv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+)
+
+type pullerSuite struct{}
+
+var _ = check.Suite(&pullerSuite{})
+
+func expectSortedEntry(c *check.C, output <-chan *model.RawKVEntry, key string, ts uint64) {
+	select {
+	case entry := <-output:
+		c.Assert(string(entry.Key), check.Equals, key)
+		c.Assert(entry.Ts, check.Equals, ts)
+	case <-time.After(10 * time.Second):
+		c.Fatalf("wait for the entry %s at %d timeout", key, ts)
+	}
+}
+
+// expectSortedResolved waits for the resolved ts, the smaller resolved ts are skipped.
+func expectSortedResolved(c *check.C, output <-chan *model.RawKVEntry, ts uint64) {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case entry := <-output:
+			c.Assert(entry.OpType, check.Equals, model.OpTypeResolved)
+			c.Assert(entry.Ts, check.LessEqual, ts)
+			if entry.Ts == ts {
+				return
+			}
+		case <-timeout:
+			c.Fatalf("wait for the resolved ts %d timeout", ts)
+		}
+	}
+}
+
+// TestPullerResolvedTs checks the resolved ts of the puller is the minimum
+// resolved ts of the regions, even if the regions are split.
+func (s *pullerSuite) TestPullerResolvedTs(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cluster, region1 := mockkv.NewCluster(c, 1)
+	defer cluster.Close()
+	region2 := cluster.Split(region1, "m")
+
+	p := NewPuller(cluster.PDClient(), nil, 100, []util.Span{{Start: []byte("a"), End: []byte("z")}}, false, nil)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- p.Run(ctx)
+	}()
+	output := p.SortedOutput(ctx)
+
+	cluster.ExpectRequest(region1, "a", "m", 100)
+	cluster.ExpectRequest(region2, "m", "z", 100)
+	cluster.Send(mockkv.InitializedEvent(region1),
+		mockkv.PrewriteEvent(region1, []byte("c"), []byte("v"), 108),
+		mockkv.CommitEvent(region1, []byte("c"), 108, 112, false),
+		mockkv.ResolvedEvent(region1, 120))
+	cluster.Send(mockkv.InitializedEvent(region2),
+		mockkv.PrewriteEvent(region2, []byte("n"), []byte("v"), 103),
+		mockkv.CommitEvent(region2, []byte("n"), 103, 105, false),
+		mockkv.ResolvedEvent(region2, 110))
+	// the key out of the span is filtered
+	cluster.Send(mockkv.CommittedEvent(region2, []byte("zz"), []byte("v"), 101, 102))
+
+	expectSortedEntry(c, output, "n", 105)
+	expectSortedResolved(c, output, 110)
+	c.Assert(p.GetResolvedTs(), check.Equals, uint64(110))
+
+	// the resolved ts is not advanced until both the regions split from region 2 are resolved
+	region3 := cluster.Split(region2, "t")
+	cluster.Send(mockkv.EpochNotMatchEvent(region2))
+	cluster.ExpectRequest(region2, "m", "t", 110)
+	cluster.ExpectRequest(region3, "t", "z", 110)
+	cluster.Send(mockkv.InitializedEvent(region2), mockkv.ResolvedEvent(region2, 130))
+	cluster.Send(mockkv.ResolvedEvent(region1, 130))
+	select {
+	case entry := <-output:
+		c.Fatalf("unexpected entry %v", entry)
+	case <-time.After(200 * time.Millisecond):
+	}
+	c.Assert(p.GetResolvedTs(), check.Equals, uint64(110))
+
+	cluster.Send(mockkv.InitializedEvent(region3), mockkv.ResolvedEvent(region3, 125))
+	expectSortedEntry(c, output, "c", 112)
+	expectSortedResolved(c, output, 125)
+	c.Assert(p.GetResolvedTs(), check.Equals, uint64(125))
+
+	cancel()
+	c.Assert(<-errCh, check.NotNil)
+}
+
+// TestSortedOutputResolvedTsNotAheadOfOtherSpans checks the resolved ts of the sorted output is
+// the frontier of all the spans. It used to be the resolved ts of the span just forwarded,
+// which could be ahead of the other spans and output their unresolved entries.
+func (s *pullerSuite) TestSortedOutputResolvedTsNotAheadOfOtherSpans(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	span1 := util.Span{Start: []byte("a"), End: []byte("m")}
+	span2 := util.Span{Start: []byte("m"), End: []byte("z")}
+	p := NewPuller(nil, nil, 100, []util.Span{span1, span2}, false, nil)
+	output := p.SortedOutput(ctx)
+	resolved := func(span util.Span, ts uint64) {
+		err := p.chanBuffer.AddEntry(ctx, model.RegionFeedEvent{Resolved: &model.ResolvedSpan{Span: span, ResolvedTs: ts}})
+		c.Assert(err, check.IsNil)
+	}
+
+	resolved(span1, 105)
+	err := p.chanBuffer.AddEntry(ctx, model.RegionFeedEvent{Val: &model.RawKVEntry{
+		OpType: model.OpTypePut, Key: []byte("c"), Value: []byte("v"), Ts: 110,
+	}})
+	c.Assert(err, check.IsNil)
+	// span 1 is still resolved at 105, so the entry at 110 is not output
+	resolved(span2, 120)
+	expectSortedResolved(c, output, 105)
+	select {
+	case entry := <-output:
+		c.Fatalf("unexpected entry %v", entry)
+	case <-time.After(200 * time.Millisecond):
+	}
+	c.Assert(p.GetResolvedTs(), check.Equals, uint64(105))
+
+	resolved(span1, 130)
+	expectSortedEntry(c, output, "c", 110)
+	expectSortedResolved(c, output, 120)
+	c.Assert(p.GetResolvedTs(), check.Equals, uint64(120))
+}
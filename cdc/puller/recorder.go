@@ -0,0 +1,442 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+	"go.uber.org/zap"
+)
+
+// The recording file starts with the magic and the version, which are followed by the records.
+// Every record starts with its kind:
+//   meta:     the length and the JSON of RecordMeta
+//   stream:   stream ID, start ts, the number of spans and the start and end key of every span
+//   kv:       stream ID and the entry encoded by writeEntry
+//   resolved: stream ID, the start and end key of the span and the resolved ts
+// A stream is the events received by a recorded puller.
+const (
+	recordMagic   = "TICDCREC"
+	recordVersion = 1
+
+	recordKindMeta     byte = 1
+	recordKindStream   byte = 2
+	recordKindKV       byte = 3
+	recordKindResolved byte = 4
+
+	recorderBufSize       = 64 * 1024
+	recorderFlushInterval = time.Second
+)
+
+var errRecorderClosed = errors.New("recorder is closed")
+
+// RecordMeta describes a recording, SchemaJobs are the DDL jobs which create
+// the schemas and tables at StartTs.
+type RecordMeta struct {
+	ChangefeedID string         `json:"changefeed-id"`
+	CaptureID    string         `json:"capture-id"`
+	StartTs      uint64         `json:"start-ts"`
+	SchemaJobs   []*timodel.Job `json:"schema-jobs"`
+}
+
+// Recorder writes the region feed events received by the pullers to a local file.
+// The recording stops with a warning if the file fails to be written, so it never
+// breaks the replication.
+type Recorder struct {
+	path string
+
+	mu           sync.Mutex
+	f            *os.File
+	w            *bufio.Writer
+	nextStreamID uint64
+	lastFlush    time.Time
+	err          error
+}
+
+// NewRecorder creates the recording file in dir and writes the meta.
+func NewRecorder(dir string, meta *RecordMeta) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Annotatef(err, "create recording dir %s", dir)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s-%d.rec", meta.ChangefeedID, meta.CaptureID, meta.StartTs))
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Annotatef(err, "create recording file %s", path)
+	}
+	r := &Recorder{
+		path:      path,
+		f:         f,
+		w:         bufio.NewWriterSize(f, recorderBufSize),
+		lastFlush: time.Now(),
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		f.Close()
+		return nil, errors.Trace(err)
+	}
+	err = r.write(func(w *bufio.Writer) error {
+		if _, err := w.WriteString(recordMagic); err != nil {
+			return err
+		}
+		if err := writeUvarints(w, recordVersion); err != nil {
+			return err
+		}
+		if err := w.WriteByte(recordKindMeta); err != nil {
+			return err
+		}
+		if err := writeBytes(w, data); err != nil {
+			return err
+		}
+		return w.Flush()
+	})
+	if err != nil {
+		f.Close()
+		return nil, errors.Trace(err)
+	}
+	log.Info("start recording", zap.String("path", path), zap.Uint64("startTs", meta.StartTs))
+	return r, nil
+}
+
+// Path returns the path of the recording file
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Close flushes and closes the recording file, it's safe to be called more than once.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	var err error
+	if r.err == nil {
+		err = r.w.Flush()
+	}
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	r.f = nil
+	r.err = errRecorderClosed
+	return errors.Trace(err)
+}
+
+// write writes a record with fn, the recording stops if it fails.
+func (r *Recorder) write(fn func(w *bufio.Writer) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	err := fn(r.w)
+	if err == nil && time.Since(r.lastFlush) >= recorderFlushInterval {
+		err = r.w.Flush()
+		r.lastFlush = time.Now()
+	}
+	if err != nil {
+		log.Warn("write recording failed, the recording stops", zap.String("path", r.path), zap.Error(err))
+		r.err = err
+	}
+	return err
+}
+
+// NewStream declares the stream of a recorded puller, nil is returned if r is nil.
+func (r *Recorder) NewStream(spans []util.Span, startTs uint64) *RecordStream {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	r.nextStreamID++
+	s := &RecordStream{id: r.nextStreamID, recorder: r}
+	r.mu.Unlock()
+
+	_ = r.write(func(w *bufio.Writer) error {
+		if err := w.WriteByte(recordKindStream); err != nil {
+			return err
+		}
+		if err := writeUvarints(w, s.id, startTs, uint64(len(spans))); err != nil {
+			return err
+		}
+		for _, span := range spans {
+			if err := writeBytes(w, span.Start); err != nil {
+				return err
+			}
+			if err := writeBytes(w, span.End); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return s
+}
+
+// RecordStream records the events received by a puller
+type RecordStream struct {
+	id       uint64
+	recorder *Recorder
+}
+
+// Record writes the event, it does nothing if s is nil.
+func (s *RecordStream) Record(e *model.RegionFeedEvent) {
+	if s == nil {
+		return
+	}
+	_ = s.recorder.write(func(w *bufio.Writer) error {
+		switch {
+		case e.Val != nil:
+			if err := w.WriteByte(recordKindKV); err != nil {
+				return err
+			}
+			if err := writeUvarints(w, s.id); err != nil {
+				return err
+			}
+			return writeEntry(w, e.Val)
+		case e.Resolved != nil:
+			if err := w.WriteByte(recordKindResolved); err != nil {
+				return err
+			}
+			if err := writeUvarints(w, s.id); err != nil {
+				return err
+			}
+			if err := writeBytes(w, e.Resolved.Span.Start); err != nil {
+				return err
+			}
+			if err := writeBytes(w, e.Resolved.Span.End); err != nil {
+				return err
+			}
+			return writeUvarints(w, e.Resolved.ResolvedTs)
+		}
+		return nil
+	})
+}
+
+func writeUvarints(w *bufio.Writer, values ...uint64) error {
+	var buf [binary.MaxVarintLen64]byte
+	for _, v := range values {
+		n := binary.PutUvarint(buf[:], v)
+		if _, err := w.Write(buf[:n]); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func writeBytes(w *bufio.Writer, data []byte) error {
+	if err := writeUvarints(w, uint64(len(data))); err != nil {
+		return errors.Trace(err)
+	}
+	_, err := w.Write(data)
+	return errors.Trace(err)
+}
+
+// RecordedStream is a puller declared in the recording
+type RecordedStream struct {
+	ID      uint64
+	Spans   []util.Span
+	StartTs uint64
+}
+
+// Record is a record read from the recording, it either declares a stream or
+// contains an event of the stream.
+type Record struct {
+	StreamID uint64
+	Stream   *RecordedStream
+	Event    *model.RegionFeedEvent
+}
+
+// RecordReader reads the records from a recording file
+type RecordReader struct {
+	path string
+	f    *os.File
+	r    *bufio.Reader
+	meta *RecordMeta
+}
+
+// OpenRecording opens the recording file and reads its meta.
+func OpenRecording(path string) (*RecordReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	r := &RecordReader{path: path, f: f, r: bufio.NewReaderSize(f, recorderBufSize)}
+	if err := r.readMeta(); err != nil {
+		f.Close()
+		return nil, errors.Annotatef(err, "read the meta of recording %s", path)
+	}
+	return r, nil
+}
+
+func (r *RecordReader) readMeta() error {
+	magic := make([]byte, len(recordMagic))
+	if _, err := io.ReadFull(r.r, magic); err != nil || string(magic) != recordMagic {
+		return errors.New("not a recording file")
+	}
+	version, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return errors.Trace(noEOF(err))
+	}
+	if version != recordVersion {
+		return errors.Errorf("unsupported recording version %d", version)
+	}
+	kind, err := r.r.ReadByte()
+	if err != nil {
+		return errors.Trace(noEOF(err))
+	}
+	if kind != recordKindMeta {
+		return errors.Errorf("unexpected record kind %d", kind)
+	}
+	data, err := readBytes(r.r)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	r.meta = new(RecordMeta)
+	return errors.Trace(json.Unmarshal(data, r.meta))
+}
+
+// Meta returns the meta of the recording
+func (r *RecordReader) Meta() *RecordMeta {
+	return r.meta
+}
+
+// Next reads the next record, io.EOF is returned at the end of the recording.
+// The recording may be truncated if the capture exits unexpectedly, the truncated
+// record is ignored.
+func (r *RecordReader) Next() (*Record, error) {
+	record, err := r.next()
+	if errors.Cause(err) == io.ErrUnexpectedEOF {
+		log.Warn("the last record is truncated", zap.String("path", r.path))
+		return nil, io.EOF
+	}
+	return record, err
+}
+
+func (r *RecordReader) next() (*Record, error) {
+	kind, err := r.r.ReadByte()
+	if err != nil {
+		// io.EOF is returned only if no byte is read
+		return nil, err
+	}
+	streamID, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, errors.Trace(noEOF(err))
+	}
+	record := &Record{StreamID: streamID}
+	switch kind {
+	case recordKindStream:
+		stream := &RecordedStream{ID: streamID}
+		var n uint64
+		if stream.StartTs, err = binary.ReadUvarint(r.r); err != nil {
+			return nil, errors.Trace(noEOF(err))
+		}
+		if n, err = binary.ReadUvarint(r.r); err != nil {
+			return nil, errors.Trace(noEOF(err))
+		}
+		for i := uint64(0); i < n; i++ {
+			span, err := r.readSpan()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			stream.Spans = append(stream.Spans, span)
+		}
+		record.Stream = stream
+	case recordKindKV:
+		entry, err := readEntry(r.r)
+		if err != nil {
+			return nil, errors.Trace(noEOF(err))
+		}
+		record.Event = &model.RegionFeedEvent{Val: entry}
+	case recordKindResolved:
+		span, err := r.readSpan()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		ts, err := binary.ReadUvarint(r.r)
+		if err != nil {
+			return nil, errors.Trace(noEOF(err))
+		}
+		record.Event = &model.RegionFeedEvent{Resolved: &model.ResolvedSpan{Span: span, ResolvedTs: ts}}
+	default:
+		return nil, errors.Errorf("unexpected record kind %d", kind)
+	}
+	return record, nil
+}
+
+func (r *RecordReader) readSpan() (util.Span, error) {
+	start, err := readBytes(r.r)
+	if err != nil {
+		return util.Span{}, errors.Trace(err)
+	}
+	end, err := readBytes(r.r)
+	if err != nil {
+		return util.Span{}, errors.Trace(err)
+	}
+	return util.Span{Start: start, End: end}, nil
+}
+
+// Close closes the recording file
+func (r *RecordReader) Close() error {
+	return errors.Trace(r.f.Close())
+}
+
+// ReplayPuller sorts the recorded events of a stream the same as the puller which
+// received them, the events are added from the recording instead of TiKV.
+type ReplayPuller struct {
+	puller *pullerImpl
+	// tracker is the frontier of the added resolved spans
+	tracker resolveTsTracker
+}
+
+// NewReplayPuller creates a ReplayPuller for the stream
+func NewReplayPuller(stream *RecordedStream) *ReplayPuller {
+	return &ReplayPuller{
+		puller:  NewPuller(nil, nil, stream.StartTs, stream.Spans, false, nil),
+		tracker: makeSpanFrontier(stream.Spans...),
+	}
+}
+
+// AddEvent adds a recorded event of the stream
+func (p *ReplayPuller) AddEvent(ctx context.Context, e *model.RegionFeedEvent) error {
+	if e.Resolved != nil {
+		p.tracker.Forward(e.Resolved.Span, e.Resolved.ResolvedTs)
+	}
+	return errors.Trace(p.puller.chanBuffer.AddEntry(ctx, *e))
+}
+
+// ResolvedTs returns the resolved ts of the added events, it must be called
+// in the same goroutine as AddEvent.
+func (p *ReplayPuller) ResolvedTs() uint64 {
+	return p.tracker.Frontier()
+}
+
+// SortedOutput returns the sorted entries of the added events
+func (p *ReplayPuller) SortedOutput(ctx context.Context) <-chan *model.RawKVEntry {
+	return p.puller.SortedOutput(ctx)
+}
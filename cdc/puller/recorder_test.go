@@ -0,0 +1,157 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+)
+
+type recorderSuite struct{}
+
+var _ = check.Suite(&recorderSuite{})
+
+func kvEvent(key string, ts uint64) *model.RegionFeedEvent {
+	return &model.RegionFeedEvent{Val: &model.RawKVEntry{OpType: model.OpTypePut, Key: []byte(key), Value: []byte("v"), Ts: ts}}
+}
+
+func resolvedEvent(start, end string, ts uint64) *model.RegionFeedEvent {
+	return &model.RegionFeedEvent{Resolved: &model.ResolvedSpan{
+		Span:       util.Span{Start: []byte(start), End: []byte(end)},
+		ResolvedTs: ts,
+	}}
+}
+
+func (s *recorderSuite) TestRecordAndRead(c *check.C) {
+	dir := c.MkDir()
+	meta := &RecordMeta{
+		ChangefeedID: "cf",
+		CaptureID:    "capture",
+		StartTs:      10,
+		SchemaJobs:   []*timodel.Job{{ID: 1, Type: timodel.ActionCreateSchema, Query: "create database test"}},
+	}
+	recorder, err := NewRecorder(dir, meta)
+	c.Assert(err, check.IsNil)
+
+	var nilRecorder *Recorder
+	c.Assert(nilRecorder.NewStream(nil, 0), check.IsNil)
+	nilRecorder.NewStream(nil, 0).Record(kvEvent("a", 1))
+	c.Assert(nilRecorder.Close(), check.IsNil)
+
+	stream1 := recorder.NewStream([]util.Span{{Start: []byte("a"), End: []byte("m")}}, 10)
+	stream2 := recorder.NewStream([]util.Span{{Start: []byte("m"), End: []byte("z")}}, 20)
+	events := []*model.RegionFeedEvent{kvEvent("b", 12), resolvedEvent("m", "z", 25), resolvedEvent("a", "m", 15)}
+	stream1.Record(events[0])
+	stream2.Record(events[1])
+	stream1.Record(events[2])
+	c.Assert(recorder.Close(), check.IsNil)
+	c.Assert(recorder.Close(), check.IsNil)
+	// the events are dropped after the recorder is closed
+	stream1.Record(kvEvent("c", 16))
+
+	reader, err := OpenRecording(recorder.Path())
+	c.Assert(err, check.IsNil)
+	defer reader.Close()
+	c.Assert(reader.Meta().ChangefeedID, check.Equals, "cf")
+	c.Assert(reader.Meta().StartTs, check.Equals, uint64(10))
+	c.Assert(reader.Meta().SchemaJobs, check.HasLen, 1)
+	c.Assert(reader.Meta().SchemaJobs[0].Query, check.Equals, "create database test")
+
+	expected := []*Record{
+		{StreamID: 1, Stream: &RecordedStream{ID: 1, Spans: []util.Span{{Start: []byte("a"), End: []byte("m")}}, StartTs: 10}},
+		{StreamID: 2, Stream: &RecordedStream{ID: 2, Spans: []util.Span{{Start: []byte("m"), End: []byte("z")}}, StartTs: 20}},
+		{StreamID: 1, Event: events[0]},
+		{StreamID: 2, Event: events[1]},
+		{StreamID: 1, Event: events[2]},
+	}
+	for _, e := range expected {
+		record, err := reader.Next()
+		c.Assert(err, check.IsNil)
+		c.Assert(record, check.DeepEquals, e)
+	}
+	_, err = reader.Next()
+	c.Assert(err, check.Equals, io.EOF)
+}
+
+func (s *recorderSuite) TestReadTruncatedRecording(c *check.C) {
+	dir := c.MkDir()
+	recorder, err := NewRecorder(dir, &RecordMeta{ChangefeedID: "cf", CaptureID: "capture", StartTs: 10})
+	c.Assert(err, check.IsNil)
+	stream := recorder.NewStream([]util.Span{{Start: []byte("a"), End: []byte("z")}}, 10)
+	stream.Record(kvEvent("b", 12))
+	c.Assert(recorder.Close(), check.IsNil)
+
+	data, err := ioutil.ReadFile(recorder.Path())
+	c.Assert(err, check.IsNil)
+	c.Assert(ioutil.WriteFile(recorder.Path(), data[:len(data)-1], 0644), check.IsNil)
+	reader, err := OpenRecording(recorder.Path())
+	c.Assert(err, check.IsNil)
+	defer reader.Close()
+	record, err := reader.Next()
+	c.Assert(err, check.IsNil)
+	c.Assert(record.Stream, check.NotNil)
+	// the truncated record is ignored
+	_, err = reader.Next()
+	c.Assert(err, check.Equals, io.EOF)
+
+	// the file is not a recording
+	path := recorder.Path() + ".bad"
+	c.Assert(ioutil.WriteFile(path, []byte("bad"), 0644), check.IsNil)
+	_, err = OpenRecording(path)
+	c.Assert(err, check.ErrorMatches, ".*not a recording file.*")
+	_, err = OpenRecording(path + ".missing")
+	c.Assert(os.IsNotExist(errors.Cause(err)), check.IsTrue)
+}
+
+func (s *recorderSuite) TestReplayPuller(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := NewReplayPuller(&RecordedStream{
+		ID:      1,
+		Spans:   []util.Span{{Start: []byte("a"), End: []byte("z")}},
+		StartTs: 10,
+	})
+	output := p.SortedOutput(ctx)
+	for _, e := range []*model.RegionFeedEvent{
+		kvEvent("c", 14), kvEvent("b", 12),
+		resolvedEvent("a", "m", 15),
+		kvEvent("n", 13),
+		resolvedEvent("m", "z", 20),
+	} {
+		c.Assert(p.AddEvent(ctx, e), check.IsNil)
+	}
+	c.Assert(p.ResolvedTs(), check.Equals, uint64(15))
+
+	expected := []struct {
+		key string
+		ts  uint64
+	}{{"b", 12}, {"n", 13}, {"c", 14}}
+	for _, e := range expected {
+		entry := <-output
+		c.Assert(string(entry.Key), check.Equals, e.key)
+		c.Assert(entry.Ts, check.Equals, e.ts)
+	}
+	entry := <-output
+	c.Assert(entry.OpType, check.Equals, model.OpTypeResolved)
+	c.Assert(entry.Ts, check.Equals, uint64(15))
+}
@@ -0,0 +1,365 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	pd "github.com/pingcap/pd/client"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/security"
+	"github.com/pingcap/ticdc/pkg/util"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// maxSharedPullerBatchSize is the max number of entries appended
+	// to the shared buffer at once.
+	maxSharedPullerBatchSize    = 256
+	defaultSubscriptionChanSize = 128
+	// defaultSharedPullerBufferSize is the max number of entries kept by a shared puller,
+	// the puller is blocked until the slowest subscriber consumes the entries.
+	defaultSharedPullerBufferSize = 10240
+)
+
+// Registry shares the table pullers across the changefeeds on the same capture.
+// Only one KV subscription is created for a span, the sorted events are kept in
+// a shared buffer, and every subscriber consumes from its own start ts.
+type Registry struct {
+	newPuller func(startTs uint64, span util.Span, needEncode bool, sorterCfg *SorterConfig, recorder *Recorder) Puller
+	// limitter accounts the memory of the shared buffers together with the buffers of the pullers
+	limitter   *BlurResourceLimitter
+	bufferSize int
+
+	mu      sync.Mutex
+	pullers map[string][]*sharedPuller
+}
+
+// NewRegistry creates a puller Registry, the pullers subscribe the spans through kvClient
+func NewRegistry(pdCli pd.Client, credential *security.Credential, limitter *BlurResourceLimitter, kvClient *kv.CDCClient) *Registry {
+	return &Registry{
+		newPuller: func(startTs uint64, span util.Span, needEncode bool, sorterCfg *SorterConfig, recorder *Recorder) Puller {
+			p := NewPuller(pdCli, credential, startTs, []util.Span{span}, needEncode, limitter)
+			p.sorterConfig = sorterCfg
+			p.kvClient = kvClient
+			p.recorder = recorder
+			return p
+		},
+		limitter:   limitter,
+		bufferSize: defaultSharedPullerBufferSize,
+		pullers:    make(map[string][]*sharedPuller),
+	}
+}
+
+func registryKey(span util.Span, needEncode bool, sorterCfg *SorterConfig, recorder *Recorder) string {
+	key := fmt.Sprintf("%x-%x-%t", span.Start, span.End, needEncode)
+	if sorterCfg != nil && sorterCfg.Engine == SortInFile {
+		key += "-" + SortInFile + "-" + sorterCfg.Dir
+	}
+	if recorder != nil {
+		key += "-record-" + recorder.Path()
+	}
+	return key
+}
+
+// Subscribe subscribes the sorted events of the span whose commit ts is greater than startTs.
+// A shared puller is reused if it can still serve startTs, otherwise a new one is created.
+// Only the subscribers with the same sorter config and recorder share a puller,
+// the events received by the puller are recorded if recorder is not nil.
+// The returned Subscription must be run to receive the events.
+func (r *Registry) Subscribe(ctx context.Context, span util.Span, startTs uint64, needEncode bool, sorterCfg *SorterConfig, recorder *Recorder) *Subscription {
+	key := registryKey(span, needEncode, sorterCfg, recorder)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, sp := range r.pullers[key] {
+		if sub, ok := sp.subscribe(startTs); ok {
+			log.Debug("reuse shared puller", zap.Reflect("span", span),
+				zap.Uint64("startTs", startTs), zap.Uint64("pullerStartTs", sp.startTs))
+			return sub
+		}
+	}
+
+	log.Info("create shared puller", zap.Reflect("span", span), zap.Uint64("startTs", startTs))
+	pctx, cancel := context.WithCancel(util.PutCaptureIDInCtx(context.Background(), util.CaptureIDFromCtx(ctx)))
+	sp := &sharedPuller{
+		key:         key,
+		startTs:     startTs,
+		discardedTs: startTs,
+		registry:    r,
+		cancel:      cancel,
+		subscribers: make(map[*Subscription]struct{}),
+		notifyCh:    make(chan struct{}),
+		spaceCh:     make(chan struct{}),
+	}
+	r.pullers[key] = append(r.pullers[key], sp)
+	sub, _ := sp.subscribe(startTs)
+	go sp.run(pctx, r.newPuller(startTs, span, needEncode, sorterCfg, recorder))
+	return sub
+}
+
+// remove removes the shared puller from the registry if it has no subscriber.
+func (r *Registry) remove(sp *sharedPuller) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if len(sp.subscribers) > 0 {
+		return
+	}
+	pullers := r.pullers[sp.key]
+	for i, p := range pullers {
+		if p == sp {
+			pullers = append(pullers[:i], pullers[i+1:]...)
+			break
+		}
+	}
+	if len(pullers) == 0 {
+		delete(r.pullers, sp.key)
+	} else {
+		r.pullers[sp.key] = pullers
+	}
+	sp.cancel()
+}
+
+// sharedPuller runs a puller and keeps the sorted events which are not consumed by all the subscribers.
+type sharedPuller struct {
+	key      string
+	startTs  uint64
+	registry *Registry
+	cancel   context.CancelFunc
+
+	mu sync.Mutex
+	// entries are the sorted entries not consumed by all the subscribers
+	entries []*model.RawKVEntry
+	// offset is the index of entries[0] in the whole sorted stream
+	offset int
+	// size is the memory size of entries
+	size int64
+	// discardedTs is the max ts of the entries discarded from the buffer
+	discardedTs uint64
+	subscribers map[*Subscription]struct{}
+	err         error
+	// notifyCh is closed when new entries are appended or an error occurs
+	notifyCh chan struct{}
+	// spaceCh is closed when some entries are discarded from the full buffer
+	spaceCh chan struct{}
+}
+
+func (sp *sharedPuller) run(ctx context.Context, puller Puller) {
+	errg, cctx := errgroup.WithContext(ctx)
+	errg.Go(func() error {
+		return puller.Run(cctx)
+	})
+	errg.Go(func() error {
+		output := puller.SortedOutput(cctx)
+		batch := make([]*model.RawKVEntry, 0, maxSharedPullerBatchSize)
+		for {
+			select {
+			case <-cctx.Done():
+				return cctx.Err()
+			case entry, ok := <-output:
+				if !ok {
+					return cctx.Err()
+				}
+				batch = append(batch[:0], entry)
+			}
+		drain:
+			for len(batch) < maxSharedPullerBatchSize {
+				select {
+				case entry, ok := <-output:
+					if !ok {
+						break drain
+					}
+					batch = append(batch, entry)
+				default:
+					break drain
+				}
+			}
+			if err := sp.append(cctx, batch); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	})
+	err := errg.Wait()
+	if err == nil {
+		err = errors.New("shared puller exited unexpectedly")
+	}
+	sp.mu.Lock()
+	sp.err = err
+	sp.entries = nil
+	sp.registry.limitter.Add(-sp.size)
+	sp.size = 0
+	close(sp.notifyCh)
+	sp.mu.Unlock()
+}
+
+// append appends the entries to the shared buffer, it blocks while the buffer is full
+// until the slowest subscriber consumes some entries.
+func (sp *sharedPuller) append(ctx context.Context, batch []*model.RawKVEntry) error {
+	sp.mu.Lock()
+	for len(sp.entries) >= sp.registry.bufferSize && sp.err == nil {
+		spaceCh := sp.spaceCh
+		sp.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-spaceCh:
+		}
+		sp.mu.Lock()
+	}
+	defer sp.mu.Unlock()
+	if sp.err != nil {
+		return nil
+	}
+	var size int64
+	for _, entry := range batch {
+		size += int64(rawEntrySize(entry))
+	}
+	sp.entries = append(sp.entries, batch...)
+	sp.size += size
+	sp.registry.limitter.Add(size)
+	close(sp.notifyCh)
+	sp.notifyCh = make(chan struct{})
+	return nil
+}
+
+// subscribe adds a subscriber which consumes the entries after startTs,
+// it fails if some entries after startTs have been discarded.
+func (sp *sharedPuller) subscribe(startTs uint64) (*Subscription, bool) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.err != nil || startTs < sp.discardedTs {
+		return nil, false
+	}
+	sub := &Subscription{
+		puller:   sp,
+		startTs:  startTs,
+		cursor:   sp.offset,
+		consumed: sp.offset,
+		output:   make(chan *model.RawKVEntry, defaultSubscriptionChanSize),
+	}
+	sp.subscribers[sub] = struct{}{}
+	return sub, true
+}
+
+func (sp *sharedPuller) unsubscribe(sub *Subscription) {
+	sp.mu.Lock()
+	delete(sp.subscribers, sub)
+	sp.gc()
+	empty := len(sp.subscribers) == 0
+	sp.mu.Unlock()
+	if empty {
+		sp.registry.remove(sp)
+	}
+}
+
+// fetch returns the entries from the cursor of the subscriber, or a channel to wait for new entries.
+func (sp *sharedPuller) fetch(sub *Subscription) ([]*model.RawKVEntry, <-chan struct{}, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.err != nil {
+		return nil, nil, sp.err
+	}
+	sp.gc()
+	idx := sub.cursor - sp.offset
+	if idx >= len(sp.entries) {
+		return nil, sp.notifyCh, nil
+	}
+	entries := sp.entries[idx:]
+	sub.cursor += len(entries)
+	return entries, nil, nil
+}
+
+// gc discards the entries which are consumed by all the subscribers.
+func (sp *sharedPuller) gc() {
+	if len(sp.subscribers) == 0 {
+		return
+	}
+	minCursor := sp.offset + len(sp.entries)
+	for sub := range sp.subscribers {
+		if sub.consumed < minCursor {
+			minCursor = sub.consumed
+		}
+	}
+	n := minCursor - sp.offset
+	if n <= 0 {
+		return
+	}
+	var size int64
+	for _, entry := range sp.entries[:n] {
+		size += int64(rawEntrySize(entry))
+	}
+	sp.discardedTs = sp.entries[n-1].Ts
+	sp.entries = sp.entries[n:]
+	sp.offset = minCursor
+	sp.size -= size
+	sp.registry.limitter.Add(-size)
+	close(sp.spaceCh)
+	sp.spaceCh = make(chan struct{})
+}
+
+// Subscription receives the sorted entries from a shared puller.
+type Subscription struct {
+	puller  *sharedPuller
+	startTs uint64
+	// cursor is the index of the next entry to fetch, protected by the lock of puller
+	cursor int
+	// consumed is the index of the next entry to output, protected by the lock of puller
+	consumed int
+	output   chan *model.RawKVEntry
+}
+
+// Output returns the channel of the sorted entries
+func (s *Subscription) Output() <-chan *model.RawKVEntry {
+	return s.output
+}
+
+// Run sends the entries after the start ts to the output until ctx is done or the shared puller fails.
+func (s *Subscription) Run(ctx context.Context) error {
+	defer s.puller.unsubscribe(s)
+	for {
+		entries, waitCh, err := s.puller.fetch(s)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if waitCh != nil {
+			select {
+			case <-ctx.Done():
+				return errors.Trace(ctx.Err())
+			case <-waitCh:
+			}
+			continue
+		}
+		for _, entry := range entries {
+			if entry.Ts <= s.startTs {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return errors.Trace(ctx.Err())
+			case s.output <- entry:
+			}
+		}
+		s.puller.mu.Lock()
+		s.consumed = s.cursor
+		s.puller.mu.Unlock()
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+)
+
+type registrySuite struct{}
+
+var _ = check.Suite(&registrySuite{})
+
+// feedPuller is a Puller whose sorted output is fed by the test.
+type feedPuller struct {
+	startTs uint64
+	ch      chan *model.RawKVEntry
+}
+
+func (p *feedPuller) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (p *feedPuller) GetResolvedTs() uint64 {
+	return 0
+}
+
+func (p *feedPuller) Output() ChanBuffer {
+	return nil
+}
+
+func (p *feedPuller) SortedOutput(ctx context.Context) <-chan *model.RawKVEntry {
+	return p.ch
+}
+
+type feedPullers struct {
+	mu      sync.Mutex
+	pullers []*feedPuller
+}
+
+func (f *feedPullers) newPuller(startTs uint64, span util.Span, needEncode bool, sorterCfg *SorterConfig, recorder *Recorder) Puller {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := &feedPuller{startTs: startTs, ch: make(chan *model.RawKVEntry, 16)}
+	f.pullers = append(f.pullers, p)
+	return p
+}
+
+func (f *feedPullers) get(i int) *feedPuller {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pullers[i]
+}
+
+func (f *feedPullers) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.pullers)
+}
+
+func newTestRegistry() (*Registry, *feedPullers) {
+	f := &feedPullers{}
+	r := &Registry{
+		newPuller:  f.newPuller,
+		limitter:   NewBlurResourceLimmter(1 << 30),
+		bufferSize: defaultSharedPullerBufferSize,
+		pullers:    make(map[string][]*sharedPuller),
+	}
+	return r, f
+}
+
+func (s *registrySuite) runSub(ctx context.Context, sub *Subscription) <-chan error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- sub.Run(ctx)
+	}()
+	return errCh
+}
+
+func expectTs(c *check.C, sub *Subscription, ts ...uint64) {
+	for _, t := range ts {
+		select {
+		case entry := <-sub.Output():
+			c.Assert(entry.Ts, check.Equals, t)
+		case <-time.After(3 * time.Second):
+			c.Fatalf("wait for entry %d timeout", t)
+		}
+	}
+}
+
+func (s *registrySuite) TestShareAndCleanUp(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, f := newTestRegistry()
+	span := util.Span{Start: []byte("a"), End: []byte("b")}
+
+	ctx1, cancel1 := context.WithCancel(ctx)
+	sub1 := r.Subscribe(ctx1, span, 1, true, nil, nil)
+	errCh1 := s.runSub(ctx1, sub1)
+	p := f.get(0)
+	p.ch <- &model.RawKVEntry{Ts: 2, OpType: model.OpTypePut}
+	p.ch <- &model.RawKVEntry{Ts: 3, OpType: model.OpTypePut}
+	expectTs(c, sub1, 2, 3)
+
+	// the second changefeed starts from ts 3 and shares the same puller
+	ctx2, cancel2 := context.WithCancel(ctx)
+	sub2 := r.Subscribe(ctx2, span, 3, true, nil, nil)
+	errCh2 := s.runSub(ctx2, sub2)
+	c.Assert(f.len(), check.Equals, 1)
+
+	p.ch <- &model.RawKVEntry{Ts: 4, OpType: model.OpTypePut}
+	p.ch <- &model.RawKVEntry{Ts: 4, OpType: model.OpTypeResolved}
+	expectTs(c, sub1, 4, 4)
+	expectTs(c, sub2, 4, 4)
+
+	// a different span uses a different puller
+	sub3 := r.Subscribe(ctx, util.Span{Start: []byte("b"), End: []byte("c")}, 1, true, nil, nil)
+	c.Assert(f.len(), check.Equals, 2)
+	c.Assert(sub3.puller, check.Not(check.Equals), sub1.puller)
+
+	cancel1()
+	c.Assert(<-errCh1, check.NotNil)
+	r.mu.Lock()
+	c.Assert(r.pullers[registryKey(span, true, nil, nil)], check.HasLen, 1)
+	r.mu.Unlock()
+
+	cancel2()
+	c.Assert(<-errCh2, check.NotNil)
+	r.mu.Lock()
+	_, exist := r.pullers[registryKey(span, true, nil, nil)]
+	r.mu.Unlock()
+	c.Assert(exist, check.IsFalse)
+}
+
+func (s *registrySuite) TestNewPullerForDiscardedData(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, f := newTestRegistry()
+	span := util.Span{Start: []byte("a"), End: []byte("b")}
+
+	sub1 := r.Subscribe(ctx, span, 5, true, nil, nil)
+	s.runSub(ctx, sub1)
+	p := f.get(0)
+	p.ch <- &model.RawKVEntry{Ts: 6, OpType: model.OpTypePut}
+	p.ch <- &model.RawKVEntry{Ts: 7, OpType: model.OpTypePut}
+	expectTs(c, sub1, 6, 7)
+	p.ch <- &model.RawKVEntry{Ts: 8, OpType: model.OpTypePut}
+	expectTs(c, sub1, 8)
+
+	// the data before ts 5 is never pulled
+	sub2 := r.Subscribe(ctx, span, 3, true, nil, nil)
+	c.Assert(f.len(), check.Equals, 2)
+	c.Assert(sub2.puller, check.Not(check.Equals), sub1.puller)
+
+	// wait until the consumed entries are discarded
+	for i := 0; ; i++ {
+		sub1.puller.mu.Lock()
+		sub1.puller.gc()
+		discardedTs := sub1.puller.discardedTs
+		sub1.puller.mu.Unlock()
+		if discardedTs >= 7 {
+			break
+		}
+		c.Assert(i, check.Less, 100)
+		time.Sleep(10 * time.Millisecond)
+	}
+	// the first puller can't serve ts 6 any more, use the second one
+	sub3 := r.Subscribe(ctx, span, 6, true, nil, nil)
+	c.Assert(f.len(), check.Equals, 2)
+	c.Assert(sub3.puller, check.Equals, sub2.puller)
+
+	// the late joiner receives the entries after its start ts
+	sub4 := r.Subscribe(ctx, span, 8, true, nil, nil)
+	c.Assert(f.len(), check.Equals, 2)
+	c.Assert(sub4.puller, check.Equals, sub1.puller)
+	s.runSub(ctx, sub4)
+	p.ch <- &model.RawKVEntry{Ts: 9, OpType: model.OpTypePut}
+	expectTs(c, sub1, 9)
+	expectTs(c, sub4, 9)
+}
+
+func (s *registrySuite) TestBlockOnSlowestSubscriber(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, f := newTestRegistry()
+	r.bufferSize = 3
+	span := util.Span{Start: []byte("a"), End: []byte("b")}
+
+	ctx1, cancel1 := context.WithCancel(ctx)
+	sub1 := r.Subscribe(ctx1, span, 1, true, nil, nil)
+	errCh1 := s.runSub(ctx1, sub1)
+	// the second subscriber doesn't consume any entry
+	ctx2, cancel2 := context.WithCancel(ctx)
+	sub2 := r.Subscribe(ctx2, span, 1, true, nil, nil)
+	p := f.get(0)
+	for ts := uint64(2); ts <= 4; ts++ {
+		p.ch <- &model.RawKVEntry{Ts: ts, OpType: model.OpTypePut, Key: []byte("k")}
+		expectTs(c, sub1, ts)
+	}
+	c.Assert(atomic.LoadInt64(&r.limitter.used), check.Greater, int64(0))
+
+	// the buffer is full, the puller is blocked until the second subscriber consumes the entries
+	p.ch <- &model.RawKVEntry{Ts: 5, OpType: model.OpTypePut, Key: []byte("k")}
+	select {
+	case entry := <-sub1.Output():
+		c.Fatalf("unexpected entry %d", entry.Ts)
+	case <-time.After(100 * time.Millisecond):
+	}
+	errCh2 := s.runSub(ctx2, sub2)
+	expectTs(c, sub2, 2, 3, 4, 5)
+	expectTs(c, sub1, 5)
+
+	// the memory of the buffer is released when the puller exits
+	cancel1()
+	cancel2()
+	c.Assert(<-errCh1, check.NotNil)
+	c.Assert(<-errCh2, check.NotNil)
+	for i := 0; atomic.LoadInt64(&r.limitter.used) != 0; i++ {
+		c.Assert(i, check.Less, 100)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
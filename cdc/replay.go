@@ -0,0 +1,292 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/util"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+const replayResolveTsInterval = 100 * time.Millisecond
+
+// Replay feeds the events in a recording through the sorters, the mounters and the sink
+// the same as the processor which recorded them. The events of the DDL puller are replayed
+// first to build the schema storage, then the events of every recorded table are mounted and
+// emitted to the sink. The resolved ts of the tables are limited by the one of the DDL puller,
+// and Replay returns after the sink flushes the rows resolved by all the recorded pullers.
+func Replay(ctx context.Context, path string, s sink.Sink) error {
+	reader, err := puller.OpenRecording(path)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	meta := reader.Meta()
+	if err := reader.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	log.Info("replay the recording", zap.String("path", path),
+		zap.String("changefeed", meta.ChangefeedID), zap.Uint64("startTs", meta.StartTs))
+
+	errg, cctx := errgroup.WithContext(ctx)
+	errg.Go(func() error {
+		err := s.Run(cctx)
+		if err == nil {
+			// some sinks return at once
+			<-cctx.Done()
+		}
+		return err
+	})
+	errg.Go(func() error {
+		return replay(cctx, errg, path, meta, s)
+	})
+	err = errg.Wait()
+	if errors.Cause(err) == errReplayFinished {
+		return nil
+	}
+	return errors.Trace(err)
+}
+
+var errReplayFinished = errors.New("replay finished")
+
+// replay runs the replay pullers, the mounters and the sink workers in errg,
+// it returns errReplayFinished after the sink flushes all the resolved rows.
+func replay(ctx context.Context, errg *errgroup.Group, path string, meta *puller.RecordMeta, s sink.Sink) error {
+	var builder *entry.StorageBuilder
+	ddlResolvedTs, err := replayStreams(ctx, path, math.MaxUint64, isDDLStream,
+		func(stream *puller.RecordedStream, p *puller.ReplayPuller) error {
+			if builder != nil {
+				return errors.New("more than one DDL puller are recorded")
+			}
+			builder = entry.NewStorageBuilder(meta.SchemaJobs, p.SortedOutput(ctx))
+			errg.Go(func() error {
+				return builder.Run(ctx)
+			})
+			return nil
+		})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if builder == nil {
+		return errors.New("the DDL puller is not recorded")
+	}
+
+	var (
+		tablesMu sync.Mutex
+		tables   []*tableInfo
+	)
+	output := make(chan *model.RowChangedEvent, defaultOutputChanSize)
+	errg.Go(func() error {
+		return replayToSink(ctx, s, output, func() uint64 {
+			tablesMu.Lock()
+			defer tablesMu.Unlock()
+			minResolvedTs := uint64(math.MaxUint64)
+			for _, table := range tables {
+				if ts := table.loadResolvedTS(); ts < minResolvedTs {
+					minResolvedTs = ts
+				}
+			}
+			return minResolvedTs
+		})
+	})
+	resolvedTs, err := replayStreams(ctx, path, ddlResolvedTs,
+		func(stream *puller.RecordedStream) bool { return !isDDLStream(stream) },
+		func(stream *puller.RecordedStream, p *puller.ReplayPuller) error {
+			storage, err := builder.Build(stream.StartTs)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			mounter := entry.NewMounter(p.SortedOutput(ctx), storage, nil, false, nil)
+			table := &tableInfo{mounter: mounter, resolvedTS: stream.StartTs}
+			errg.Go(func() error {
+				return mounter.Run(ctx)
+			})
+			errg.Go(func() error {
+				for {
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case row := <-mounter.Output():
+						if row.Resolved {
+							table.storeResolvedTS(row.Ts)
+							continue
+						}
+						select {
+						case <-ctx.Done():
+							return ctx.Err()
+						case output <- row:
+						}
+					}
+				}
+			})
+			tablesMu.Lock()
+			tables = append(tables, table)
+			tablesMu.Unlock()
+			return nil
+		})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if resolvedTs == math.MaxUint64 {
+		log.Warn("no table is recorded", zap.String("path", path))
+		return errReplayFinished
+	}
+
+	// wait for the sink to flush all the resolved rows
+	ticker := time.NewTicker(replayResolveTsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if checkpointTs := s.CheckpointTs(); checkpointTs >= resolvedTs {
+			log.Info("replay finished", zap.Uint64("checkpointTs", checkpointTs))
+			return errReplayFinished
+		}
+	}
+}
+
+func isDDLStream(stream *puller.RecordedStream) bool {
+	ddlSpan := util.GetDDLSpan()
+	for _, span := range stream.Spans {
+		if bytes.Equal(span.Start, ddlSpan.Start) {
+			return true
+		}
+	}
+	return false
+}
+
+// replayStreams adds the events of the selected streams in the recording to their replay pullers,
+// the resolved ts of the events are limited by maxResolvedTs. onStream is called when a selected
+// stream is declared. It returns the minimum resolved ts of the selected streams.
+func replayStreams(
+	ctx context.Context,
+	path string,
+	maxResolvedTs uint64,
+	selected func(*puller.RecordedStream) bool,
+	onStream func(*puller.RecordedStream, *puller.ReplayPuller) error,
+) (uint64, error) {
+	reader, err := puller.OpenRecording(path)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer reader.Close()
+
+	pullers := make(map[uint64]*puller.ReplayPuller)
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		if record.Stream != nil {
+			if !selected(record.Stream) {
+				continue
+			}
+			p := puller.NewReplayPuller(record.Stream)
+			if err := onStream(record.Stream, p); err != nil {
+				return 0, errors.Trace(err)
+			}
+			pullers[record.StreamID] = p
+			continue
+		}
+		p, ok := pullers[record.StreamID]
+		if !ok {
+			continue
+		}
+		if resolved := record.Event.Resolved; resolved != nil && resolved.ResolvedTs > maxResolvedTs {
+			resolved.ResolvedTs = maxResolvedTs
+		}
+		if err := p.AddEvent(ctx, record.Event); err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+
+	resolvedTs := uint64(math.MaxUint64)
+	for _, p := range pullers {
+		if ts := p.ResolvedTs(); ts < resolvedTs {
+			resolvedTs = ts
+		}
+	}
+	return resolvedTs, nil
+}
+
+// replayToSink emits the rows and the minimum resolved ts of the tables to the sink,
+// the same as the processor does.
+func replayToSink(
+	ctx context.Context,
+	s sink.Sink,
+	output <-chan *model.RowChangedEvent,
+	getResolvedTs func() uint64,
+) error {
+	ticker := time.NewTicker(replayResolveTsInterval)
+	defer ticker.Stop()
+	var lastResolvedTs uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case row := <-output:
+			if err := s.EmitRowChangedEvent(ctx, row); err != nil {
+				return errors.Trace(err)
+			}
+		case <-ticker.C:
+			resolvedTs := getResolvedTs()
+			if resolvedTs == math.MaxUint64 || resolvedTs <= lastResolvedTs {
+				continue
+			}
+			// the rows before the resolved ts are all in output since the table resolved ts
+			// is stored after the rows are sent
+			if err := emitPendingRows(ctx, s, output); err != nil {
+				return errors.Trace(err)
+			}
+			if err := s.EmitRowChangedEvent(ctx, &model.RowChangedEvent{Resolved: true, Ts: resolvedTs}); err != nil {
+				return errors.Trace(err)
+			}
+			if err := s.EmitResolvedEvent(ctx, resolvedTs); err != nil {
+				return errors.Trace(err)
+			}
+			lastResolvedTs = resolvedTs
+		}
+	}
+}
+
+func emitPendingRows(ctx context.Context, s sink.Sink, output <-chan *model.RowChangedEvent) error {
+	for {
+		select {
+		case row := <-output:
+			if err := s.EmitRowChangedEvent(ctx, row); err != nil {
+				return errors.Trace(err)
+			}
+		default:
+			return nil
+		}
+	}
+}
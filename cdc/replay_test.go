@@ -0,0 +1,205 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/types"
+)
+
+type replaySuite struct{}
+
+var _ = check.Suite(&replaySuite{})
+
+// collectSink collects the rows resolved by the global resolved ts
+type collectSink struct {
+	mu           sync.Mutex
+	unresolved   []*model.RowChangedEvent
+	rows         []*model.RowChangedEvent
+	resolvedTs   uint64
+	checkpointTs uint64
+}
+
+func (s *collectSink) EmitResolvedEvent(ctx context.Context, ts uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var unresolved []*model.RowChangedEvent
+	for _, row := range s.unresolved {
+		if row.Ts <= ts {
+			s.rows = append(s.rows, row)
+		} else {
+			unresolved = append(unresolved, row)
+		}
+	}
+	s.unresolved = unresolved
+	s.checkpointTs = ts
+	return nil
+}
+
+func (s *collectSink) EmitCheckpointEvent(ctx context.Context, ts uint64) error {
+	return nil
+}
+
+func (s *collectSink) EmitRowChangedEvent(ctx context.Context, rows ...*model.RowChangedEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range rows {
+		if row.Resolved {
+			s.resolvedTs = row.Ts
+			continue
+		}
+		s.unresolved = append(s.unresolved, row)
+	}
+	return nil
+}
+
+func (s *collectSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	return nil
+}
+
+func (s *collectSink) CheckpointTs() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.checkpointTs
+}
+
+func (s *collectSink) Run(ctx context.Context) error {
+	return nil
+}
+
+func (s *collectSink) PrintStatus(ctx context.Context) error {
+	return nil
+}
+
+func (s *collectSink) Close() error {
+	return nil
+}
+
+func newReplayTableInfo() *timodel.TableInfo {
+	idCol := &timodel.ColumnInfo{ID: 1, Name: timodel.NewCIStr("id"), Offset: 0, State: timodel.StatePublic}
+	idCol.Tp = mysql.TypeLonglong
+	idCol.Flag = mysql.PriKeyFlag | mysql.NotNullFlag
+	vCol := &timodel.ColumnInfo{ID: 2, Name: timodel.NewCIStr("v"), Offset: 1, State: timodel.StatePublic}
+	vCol.Tp = mysql.TypeLonglong
+	return &timodel.TableInfo{
+		ID:         50,
+		Name:       timodel.NewCIStr("t1"),
+		PKIsHandle: true,
+		State:      timodel.StatePublic,
+		Columns:    []*timodel.ColumnInfo{idCol, vCol},
+	}
+}
+
+func rowEvent(c *check.C, tableID, id, v int64, ts uint64, deleted bool) *model.RegionFeedEvent {
+	entry := &model.RawKVEntry{
+		OpType: model.OpTypePut,
+		Key:    tablecodec.EncodeRowKeyWithHandle(tableID, id),
+		Ts:     ts,
+	}
+	if deleted {
+		entry.OpType = model.OpTypeDelete
+	} else {
+		value, err := tablecodec.EncodeOldRow(&stmtctx.StatementContext{},
+			[]types.Datum{types.NewIntDatum(v)}, []int64{2}, nil, nil)
+		c.Assert(err, check.IsNil)
+		entry.Value = value
+	}
+	return &model.RegionFeedEvent{Val: entry}
+}
+
+func resolvedSpanEvent(span util.Span, ts uint64) *model.RegionFeedEvent {
+	return &model.RegionFeedEvent{Resolved: &model.ResolvedSpan{Span: span, ResolvedTs: ts}}
+}
+
+func (s *replaySuite) TestReplay(c *check.C) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	historyJobs := []*timodel.Job{{
+		ID:       1,
+		State:    timodel.JobStateSynced,
+		SchemaID: 1,
+		Type:     timodel.ActionCreateSchema,
+		BinlogInfo: &timodel.HistoryInfo{
+			SchemaVersion: 1,
+			DBInfo:        &timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test"), State: timodel.StatePublic},
+			FinishedTS:    10,
+		},
+		Query: "create database test",
+	}, {
+		ID:         2,
+		State:      timodel.JobStateSynced,
+		SchemaID:   1,
+		TableID:    50,
+		Type:       timodel.ActionCreateTable,
+		BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 2, TableInfo: newReplayTableInfo(), FinishedTS: 20},
+		Query:      "create table test.t1(id bigint primary key, v bigint)",
+	}}
+	jobs, err := entry.NewStorageBuilder(historyJobs, nil).SnapshotJobs(100)
+	c.Assert(err, check.IsNil)
+
+	recorder, err := puller.NewRecorder(c.MkDir(), &puller.RecordMeta{
+		ChangefeedID: "cf",
+		CaptureID:    "capture",
+		StartTs:      100,
+		SchemaJobs:   jobs,
+	})
+	c.Assert(err, check.IsNil)
+	ddlSpans := []util.Span{util.GetDDLSpan(), util.GetAddIndexDDLSpan()}
+	ddlStream := recorder.NewStream(ddlSpans, 100)
+	tableSpan := util.GetTableSpan(50, true)
+	tableStream := recorder.NewStream([]util.Span{tableSpan}, 100)
+
+	tableStream.Record(rowEvent(c, 50, 1, 10, 120, false))
+	tableStream.Record(rowEvent(c, 50, 2, 20, 110, false))
+	ddlStream.Record(resolvedSpanEvent(ddlSpans[0], 200))
+	tableStream.Record(resolvedSpanEvent(tableSpan, 130))
+	tableStream.Record(rowEvent(c, 50, 1, 0, 150, true))
+	ddlStream.Record(resolvedSpanEvent(ddlSpans[1], 200))
+	// the row after the resolved ts of the DDL puller is not replayed
+	tableStream.Record(rowEvent(c, 50, 3, 30, 210, false))
+	tableStream.Record(resolvedSpanEvent(tableSpan, 250))
+	c.Assert(recorder.Close(), check.IsNil)
+
+	sink := &collectSink{}
+	c.Assert(Replay(ctx, recorder.Path(), sink), check.IsNil)
+	c.Assert(sink.CheckpointTs(), check.Equals, uint64(200))
+	c.Assert(sink.rows, check.HasLen, 3)
+	expected := []struct {
+		ts      uint64
+		id      int64
+		deleted bool
+	}{{110, 2, false}, {120, 1, false}, {150, 1, true}}
+	for i, e := range expected {
+		row := sink.rows[i]
+		c.Assert(row.Ts, check.Equals, e.ts)
+		c.Assert(row.Schema, check.Equals, "test")
+		c.Assert(row.Table, check.Equals, "t1")
+		c.Assert(row.Delete, check.Equals, e.deleted)
+		c.Assert(row.Columns["id"].Value, check.Equals, e.id)
+	}
+	c.Assert(sink.rows[0].Columns["v"].Value, check.Equals, int64(20))
+}
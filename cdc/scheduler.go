@@ -20,7 +20,10 @@ import (
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	"go.uber.org/zap"
 )
@@ -29,6 +32,10 @@ import (
 func runProcessor(
 	ctx context.Context,
 	pdEndpoints []string,
+	credential *security.Credential,
+	pullerRegistry *puller.Registry,
+	mounterPool *entry.MounterWorkerPool,
+	sharedSchemas *sharedSchemaStore,
 	info model.ChangeFeedInfo,
 	changefeedID string,
 	captureID string,
@@ -55,7 +62,7 @@ func runProcessor(
 			errCh <- err
 		}
 	}()
-	processor, err := NewProcessor(ctx, pdEndpoints, info, sink, changefeedID, captureID, checkpointTs)
+	processor, err := NewProcessor(ctx, pdEndpoints, credential, pullerRegistry, mounterPool, sharedSchemas, info, sink, changefeedID, captureID, checkpointTs)
 	if err != nil {
 		cancel()
 		return nil, err
@@ -15,11 +15,15 @@ package cdc
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	"go.uber.org/zap"
 )
@@ -28,6 +32,11 @@ type options struct {
 	pdEndpoints string
 	statusHost  string
 	statusPort  int
+	labels      map[string]string
+	kvConfig    *kv.ClientConfig
+	credential  *security.Credential
+
+	mounterWorkerNum int
 }
 
 var defaultServerOptions = options{
@@ -57,6 +66,34 @@ func StatusPort(p int) ServerOption {
 	}
 }
 
+// CaptureLabels returns a ServerOption that sets the labels of the capture
+func CaptureLabels(labels map[string]string) ServerOption {
+	return func(o *options) {
+		o.labels = labels
+	}
+}
+
+// KVClientConfig returns a ServerOption that sets the config of the kv clients
+func KVClientConfig(cfg *kv.ClientConfig) ServerOption {
+	return func(o *options) {
+		o.kvConfig = cfg
+	}
+}
+
+// MounterWorkerNum returns a ServerOption that sets the number of the workers decoding the rows
+func MounterWorkerNum(n int) ServerOption {
+	return func(o *options) {
+		o.mounterWorkerNum = n
+	}
+}
+
+// Credential returns a ServerOption that sets the TLS credential of the server
+func Credential(credential *security.Credential) ServerOption {
+	return func(o *options) {
+		o.credential = credential
+	}
+}
+
 // A ServerOption sets options such as the addr of PD.
 type ServerOption func(*options)
 
@@ -76,9 +113,14 @@ func NewServer(opt ...ServerOption) (*Server, error) {
 	log.Info("creating CDC server",
 		zap.String("pd-addr", opts.pdEndpoints),
 		zap.String("status-host", opts.statusHost),
-		zap.Int("status-port", opts.statusPort))
-
-	capture, err := NewCapture(strings.Split(opts.pdEndpoints, ","))
+		zap.Int("status-port", opts.statusPort),
+		zap.Reflect("labels", opts.labels),
+		zap.Reflect("kv-client-config", opts.kvConfig),
+		zap.Int("mounter-worker-num", opts.mounterWorkerNum),
+		zap.Bool("tls-enabled", opts.credential.IsTLSEnabled()))
+
+	statusAddr := fmt.Sprintf("%s:%d", opts.statusHost, opts.statusPort)
+	capture, err := NewCapture(strings.Split(opts.pdEndpoints, ","), opts.credential, opts.labels, opts.kvConfig, opts.mounterWorkerNum, statusAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -92,7 +134,9 @@ func NewServer(opt ...ServerOption) (*Server, error) {
 
 // Run runs the server.
 func (s *Server) Run(ctx context.Context) error {
-	s.startStatusHTTP()
+	if err := s.startStatusHTTP(); err != nil {
+		return errors.Trace(err)
+	}
 	ctx = util.PutCaptureIDInCtx(ctx, s.capture.info.ID)
 	return s.capture.Start(ctx)
 }
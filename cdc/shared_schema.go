@@ -0,0 +1,133 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	pd "github.com/pingcap/pd/client"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/pkg/security"
+	"github.com/pingcap/ticdc/pkg/util"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// sharedSchemaStore shares a schema store among the processors on a capture. The store is created
+// when it's first used, it's built from the latest schema snapshot of the first processor if there is
+// one, otherwise the whole DDL history is loaded. The newer DDL jobs are received from one DDL puller,
+// which subscribes the DDL spans through the kv client of the capture.
+type sharedSchemaStore struct {
+	pdCli       pd.Client
+	pdEndpoints []string
+	credential  *security.Credential
+	captureID   string
+	kvClient    *kv.CDCClient
+
+	mu     sync.Mutex
+	store  *entry.SchemaStore
+	cancel context.CancelFunc
+}
+
+func newSharedSchemaStore(
+	pdCli pd.Client, pdEndpoints []string, credential *security.Credential, captureID string, kvClient *kv.CDCClient,
+) *sharedSchemaStore {
+	return &sharedSchemaStore{
+		pdCli:       pdCli,
+		pdEndpoints: pdEndpoints,
+		credential:  credential,
+		captureID:   captureID,
+		kvClient:    kvClient,
+	}
+}
+
+// get returns the shared schema store, a new one is created if the store isn't created or it's closed.
+// The new store is built from the latest snapshot in schemaSnapshots whose ts is not greater than ts.
+func (s *sharedSchemaStore) get(ctx context.Context, schemaSnapshots entry.SchemaSnapshotStorage, ts uint64) (*entry.SchemaStore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store != nil {
+		return s.store, nil
+	}
+
+	// the tikv stores are cached by the driver and shared in the process, so it's not closed here
+	kvStore, err := kv.CreateTiStore(strings.Join(s.pdEndpoints, ","), s.credential)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// the history jobs include all the jobs finished before the current version
+	version, err := kvStore.CurrentVersion()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	pullerCtx, cancel := context.WithCancel(util.PutCaptureIDInCtx(context.Background(), s.captureID))
+	ddlPuller := puller.NewPuller(s.pdCli, s.credential, version.Ver,
+		[]util.Span{util.GetDDLSpan(), util.GetAddIndexDDLSpan()}, false, puller.NewBlurResourceLimmter(defaultMemBufferCapacity))
+	ddlPuller.SetClient(s.kvClient)
+	store, err := loadSchemaStore(ctx, kvStore, ddlPuller.SortedOutput(pullerCtx), schemaSnapshots, ts, version.Ver)
+	if err != nil {
+		cancel()
+		return nil, errors.Trace(err)
+	}
+	log.Info("create shared schema store", zap.Uint64("startTs", version.Ver))
+
+	go func() {
+		errg, ectx := errgroup.WithContext(pullerCtx)
+		errg.Go(func() error {
+			return ddlPuller.Run(ectx)
+		})
+		errg.Go(func() error {
+			return store.Run(ectx)
+		})
+		err := errg.Wait()
+		if errors.Cause(err) != context.Canceled {
+			log.Error("shared schema store exited", zap.Error(err))
+		}
+		if err == nil {
+			err = context.Canceled
+		}
+		store.Close(err)
+		s.mu.Lock()
+		if s.store == store {
+			s.store = nil
+			s.cancel = nil
+		}
+		s.mu.Unlock()
+		cancel()
+	}()
+	s.store = store
+	s.cancel = cancel
+	return store, nil
+}
+
+// close stops the DDL puller of the shared schema store
+func (s *sharedSchemaStore) close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.store = nil
+	s.cancel = nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/format"
+	// the parser driver is required to parse the values in the DDL
+	_ "github.com/pingcap/tidb/types/parser_driver"
+	"go.uber.org/zap"
+)
+
+// serverType is the type of the downstream server of the MySQL sink
+type serverType int
+
+const (
+	serverTypeUnknown serverType = iota
+	serverTypeTiDB
+	serverTypeMySQL
+)
+
+func (t serverType) String() string {
+	switch t {
+	case serverTypeTiDB:
+		return "TiDB"
+	case serverTypeMySQL:
+		return "MySQL"
+	default:
+		return "unknown"
+	}
+}
+
+// detectServerType detects the type of the downstream server by its version,
+// the version of TiDB looks like "5.7.25-TiDB-v4.0.0".
+func detectServerType(ctx context.Context, db *sql.DB) (serverType, error) {
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		return serverTypeUnknown, errors.Trace(err)
+	}
+	if strings.Contains(strings.ToLower(version), "tidb") {
+		return serverTypeTiDB, nil
+	}
+	return serverTypeMySQL, nil
+}
+
+// rewriteDDLForMySQL removes the TiDB specific clauses, which are rejected by MySQL, from the DDL query.
+// The query is returned unchanged if there is no such clause. An empty query is returned if nothing
+// is left after the clauses are removed, e.g. `ALTER TABLE t SET TIFLASH REPLICA 1`, which should be skipped.
+//
+// The query is returned unchanged with a warning if it can't be parsed, or it has several statements,
+// which happens when the DDLs are executed in one query upstream, so the downstream decides whether to
+// accept it. The CLUSTERED and NONCLUSTERED clauses of the primary keys are not removed, because they
+// are not supported by the parser, the queries with them fail to be parsed and are returned unchanged.
+func rewriteDDLForMySQL(query string) (string, error) {
+	stmts, _, err := parser.New().Parse(query, "", "")
+	if err != nil {
+		log.Warn("failed to parse the DDL, execute it without rewriting", zap.String("query", query), zap.Error(err))
+		return query, nil
+	}
+	if len(stmts) != 1 {
+		log.Warn("the DDL has several statements, execute it without rewriting",
+			zap.String("query", query), zap.Int("count", len(stmts)))
+		return query, nil
+	}
+	stmt := stmts[0]
+	changed := false
+	switch stmt := stmt.(type) {
+	case *ast.CreateTableStmt:
+		stmt.Options, changed = removeTiDBTableOptions(stmt.Options)
+		for _, col := range stmt.Cols {
+			changed = removeTiDBColumnOptions(col) || changed
+		}
+	case *ast.AlterTableStmt:
+		specs := stmt.Specs[:0]
+		for _, spec := range stmt.Specs {
+			switch spec.Tp {
+			case ast.AlterTableSetTiFlashReplica:
+				changed = true
+				continue
+			case ast.AlterTableOption:
+				var removed bool
+				spec.Options, removed = removeTiDBTableOptions(spec.Options)
+				changed = changed || removed
+				if len(spec.Options) == 0 {
+					continue
+				}
+			}
+			for _, col := range spec.NewColumns {
+				changed = removeTiDBColumnOptions(col) || changed
+			}
+			specs = append(specs, spec)
+		}
+		stmt.Specs = specs
+		if len(specs) == 0 {
+			return "", nil
+		}
+	}
+	if !changed {
+		return query, nil
+	}
+
+	var sb strings.Builder
+	if err := stmt.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)); err != nil {
+		return "", errors.Annotatef(err, "restore DDL %s", query)
+	}
+	return sb.String(), nil
+}
+
+// removeTiDBTableOptions removes SHARD_ROW_ID_BITS and PRE_SPLIT_REGIONS
+func removeTiDBTableOptions(options []*ast.TableOption) ([]*ast.TableOption, bool) {
+	kept := options[:0]
+	for _, opt := range options {
+		switch opt.Tp {
+		case ast.TableOptionShardRowID, ast.TableOptionPreSplitRegion:
+		default:
+			kept = append(kept, opt)
+		}
+	}
+	return kept, len(kept) != len(options)
+}
+
+// removeTiDBColumnOptions removes AUTO_RANDOM, the values of the column are replicated as they're,
+// so the column is kept as a plain primary key downstream.
+func removeTiDBColumnOptions(col *ast.ColumnDef) bool {
+	kept := col.Options[:0]
+	for _, opt := range col.Options {
+		if opt.Tp != ast.ColumnOptionAutoRandom {
+			kept = append(kept, opt)
+		}
+	}
+	changed := len(kept) != len(col.Options)
+	col.Options = kept
+	return changed
+}
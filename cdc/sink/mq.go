@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"hash/crc32"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -30,6 +31,8 @@ type mqSink struct {
 	filter             *util.Filter
 
 	changefeedID string
+	// withSchemaVersion adds the version of the table schema to the keys of the row messages
+	withSchemaVersion bool
 
 	count int64
 }
@@ -76,6 +79,9 @@ func (k *mqSink) EmitRowChangedEvent(ctx context.Context, rows ...*model.RowChan
 		}
 		partition := k.calPartition(row)
 		key, value := row.ToMqMessage()
+		if !k.withSchemaVersion {
+			key.SchemaVersion = 0
+		}
 		keyByte, err := key.Encode()
 		if err != nil {
 			return errors.Trace(err)
@@ -125,10 +131,40 @@ func (k *mqSink) calPartition(row *model.RowChangedEvent) int32 {
 		if err != nil {
 			log.Fatal("calculate hash of message key failed, please report a bug", zap.Error(err))
 		}
+	} else if isKeylessRow(row) {
+		// distribute partition by all the column values of the row without a primary key or unique index
+		colNames := make([]string, 0, len(row.Columns))
+		for colName := range row.Columns {
+			colNames = append(colNames, colName)
+		}
+		sort.Strings(colNames)
+		for _, colName := range colNames {
+			b, err := json.Marshal(row.Columns[colName].Value)
+			if err != nil {
+				log.Fatal("calculate hash of message key failed, please report a bug", zap.Error(err))
+			}
+			_, err = hash.Write(b)
+			if err != nil {
+				log.Fatal("calculate hash of message key failed, please report a bug", zap.Error(err))
+			}
+		}
 	}
 	return int32(hash.Sum32() % uint32(k.partitionNum))
 }
 
+// isKeylessRow returns true if the row is identified by all the columns
+func isKeylessRow(row *model.RowChangedEvent) bool {
+	if len(row.Columns) == 0 {
+		return false
+	}
+	for _, col := range row.Columns {
+		if !col.WhereHandle {
+			return false
+		}
+	}
+	return true
+}
+
 func (k *mqSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
 	if k.filter.ShouldIgnoreEvent(ddl.Ts, ddl.Schema, ddl.Table) {
 		log.Info(
@@ -181,6 +217,11 @@ func (k *mqSink) Run(ctx context.Context) error {
 	}
 }
 
+// Close implements the Sink interface, the producer is closed when Run exits
+func (k *mqSink) Close() error {
+	return nil
+}
+
 func (k *mqSink) PrintStatus(ctx context.Context) error {
 	lastTime := time.Now()
 	var lastCount int64
@@ -248,6 +289,23 @@ func newKafkaSaramaSink(sinkURI *url.URL, filter *util.Filter, opts map[string]s
 		config.MaxMessageBytes = c
 	}
 
+	withSchemaVersion := false
+	if s, ok := opts[OptSchemaVersion]; ok {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		withSchemaVersion = b
+	}
+	s = sinkURI.Query().Get(OptSchemaVersion)
+	if s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		withSchemaVersion = b
+	}
+
 	topic := strings.TrimFunc(sinkURI.Path, func(r rune) bool {
 		return r == '/'
 	})
@@ -255,5 +313,7 @@ func newKafkaSaramaSink(sinkURI *url.URL, filter *util.Filter, opts map[string]s
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	return newMqSink(producer, filter, opts), nil
+	sink := newMqSink(producer, filter, opts)
+	sink.withSchemaVersion = withSchemaVersion
+	return sink, nil
 }
@@ -55,6 +55,12 @@ type mysqlSink struct {
 	params           params
 
 	filter *util.Filter
+	// serverType is detected before the first DDL is executed
+	serverType serverType
+	// asyncDDLs are the DDLs executed asynchronously, keyed by the quoted table name,
+	// the failed ones are kept until they're reported by AsyncDDLStatus.
+	asyncDDLsMu sync.Mutex
+	asyncDDLs   map[string]*asyncDDL
 
 	globalForwardCh chan struct{}
 
@@ -106,10 +112,180 @@ func (s *mysqlSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error
 		)
 		return nil
 	}
-	err := s.execDDLWithMaxRetries(ctx, ddl, 5)
+	if err := s.waitAsyncDDLs(ddl); err != nil {
+		return errors.Trace(err)
+	}
+	ddl, err := s.rewriteDDL(ctx, ddl)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(ddl.Query) == 0 {
+		log.Info("DDL event skipped, it's not supported by the downstream", zap.Uint64("ts", ddl.Ts))
+		return nil
+	}
+	if isAsyncDDL(ddl) {
+		s.execDDLAsync(ctx, ddl)
+		return nil
+	}
+	err = s.execDDLWithMaxRetries(ctx, ddl, 5)
 	return errors.Trace(err)
 }
 
+// getServerType returns the type of the downstream, which is detected at the first call
+func (s *mysqlSink) getServerType(ctx context.Context) (serverType, error) {
+	if s.serverType != serverTypeUnknown {
+		return s.serverType, nil
+	}
+	tp, err := detectServerType(ctx, s.db)
+	if err != nil {
+		return serverTypeUnknown, errors.Annotate(err, "detect the type of the downstream")
+	}
+	log.Info("detect the type of the downstream", zap.Stringer("type", tp))
+	s.serverType = tp
+	return tp, nil
+}
+
+// SyncSequence implements the SequenceSyncer interface, the value is only synchronized to TiDB
+// since MySQL doesn't support sequences.
+func (s *mysqlSink) SyncSequence(ctx context.Context, schema, name string, value int64) error {
+	tp, err := s.getServerType(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if tp != serverTypeTiDB {
+		return nil
+	}
+	_, err = s.db.ExecContext(ctx, fmt.Sprintf("SELECT SETVAL(%s, %d)", util.QuoteSchema(schema, name), value))
+	return errors.Trace(err)
+}
+
+// asyncDDL is a DDL executed in the background
+type asyncDDL struct {
+	ddl    *model.DDLEvent
+	done   chan struct{}
+	err    error
+	cancel context.CancelFunc
+}
+
+// isAsyncDDL returns whether the DDL can be executed while the rows are replicated,
+// these DDLs don't change the format of the rows but may take a long time downstream.
+func isAsyncDDL(ddl *model.DDLEvent) bool {
+	if len(ddl.Table) == 0 {
+		return false
+	}
+	switch ddl.Type {
+	case timodel.ActionAddIndex, timodel.ActionDropIndex, timodel.ActionRenameIndex,
+		timodel.ActionModifyTableComment:
+		return true
+	}
+	return false
+}
+
+// execDDLAsync executes the DDL in the background, the owner holds the checkpoint ts below the DDL
+// until it finishes, and its error is reported by AsyncDDLStatus.
+func (s *mysqlSink) execDDLAsync(ctx context.Context, ddl *model.DDLEvent) {
+	ctx, cancel := context.WithCancel(ctx)
+	pending := &asyncDDL{ddl: ddl, done: make(chan struct{}), cancel: cancel}
+	s.asyncDDLsMu.Lock()
+	s.asyncDDLs[asyncDDLKey(ddl.Schema, ddl.Table)] = pending
+	s.asyncDDLsMu.Unlock()
+	log.Info("execute DDL asynchronously", zap.String("query", ddl.Query), zap.Uint64("ts", ddl.Ts))
+	go func() {
+		defer close(pending.done)
+		defer cancel()
+		pending.err = s.execDDLWithMaxRetries(ctx, ddl, 5)
+		if pending.err != nil {
+			log.Error("execute DDL asynchronously failed", zap.String("query", ddl.Query),
+				zap.Uint64("ts", ddl.Ts), zap.Error(pending.err))
+		}
+	}()
+}
+
+// waitAsyncDDLs waits for the asynchronous DDLs of the table changed by the DDL, the DDLs of a schema
+// wait for the asynchronous DDLs of the tables in the schema and renaming tables waits for all of them.
+// The DDL isn't executed if any asynchronous DDL failed, the failed one is reported by AsyncDDLStatus.
+func (s *mysqlSink) waitAsyncDDLs(ddl *model.DDLEvent) error {
+	s.asyncDDLsMu.Lock()
+	var blocking []*asyncDDL
+	for _, pending := range s.asyncDDLs {
+		if ddl.Type == timodel.ActionRenameTable ||
+			(pending.ddl.Schema == ddl.Schema && (len(ddl.Table) == 0 || pending.ddl.Table == ddl.Table)) {
+			blocking = append(blocking, pending)
+		}
+	}
+	s.asyncDDLsMu.Unlock()
+	for _, pending := range blocking {
+		log.Info("wait for the asynchronous DDL", zap.String("query", pending.ddl.Query),
+			zap.String("blocked", ddl.Query))
+		<-pending.done
+	}
+	_, failed, err := s.AsyncDDLStatus()
+	if err != nil {
+		return errors.Annotatef(err, "the asynchronous DDL failed, ts: %d, query: %s", failed.Ts, failed.Query)
+	}
+	return nil
+}
+
+// AsyncDDLStatus implements the AsyncDDLSink interface
+func (s *mysqlSink) AsyncDDLStatus() (pendingTs uint64, failed *model.DDLEvent, err error) {
+	s.asyncDDLsMu.Lock()
+	defer s.asyncDDLsMu.Unlock()
+	for key, pending := range s.asyncDDLs {
+		select {
+		case <-pending.done:
+		default:
+			if pendingTs == 0 || pending.ddl.Ts < pendingTs {
+				pendingTs = pending.ddl.Ts
+			}
+			continue
+		}
+		if pending.err == nil {
+			delete(s.asyncDDLs, key)
+			continue
+		}
+		if failed == nil || pending.ddl.Ts < failed.Ts {
+			failed, err = pending.ddl, pending.err
+		}
+	}
+	return pendingTs, failed, err
+}
+
+func asyncDDLKey(schema, table string) string {
+	return util.QuoteSchema(schema, table)
+}
+
+// rewriteDDL removes the TiDB specific clauses from the query of the DDL if the downstream isn't TiDB
+func (s *mysqlSink) rewriteDDL(ctx context.Context, ddl *model.DDLEvent) (*model.DDLEvent, error) {
+	if s.params.ddlPassthrough {
+		return ddl, nil
+	}
+	tp, err := s.getServerType(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if tp == serverTypeTiDB {
+		return ddl, nil
+	}
+	switch ddl.Type {
+	case timodel.ActionCreateSequence, timodel.ActionAlterSequence, timodel.ActionDropSequence:
+		// MySQL doesn't support sequences
+		rewritten := *ddl
+		rewritten.Query = ""
+		return &rewritten, nil
+	}
+	query, err := rewriteDDLForMySQL(ddl.Query)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if query != ddl.Query {
+		log.Info("rewrite DDL for the downstream", zap.String("query", ddl.Query), zap.String("rewritten", query))
+		rewritten := *ddl
+		rewritten.Query = query
+		ddl = &rewritten
+	}
+	return ddl, nil
+}
+
 func (s *mysqlSink) execDDLWithMaxRetries(ctx context.Context, ddl *model.DDLEvent, maxRetries uint64) error {
 	return retry.Run(func() error {
 		err := s.execDDL(ctx, ddl)
@@ -232,6 +408,8 @@ type params struct {
 	maxTxnRow    int
 	changefeedID string
 	captureID    string
+	// ddlPassthrough executes the DDL unchanged even if the downstream isn't TiDB
+	ddlPassthrough bool
 }
 
 var defaultParams = params{
@@ -260,6 +438,13 @@ func newMySQLSink(sinkURI *url.URL, dsn *dmysql.Config, filter *util.Filter, opt
 	if cid, ok := opts[OptCaptureID]; ok {
 		params.captureID = cid
 	}
+	if s, ok := opts[OptDDLPassthrough]; ok {
+		passthrough, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		params.ddlPassthrough = passthrough
+	}
 
 	switch {
 	case sinkURI != nil:
@@ -283,6 +468,14 @@ func newMySQLSink(sinkURI *url.URL, dsn *dmysql.Config, filter *util.Filter, opt
 			}
 			params.maxTxnRow = c
 		}
+		s = sinkURI.Query().Get(OptDDLPassthrough)
+		if s != "" {
+			passthrough, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			params.ddlPassthrough = passthrough
+		}
 		// dsn format of the driver:
 		// [username[:password]@][protocol[(address)]]/dbname[?param1=value1&...&paramN=valueN]
 		username := sinkURI.User.Username()
@@ -319,6 +512,7 @@ func newMySQLSink(sinkURI *url.URL, dsn *dmysql.Config, filter *util.Filter, opt
 		unresolvedRows:  make(map[string][]*model.RowChangedEvent),
 		params:          params,
 		filter:          filter,
+		asyncDDLs:       make(map[string]*asyncDDL),
 		globalForwardCh: make(chan struct{}, 1),
 	}
 
@@ -386,7 +580,19 @@ func rowLimitIterator(rows []*model.RowChangedEvent, maxTxnRow int, fn func([]*m
 	return nil
 }
 
+// Close cancels the asynchronous DDLs and waits for them, the canceled DDLs are executed again
+// when the changefeed restarts since the checkpoint ts is held below them.
 func (s *mysqlSink) Close() error {
+	s.asyncDDLsMu.Lock()
+	pendings := make([]*asyncDDL, 0, len(s.asyncDDLs))
+	for _, pending := range s.asyncDDLs {
+		pendings = append(pendings, pending)
+	}
+	s.asyncDDLsMu.Unlock()
+	for _, pending := range pendings {
+		pending.cancel()
+		<-pending.done
+	}
 	return nil
 }
 
@@ -14,9 +14,13 @@
 package sink
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
 	"github.com/pingcap/ticdc/cdc/model"
 )
 
@@ -447,3 +451,132 @@ func (s EmitSuite) TestTxnRowLimiter(c *check.C) {
    }
 
 */
+
+func (s EmitSuite) TestRewriteDDLForMySQL(c *check.C) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{{
+		query:    "create table t (id int primary key, name varchar(10))",
+		expected: "create table t (id int primary key, name varchar(10))",
+	}, {
+		query:    "CREATE TABLE t (id bigint PRIMARY KEY AUTO_RANDOM(3), name varchar(10)) SHARD_ROW_ID_BITS=4 PRE_SPLIT_REGIONS=2 CHARSET=utf8mb4",
+		expected: "CREATE TABLE `t` (`id` BIGINT PRIMARY KEY,`name` VARCHAR(10)) DEFAULT CHARACTER SET = UTF8MB4",
+	}, {
+		query:    "CREATE TABLE t (id int) /*T! SHARD_ROW_ID_BITS=4 */",
+		expected: "CREATE TABLE `t` (`id` INT)",
+	}, {
+		query:    "ALTER TABLE t SHARD_ROW_ID_BITS=4",
+		expected: "",
+	}, {
+		query:    "ALTER TABLE t SET TIFLASH REPLICA 1",
+		expected: "",
+	}, {
+		query:    "ALTER TABLE t ADD COLUMN c int, SHARD_ROW_ID_BITS=4, COMMENT='x'",
+		expected: "ALTER TABLE `t` ADD COLUMN `c` INT, COMMENT = 'x'",
+	}, {
+		query:    "alter table t add index idx(c)",
+		expected: "alter table t add index idx(c)",
+	}, {
+		// the queries with several statements are not rewritten
+		query:    "create table t1 (id int) shard_row_id_bits=4; create table t2 (id int)",
+		expected: "create table t1 (id int) shard_row_id_bits=4; create table t2 (id int)",
+	}, {
+		// the clustered index isn't supported by the parser, the query is not rewritten
+		query:    "create table t (id int, primary key (id) clustered)",
+		expected: "create table t (id int, primary key (id) clustered)",
+	}}
+	for _, tc := range testCases {
+		query, err := rewriteDDLForMySQL(tc.query)
+		c.Assert(err, check.IsNil)
+		c.Assert(query, check.Equals, tc.expected, check.Commentf("query: %s", tc.query))
+	}
+}
+
+func (s EmitSuite) TestWaitAsyncDDLs(c *check.C) {
+	addIndex := &model.DDLEvent{Schema: "test", Table: "t1", Type: timodel.ActionAddIndex, Query: "alter table t1 add index idx(a)"}
+	c.Assert(isAsyncDDL(addIndex), check.IsTrue)
+	c.Assert(isAsyncDDL(&model.DDLEvent{Schema: "test", Table: "t1", Type: timodel.ActionAddColumn}), check.IsFalse)
+
+	sink := &mysqlSink{asyncDDLs: make(map[string]*asyncDDL)}
+	pending := &asyncDDL{ddl: addIndex, done: make(chan struct{})}
+	sink.asyncDDLs[asyncDDLKey("test", "t1")] = pending
+
+	// the DDLs of the other tables are not blocked
+	c.Assert(sink.waitAsyncDDLs(&model.DDLEvent{Schema: "test", Table: "t2", Type: timodel.ActionAddColumn}), check.IsNil)
+	c.Assert(sink.waitAsyncDDLs(&model.DDLEvent{Schema: "test2", Type: timodel.ActionCreateSchema}), check.IsNil)
+	c.Assert(sink.asyncDDLs, check.HasLen, 1)
+
+	// the DDLs of the same table are blocked until the asynchronous DDL finishes
+	for _, ddl := range []*model.DDLEvent{
+		{Schema: "test", Table: "t1", Type: timodel.ActionAddColumn},
+		{Schema: "test", Type: timodel.ActionDropSchema},
+		{Schema: "test2", Table: "t3", Type: timodel.ActionRenameTable},
+	} {
+		pending := &asyncDDL{ddl: addIndex, done: make(chan struct{})}
+		sink.asyncDDLs[asyncDDLKey("test", "t1")] = pending
+		done := make(chan error, 1)
+		go func() {
+			done <- sink.waitAsyncDDLs(ddl)
+		}()
+		select {
+		case <-done:
+			c.Fatalf("%v is not blocked", ddl)
+		case <-time.After(50 * time.Millisecond):
+		}
+		close(pending.done)
+		c.Assert(<-done, check.IsNil)
+		c.Assert(sink.asyncDDLs, check.HasLen, 0)
+	}
+
+	// the next DDL isn't executed if the asynchronous DDL failed, and the failure is kept for AsyncDDLStatus
+	pending = &asyncDDL{ddl: addIndex, done: make(chan struct{}), err: errors.New("duplicate entry")}
+	close(pending.done)
+	sink.asyncDDLs[asyncDDLKey("test", "t1")] = pending
+	err := sink.waitAsyncDDLs(&model.DDLEvent{Schema: "test2", Table: "t2", Type: timodel.ActionAddColumn})
+	c.Assert(err, check.ErrorMatches, ".*alter table t1 add index idx.*duplicate entry.*")
+	c.Assert(sink.asyncDDLs, check.HasLen, 1)
+}
+
+func (s EmitSuite) TestAsyncDDLStatus(c *check.C) {
+	sink := &mysqlSink{asyncDDLs: make(map[string]*asyncDDL)}
+	newAsyncDDL := func(table string, ts uint64, finished bool, err error) {
+		pending := &asyncDDL{ddl: &model.DDLEvent{Schema: "test", Table: table, Ts: ts}, done: make(chan struct{}), err: err, cancel: func() {}}
+		if finished {
+			close(pending.done)
+		}
+		sink.asyncDDLs[asyncDDLKey("test", table)] = pending
+	}
+	newAsyncDDL("t1", 10, false, nil)
+	newAsyncDDL("t2", 5, true, nil)
+	newAsyncDDL("t3", 8, false, nil)
+	pendingTs, failed, err := sink.AsyncDDLStatus()
+	c.Assert(err, check.IsNil)
+	c.Assert(failed, check.IsNil)
+	c.Assert(pendingTs, check.Equals, uint64(8))
+	// the succeeded DDLs are removed
+	c.Assert(sink.asyncDDLs, check.HasLen, 2)
+
+	// the failed DDL with the min ts is reported
+	newAsyncDDL("t4", 12, true, errors.New("error 1"))
+	newAsyncDDL("t5", 11, true, errors.New("error 2"))
+	pendingTs, failed, err = sink.AsyncDDLStatus()
+	c.Assert(err, check.ErrorMatches, "error 2")
+	c.Assert(failed.Ts, check.Equals, uint64(11))
+	c.Assert(pendingTs, check.Equals, uint64(8))
+	c.Assert(sink.asyncDDLs, check.HasLen, 4)
+
+	// Close cancels the pending DDLs
+	for _, table := range []string{"t1", "t3"} {
+		pending := sink.asyncDDLs[asyncDDLKey("test", table)]
+		ctx, cancel := context.WithCancel(context.Background())
+		pending.cancel = cancel
+		go func() {
+			<-ctx.Done()
+			close(pending.done)
+		}()
+	}
+	c.Assert(sink.Close(), check.IsNil)
+	pendingTs, _, _ = sink.AsyncDDLStatus()
+	c.Assert(pendingTs, check.Equals, uint64(0))
+}
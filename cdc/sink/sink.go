@@ -30,6 +30,12 @@ import (
 const (
 	OptChangefeedID = "_changefeed_id"
 	OptCaptureID    = "_capture_id"
+	// OptDDLPassthrough executes the DDL unchanged in the MySQL sink, it can be set in the
+	// options of the changefeed or the query of the sink URI.
+	OptDDLPassthrough = "ddl-passthrough"
+	// OptSchemaVersion adds the version of the table schema to the keys of the row messages in the MQ sink,
+	// it can be set in the options of the changefeed or the query of the sink URI.
+	OptSchemaVersion = "schema-version"
 )
 
 // Sink is an abstraction for anything that a changefeed may emit into.
@@ -48,6 +54,21 @@ type Sink interface {
 	Run(ctx context.Context) error
 	// PrintStatus prints necessary status periodically
 	PrintStatus(ctx context.Context) error
+	// Close closes the sink, the DDLs being executed are canceled
+	Close() error
+}
+
+// AsyncDDLSink is implemented by the sinks which execute some DDLs asynchronously
+type AsyncDDLSink interface {
+	// AsyncDDLStatus returns the min ts of the asynchronous DDLs which are not finished, it's 0 if all of
+	// them are finished. The failed DDL with the min ts and its error are returned if any DDL failed.
+	AsyncDDLStatus() (pendingTs uint64, failed *model.DDLEvent, err error)
+}
+
+// SequenceSyncer is implemented by the sinks which synchronize the values of the sequences
+type SequenceSyncer interface {
+	// SyncSequence sets the value of the sequence downstream
+	SyncSequence(ctx context.Context, schema, name string, value int64) error
 }
 
 // DSNScheme is the scheme name of DSN
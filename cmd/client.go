@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -22,8 +25,9 @@ import (
 	pd "github.com/pingcap/pd/client"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/cdc/roles"
 	"github.com/pingcap/ticdc/pkg/util"
-	"github.com/pingcap/tidb/store/tikv"
 	"github.com/pingcap/tidb/store/tikv/oracle"
 	"github.com/spf13/cobra"
 	"go.etcd.io/etcd/clientv3"
@@ -35,9 +39,20 @@ func init() {
 	cliCmd := newCliCommand()
 	cliCmd.PersistentFlags().StringVar(&cliPdAddr, "pd", "http://127.0.0.1:2379", "PD address")
 	cliCmd.PersistentFlags().BoolVarP(&interact, "interact", "i", false, "Run cdc cli with readline")
+	cliCmd.PersistentFlags().StringVar(&caPath, "ca", "", "CA certificate path for TLS connection")
+	cliCmd.PersistentFlags().StringVar(&certPath, "cert", "", "Certificate path for TLS connection")
+	cliCmd.PersistentFlags().StringVar(&keyPath, "key", "", "Private key path for TLS connection")
 	rootCmd.AddCommand(cliCmd)
 }
 
+// changefeedCreatingGCTTL is the TTL of the service safepoint registered when
+// creating a changefeed, in seconds. It must be long enough for the owner to
+// take over the new changefeed.
+const changefeedCreatingGCTTL = 10 * 60
+
+// ownerAdminJobTimeout is the timeout of sending an admin job to the owner.
+const ownerAdminJobTimeout = 10 * time.Second
+
 var (
 	opts       []string
 	startTs    uint64
@@ -47,6 +62,10 @@ var (
 	cliPdAddr  string
 	noConfirm  bool
 
+	ddlChangefeedID string
+	ddlTs           uint64
+	ddlQuery        string
+
 	cdcEtcdCli kv.CDCEtcdClient
 	pdCli      pd.Client
 
@@ -58,8 +77,14 @@ func newCliCommand() *cobra.Command {
 		Use:   "cli",
 		Short: "Manage replication task and TiCDC cluster",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			credential := getCredential()
+			tlsConfig, err := credential.ToTLSConfig()
+			if err != nil {
+				return err
+			}
 			etcdCli, err := clientv3.New(clientv3.Config{
 				Endpoints:   []string{cliPdAddr},
+				TLS:         tlsConfig,
 				DialTimeout: 5 * time.Second,
 				DialOptions: []grpc.DialOption{
 					grpc.WithConnectParams(grpc.ConnectParams{
@@ -77,7 +102,7 @@ func newCliCommand() *cobra.Command {
 				return err
 			}
 			cdcEtcdCli = kv.NewCDCEtcdClient(etcdCli)
-			pdCli, err = pd.NewClient([]string{cliPdAddr}, pd.SecurityOption{})
+			pdCli, err = pd.NewClient([]string{cliPdAddr}, credential.PDSecurityOption())
 			if err != nil {
 				return err
 			}
@@ -122,11 +147,154 @@ func newChangefeedCommand() *cobra.Command {
 		newListChangefeedCommand(),
 		newQueryChangefeedCommand(),
 		newCreateChangefeedCommand(),
+		newDDLChangefeedCommand(),
 		// TODO: add stop, resume, delete changefeed
 	)
 	return command
 }
 
+func newDDLChangefeedCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "ddl",
+		Short: "Skip or replace a DDL of a stopped changefeed",
+	}
+	command.AddCommand(
+		newSkipDDLCommand(),
+		newReplaceDDLCommand(),
+	)
+	return command
+}
+
+func newSkipDDLCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "skip",
+		Short: "Skip the DDL finished at the ts, the changefeed is resumed if it's stopped by the DDL",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			override := &model.DDLOverride{Ts: ddlTs, Skip: true}
+			return runOverrideDDL(cmd, override)
+		},
+	}
+	command.PersistentFlags().StringVar(&ddlChangefeedID, "changefeed-id", "", "Replication task (changefeed) ID")
+	command.PersistentFlags().Uint64Var(&ddlTs, "ts", 0, "Finished ts of the DDL")
+	return command
+}
+
+func newReplaceDDLCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "replace",
+		Short: "Execute the query instead of the DDL finished at the ts, the changefeed is resumed if it's stopped by the DDL",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(strings.TrimSpace(ddlQuery)) == 0 {
+				return errors.New("the query is empty, use `ddl skip` to skip the DDL")
+			}
+			override := &model.DDLOverride{Ts: ddlTs, Query: ddlQuery}
+			return runOverrideDDL(cmd, override)
+		},
+	}
+	command.PersistentFlags().StringVar(&ddlChangefeedID, "changefeed-id", "", "Replication task (changefeed) ID")
+	command.PersistentFlags().Uint64Var(&ddlTs, "ts", 0, "Finished ts of the DDL")
+	command.PersistentFlags().StringVar(&ddlQuery, "query", "", "Query executed instead of the DDL")
+	return command
+}
+
+func runOverrideDDL(cmd *cobra.Command, override *model.DDLOverride) error {
+	if len(ddlChangefeedID) == 0 || override.Ts == 0 {
+		return errors.New("both the changefeed id and the ts of the DDL must be specified")
+	}
+	ctx := context.Background()
+	resumed, err := checkDDLOverride(ctx, cdcEtcdCli, ddlChangefeedID, override)
+	if err != nil {
+		return err
+	}
+	addr, err := getOwnerAddr(ctx, cdcEtcdCli)
+	if err != nil {
+		return err
+	}
+	form := url.Values{}
+	form.Set("admin-job", strconv.Itoa(int(model.AdminOverrideDDL)))
+	form.Set("cf-id", ddlChangefeedID)
+	form.Set("ddl-ts", strconv.FormatUint(override.Ts, 10))
+	form.Set("ddl-skip", strconv.FormatBool(override.Skip))
+	form.Set("ddl-query", override.Query)
+	if err := sendOwnerAdminJob(ctx, addr, form); err != nil {
+		return err
+	}
+	if resumed {
+		cmd.Printf("the DDL is overridden and changefeed %s is resumed\n", ddlChangefeedID)
+	} else {
+		cmd.Printf("the DDL is overridden, it takes effect after changefeed %s is resumed\n", ddlChangefeedID)
+	}
+	return nil
+}
+
+// checkDDLOverride checks the override against the changefeed, which must be stopped, before the override is
+// sent to the owner, it returns whether the changefeed will be resumed since it's stopped by the failure of the DDL.
+func checkDDLOverride(ctx context.Context, cli kv.CDCEtcdClient, changefeedID string, override *model.DDLOverride) (resumed bool, err error) {
+	info, err := cli.GetChangeFeedInfo(ctx, changefeedID)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	status, err := cli.GetChangeFeedStatus(ctx, changefeedID)
+	if err != nil && errors.Cause(err) != model.ErrChangeFeedNotExists {
+		return false, errors.Trace(err)
+	}
+	resumed, err = info.OverrideDDL(status, override)
+	return resumed, errors.Annotatef(err, "changefeed %s", changefeedID)
+}
+
+// getOwnerAddr returns the status server address of the owner capture.
+func getOwnerAddr(ctx context.Context, cli kv.CDCEtcdClient) (string, error) {
+	ownerID, err := roles.GetOwnerID(ctx, cli, kv.CaptureOwnerKey)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	_, captures, err := cli.GetCaptures(ctx)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	for _, capture := range captures {
+		if capture.ID != ownerID {
+			continue
+		}
+		if len(capture.AdvertiseAddr) == 0 {
+			return "", errors.Errorf("the address of owner %s is unknown", ownerID)
+		}
+		return capture.AdvertiseAddr, nil
+	}
+	return "", errors.Errorf("owner %s not found", ownerID)
+}
+
+// sendOwnerAdminJob sends the admin job to the status server of the owner.
+func sendOwnerAdminJob(ctx context.Context, addr string, form url.Values) error {
+	tlsConfig, err := getCredential().ToTLSConfig()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	client := &http.Client{
+		Timeout:   ownerAdminJobTimeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s://%s/capture/owner/admin", scheme, addr), strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Annotatef(err, "send admin job to owner %s", addr)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("owner %s rejects the admin job: %s", addr, msg)
+	}
+	return nil
+}
+
 func newProcessorCommand() *cobra.Command {
 	command := &cobra.Command{
 		Use:   "processor",
@@ -176,7 +344,7 @@ func newCreateChangefeedCommand() *cobra.Command {
 				}
 				startTs = oracle.ComposeTS(ts, logical)
 			}
-			if err := verifyStartTs(ctx, startTs, cdcEtcdCli); err != nil {
+			if err := verifyStartTs(ctx, id, startTs, kv.NewGCSafePointManager(cdcEtcdCli)); err != nil {
 				return err
 			}
 
@@ -186,6 +354,11 @@ func newCreateChangefeedCommand() *cobra.Command {
 					return err
 				}
 			}
+			switch cfg.SortEngine {
+			case "", puller.SortInMemory, puller.SortInFile:
+			default:
+				return errors.Errorf("unknown sort engine %s", cfg.SortEngine)
+			}
 
 			info := &model.ChangeFeedInfo{
 				SinkURI:    sinkURI,
@@ -200,7 +373,11 @@ func newCreateChangefeedCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			if len(ineligibleTables) != 0 {
+			if len(ineligibleTables) != 0 && cfg.ForceReplicate {
+				cmd.Printf("[WARN] the tables without a primary key or unique index are replicated by all the columns, "+
+					"one of the duplicate rows is deleted or updated when a row changes, and the old values of the rows "+
+					"are read from TiKV if they're not cached, %#v\n", ineligibleTables)
+			} else if len(ineligibleTables) != 0 {
 				cmd.Printf("[WARN] some tables are not eligible to replicate, %#v\n", ineligibleTables)
 				if !noConfirm {
 					cmd.Printf("Could you agree to ignore those tables, and continue to replicate [Y/N]\n")
@@ -250,26 +427,24 @@ func newCreateChangefeedCommand() *cobra.Command {
 	return command
 }
 
-func verifyStartTs(ctx context.Context, startTs uint64, cli kv.CDCEtcdClient) error {
-	resp, err := cli.Client.Get(ctx, tikv.GcSavedSafePoint)
-	if err != nil {
-		return errors.Trace(err)
-	}
-	if resp.Count == 0 {
-		return nil
-	}
-	safePoint, err := strconv.ParseUint(string(resp.Kvs[0].Value), 10, 64)
+// verifyStartTs checks that startTs is not earlier than the GC safepoint, and
+// registers a temporary service safepoint for the new changefeed, so that the
+// data won't be garbage collected before the owner takes over the changefeed.
+// The owner removes it after the changefeed is protected by the service safepoint of CDC.
+func verifyStartTs(ctx context.Context, changefeedID string, startTs uint64, gcManager kv.GCSafePointManager) error {
+	safePoint, err := gcManager.GetGCSafePoint(ctx)
 	if err != nil {
 		return errors.Trace(err)
 	}
 	if startTs < safePoint {
-		return errors.Errorf("startTs %d less than gcSafePoint %d", startTs, safePoint)
+		return errors.Annotatef(model.ErrStartTsBeforeGC, "start-ts %d, gc safepoint %d", startTs, safePoint)
 	}
-	return nil
+	err = gcManager.UpdateServiceSafePoint(ctx, kv.CreatingServiceSafePointID(changefeedID), changefeedCreatingGCTTL, startTs)
+	return errors.Trace(err)
 }
 
 func verifyTables(ctx context.Context, cfg *util.ReplicaConfig) (ineligibleTables []entry.TableName, err error) {
-	kvStore, err := kv.CreateTiStore(cliPdAddr)
+	kvStore, err := kv.CreateTiStore(cliPdAddr, getCredential())
 	if err != nil {
 		return nil, err
 	}
@@ -14,13 +14,26 @@
 package cmd
 
 import (
+	"context"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/etcd"
 	"github.com/pingcap/ticdc/pkg/util"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/clientv3/concurrency"
 
 	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	bf "github.com/pingcap/tidb-tools/pkg/binlog-filter"
 	"github.com/pingcap/tidb-tools/pkg/filter"
 )
 
@@ -46,6 +59,12 @@ tbl-name = "user"
 [[filter-rules.do-tables]]
 db-name = "sns"
 tbl-name = "following"
+
+[[ddl-filter-rules]]
+schema-pattern = "sns"
+events = ["drop table", "truncate table"]
+sql-pattern = ["^DROP\\s+INDEX"]
+action = "Ignore"
 `
 	err := ioutil.WriteFile(path, []byte(content), 0644)
 	c.Assert(err, check.IsNil)
@@ -60,6 +79,11 @@ tbl-name = "following"
 		{Schema: "sns", Name: "user"},
 		{Schema: "sns", Name: "following"},
 	})
+	c.Assert(cfg.DDLFilterRules, check.HasLen, 1)
+	c.Assert(cfg.DDLFilterRules[0].SchemaPattern, check.Equals, "sns")
+	c.Assert(cfg.DDLFilterRules[0].Events, check.DeepEquals, []bf.EventType{bf.DropTable, bf.TruncateTable})
+	c.Assert(cfg.DDLFilterRules[0].SQLPattern, check.DeepEquals, []string{"^DROP\\s+INDEX"})
+	c.Assert(cfg.DDLFilterRules[0].Action, check.Equals, bf.Ignore)
 }
 
 func (s *decodeFileSuite) TestShouldReturnErrForUnknownCfgs(c *check.C) {
@@ -74,3 +98,104 @@ func (s *decodeFileSuite) TestShouldReturnErrForUnknownCfgs(c *check.C) {
 	c.Assert(err, check.NotNil)
 	c.Assert(err, check.ErrorMatches, ".*unknown config.*")
 }
+
+type verifyStartTsSuite struct{}
+
+var _ = check.Suite(&verifyStartTsSuite{})
+
+func (s *verifyStartTsSuite) TestVerifyStartTs(c *check.C) {
+	ctx := context.Background()
+	gcManager := kv.NewMockGCSafePointManager(100)
+
+	err := verifyStartTs(ctx, "cf1", 99, gcManager)
+	c.Assert(errors.Cause(err), check.Equals, model.ErrStartTsBeforeGC)
+	_, _, ok := gcManager.ServiceSafePoint(kv.CreatingServiceSafePointID("cf1"))
+	c.Assert(ok, check.IsFalse)
+
+	err = verifyStartTs(ctx, "cf2", 100, gcManager)
+	c.Assert(err, check.IsNil)
+	safePoint, ttl, ok := gcManager.ServiceSafePoint(kv.CreatingServiceSafePointID("cf2"))
+	c.Assert(ok, check.IsTrue)
+	c.Assert(safePoint, check.Equals, uint64(100))
+	c.Assert(ttl, check.Equals, int64(changefeedCreatingGCTTL))
+}
+
+type overrideDDLSuite struct{}
+
+var _ = check.Suite(&overrideDDLSuite{})
+
+func (s *overrideDDLSuite) TestOverrideDDL(c *check.C) {
+	ctx := context.Background()
+	clientURL, e, err := etcd.SetupEmbedEtcd(c.MkDir())
+	c.Assert(err, check.IsNil)
+	defer e.Close()
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{clientURL.String()},
+		DialTimeout: 3 * time.Second,
+	})
+	c.Assert(err, check.IsNil)
+	defer client.Close()
+	cli := kv.NewCDCEtcdClient(client)
+
+	info := &model.ChangeFeedInfo{
+		SinkURI:     "blackhole://",
+		State:       model.StateFailed,
+		Error:       "exec DDL failed",
+		FailedDDLTs: 100,
+	}
+	c.Assert(cli.SaveChangeFeedInfo(ctx, info, "cf"), check.IsNil)
+	status := &model.ChangeFeedStatus{CheckpointTs: 100, ResolvedTs: 100}
+	c.Assert(cli.PutChangeFeedStatus(ctx, "cf", status), check.IsNil)
+
+	// the changefeed isn't stopped
+	_, err = checkDDLOverride(ctx, cli, "cf", &model.DDLOverride{Ts: 100, Skip: true})
+	c.Assert(err, check.ErrorMatches, ".*is not stopped.*")
+
+	status.AdminJobType = model.AdminStop
+	c.Assert(cli.PutChangeFeedStatus(ctx, "cf", status), check.IsNil)
+	_, err = checkDDLOverride(ctx, cli, "cf", &model.DDLOverride{Ts: 99, Skip: true})
+	c.Assert(err, check.ErrorMatches, ".*is executed.*")
+
+	// the override of a later DDL doesn't resume the changefeed
+	resumed, err := checkDDLOverride(ctx, cli, "cf", &model.DDLOverride{Ts: 200, Query: "create table t (id int primary key)"})
+	c.Assert(err, check.IsNil)
+	c.Assert(resumed, check.IsFalse)
+	resumed, err = checkDDLOverride(ctx, cli, "cf", &model.DDLOverride{Ts: 100, Query: "alter table t add column c int"})
+	c.Assert(err, check.IsNil)
+	c.Assert(resumed, check.IsTrue)
+
+	// the changefeed is overridden by the owner
+	info, err = cli.GetChangeFeedInfo(ctx, "cf")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.DDLOverrides, check.HasLen, 0)
+	c.Assert(info.GetState(), check.Equals, model.StateFailed)
+
+	_, err = getOwnerAddr(ctx, cli)
+	c.Assert(errors.Cause(err), check.Equals, concurrency.ErrElectionNoLeader)
+	_, err = client.Put(ctx, kv.CaptureOwnerKey+"/1", "capture-1")
+	c.Assert(err, check.IsNil)
+	_, err = getOwnerAddr(ctx, cli)
+	c.Assert(err, check.ErrorMatches, ".*owner capture-1 not found.*")
+
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		c.Assert(req.URL.Path, check.Equals, "/capture/owner/admin")
+		c.Assert(req.ParseForm(), check.IsNil)
+		form = req.PostForm
+		if form.Get("cf-id") != "cf" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("changefeed not found"))
+		}
+	}))
+	defer server.Close()
+	capture := &model.CaptureInfo{ID: "capture-1", AdvertiseAddr: strings.TrimPrefix(server.URL, "http://")}
+	c.Assert(cli.PutCaptureInfo(ctx, capture, clientv3.NoLease), check.IsNil)
+	addr, err := getOwnerAddr(ctx, cli)
+	c.Assert(err, check.IsNil)
+	c.Assert(addr, check.Equals, capture.AdvertiseAddr)
+
+	c.Assert(sendOwnerAdminJob(ctx, addr, url.Values{"cf-id": {"cf"}, "ddl-ts": {"100"}}), check.IsNil)
+	c.Assert(form.Get("ddl-ts"), check.Equals, "100")
+	err = sendOwnerAdminJob(ctx, addr, url.Values{"cf-id": {"cf2"}})
+	c.Assert(err, check.ErrorMatches, ".*rejects the admin job: changefeed not found.*")
+}
@@ -32,15 +32,17 @@ var (
 	captureID    *string
 )
 
-// cf holds changefeed id, which is used for output only
+// cf holds changefeed id and state, which is used for output only
 type cf struct {
-	ID string `json:"id"`
+	ID    string          `json:"id"`
+	State model.FeedState `json:"state"`
 }
 
 // capture holds capture information
 type capture struct {
-	ID      string `json:"id"`
-	IsOwner bool   `json:"is-owner"`
+	ID      string            `json:"id"`
+	IsOwner bool              `json:"is-owner"`
+	Labels  map[string]string `json:"labels,omitempty"`
 }
 
 // cfMeta holds changefeed info and changefeed status
@@ -79,7 +81,7 @@ func newListCaptureCommand() *cobra.Command {
 			captures := make([]*capture, 0, len(raw))
 			for _, c := range raw {
 				isOwner := c.ID == ownerID
-				captures = append(captures, &capture{ID: c.ID, IsOwner: isOwner})
+				captures = append(captures, &capture{ID: c.ID, IsOwner: isOwner, Labels: c.Labels})
 			}
 			return jsonPrint(cmd, captures)
 		},
@@ -97,8 +99,12 @@ func newListChangefeedCommand() *cobra.Command {
 				return err
 			}
 			cfs := make([]*cf, 0, len(raw))
-			for id := range raw {
-				cfs = append(cfs, &cf{ID: id})
+			for id, rawKv := range raw {
+				info := &model.ChangeFeedInfo{}
+				if err := info.Unmarshal(rawKv.Value); err != nil {
+					return err
+				}
+				cfs = append(cfs, &cf{ID: id, State: info.GetState()})
 			}
 			return jsonPrint(cmd, cfs)
 		},
@@ -0,0 +1,105 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	replayFile       string
+	replaySinkURI    string
+	replayConfigFile string
+)
+
+func init() {
+	rootCmd.AddCommand(newDebugCommand())
+}
+
+func newDebugCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "debug",
+		Short: "Debug tools of TiCDC",
+	}
+	command.AddCommand(newReplayCommand())
+	return command
+}
+
+func newReplayCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "replay",
+		Short: "Replay a recording of the region feed events through the mounter and a sink",
+		Long: `Replay the region feed events recorded by a processor, which is enabled by the recorder
+section of the changefeed config. The events are sorted and mounted with the recorded schema
+snapshot, and the rows are emitted to the sink, so the bugs of mounting and the sink can be
+reproduced offline.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(replayFile) == 0 {
+				return errors.New("the recording file is required")
+			}
+			cfg := new(util.ReplicaConfig)
+			if len(replayConfigFile) > 0 {
+				if err := strictDecodeFile(replayConfigFile, "cdc", cfg); err != nil {
+					return err
+				}
+			}
+			filter, err := util.NewFilter(cfg)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			s, err := sink.NewSink(replaySinkURI, filter, map[string]string{
+				sink.OptChangefeedID: "replay",
+				sink.OptCaptureID:    "replay",
+			})
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			sc := make(chan os.Signal, 1)
+			signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+			defer signal.Stop(sc)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				select {
+				case sig := <-sc:
+					log.Info("got signal to exit", zap.Stringer("signal", sig))
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+
+			if err := cdc.Replay(ctx, replayFile, s); err != nil {
+				return errors.Annotate(err, "replay")
+			}
+			cmd.Printf("replay %s finished\n", replayFile)
+			return nil
+		},
+	}
+	command.Flags().StringVar(&replayFile, "file", "", "Path of the recording file")
+	command.Flags().StringVar(&replaySinkURI, "sink-uri", "blackhole://", "Sink uri the rows are emitted to")
+	command.Flags().StringVar(&replayConfigFile, "config", "", "Path of the changefeed configuration file, whose filter is used by the sink")
+	return command
+}
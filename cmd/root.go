@@ -3,9 +3,11 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -14,6 +16,12 @@ import (
 var (
 	logFile  string
 	logLevel string
+
+	// the TLS flags shared by the server and the cli commands
+	caPath        string
+	certPath      string
+	keyPath       string
+	allowedCertCN string
 )
 
 var rootCmd = &cobra.Command{
@@ -49,6 +57,20 @@ func initLog() error {
 	return nil
 }
 
+// getCredential returns the TLS credential from the flags
+func getCredential() *security.Credential {
+	var certAllowedCN []string
+	if len(allowedCertCN) != 0 {
+		certAllowedCN = strings.Split(allowedCertCN, ",")
+	}
+	return &security.Credential{
+		CAPath:        caPath,
+		CertPath:      certPath,
+		KeyPath:       keyPath,
+		CertAllowedCN: certAllowedCN,
+	}
+}
+
 // Execute runs the root command
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
@@ -2,15 +2,18 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc"
+	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -19,6 +22,18 @@ import (
 var (
 	serverPdAddr string
 	statusAddr   string
+	serverLabels string
+
+	regionStallDuration  time.Duration
+	regionStallAction    string
+	resolveLockThreshold time.Duration
+	resolveLockRate      float64
+
+	maxInitializingRegions int
+	regionScanRate         float64
+	regionInitTimeout      time.Duration
+
+	mounterWorkerNum int
 
 	serverCmd = &cobra.Command{
 		Use:              "server",
@@ -33,6 +48,20 @@ func init() {
 
 	serverCmd.Flags().StringVar(&serverPdAddr, "pd", "http://127.0.0.1:2379", "PD address, separated by comma")
 	serverCmd.Flags().StringVar(&statusAddr, "status-addr", "127.0.0.1:8300", "Bind address for http status server")
+	serverCmd.Flags().StringVar(&serverLabels, "labels", "", "Labels of the capture in the format of key=value, separated by comma, e.g. zone=az1,host=h1")
+	serverCmd.Flags().DurationVar(&regionStallDuration, "region-stall-duration", 3*time.Minute, "Duration after which a region whose resolved ts doesn't advance is considered stalled, 0 disables the check")
+	serverCmd.Flags().StringVar(&regionStallAction, "region-stall-action", kv.RegionStallActionNone,
+		fmt.Sprintf("Action taken on the stalled regions, one of %s, %s and %s", kv.RegionStallActionNone, kv.RegionStallActionReconnect, kv.RegionStallActionResolveLock))
+	serverCmd.Flags().DurationVar(&resolveLockThreshold, "resolve-lock-threshold", time.Minute, "Minimum age of the locks resolved in the stalled regions")
+	serverCmd.Flags().Float64Var(&resolveLockRate, "resolve-lock-rate", 1, "Max number of the stalled regions whose locks are resolved per second")
+	serverCmd.Flags().IntVar(&maxInitializingRegions, "max-initializing-regions-per-store", 64, "Max number of regions in incremental scan of each store, 0 means unlimited")
+	serverCmd.Flags().StringVar(&caPath, "ca", "", "CA certificate path for TLS connection")
+	serverCmd.Flags().StringVar(&certPath, "cert", "", "Certificate path for TLS connection")
+	serverCmd.Flags().StringVar(&keyPath, "key", "", "Private key path for TLS connection")
+	serverCmd.Flags().StringVar(&allowedCertCN, "cert-allowed-cn", "", "Verify the common name of the client certificates of the status server, separated by comma")
+	serverCmd.Flags().IntVar(&mounterWorkerNum, "mounter-worker-num", 16, "Number of the workers decoding the rows of the tables on the capture, 0 means every table decodes its rows in one goroutine")
+	serverCmd.Flags().Float64Var(&regionScanRate, "region-scan-rate", 0, "Max number of regions starting the incremental scan per second, 0 means unlimited")
+	serverCmd.Flags().DurationVar(&regionInitTimeout, "region-init-timeout", time.Minute, "Duration after which a subscribed region without any event is resubscribed, 0 means disabled")
 }
 
 func preRunLogInfo(cmd *cobra.Command, args []string) {
@@ -49,8 +78,29 @@ func runEServer(cmd *cobra.Command, args []string) error {
 		return errors.Annotatef(err, "invalid status address: %s", statusAddr)
 	}
 
+	labels, err := util.ParseLabels(serverLabels)
+	if err != nil {
+		return errors.Annotate(err, "invalid capture labels")
+	}
+
+	kvCfg := kv.NewClientConfig()
+	kvCfg.RegionStallDuration = regionStallDuration
+	kvCfg.RegionStallAction = regionStallAction
+	kvCfg.ResolveLockThreshold = resolveLockThreshold
+	kvCfg.ResolveLockRate = resolveLockRate
+	kvCfg.MaxInitializingRegionsPerStore = maxInitializingRegions
+	kvCfg.RegionScanRate = regionScanRate
+	kvCfg.RegionInitTimeout = regionInitTimeout
+	if err := kvCfg.Validate(); err != nil {
+		return errors.Annotate(err, "invalid kv client config")
+	}
+
+	if mounterWorkerNum < 0 {
+		return errors.Errorf("invalid mounter worker number: %d", mounterWorkerNum)
+	}
+
 	var opts []cdc.ServerOption
-	opts = append(opts, cdc.PDEndpoints(serverPdAddr), cdc.StatusHost(addrs[0]), cdc.StatusPort(int(statusPort)))
+	opts = append(opts, cdc.PDEndpoints(serverPdAddr), cdc.StatusHost(addrs[0]), cdc.StatusPort(int(statusPort)), cdc.CaptureLabels(labels), cdc.KVClientConfig(kvCfg), cdc.MounterWorkerNum(mounterWorkerNum), cdc.Credential(getCredential()))
 
 	server, err := cdc.NewServer(opts...)
 	if err != nil {
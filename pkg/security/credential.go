@@ -0,0 +1,151 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/pingcap/errors"
+	pd "github.com/pingcap/pd/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Credential holds the certificates used by the connections to PD, TiKV, etcd
+// and the HTTP status server. TLS is disabled if CAPath is empty.
+type Credential struct {
+	CAPath   string `toml:"ca-path" json:"ca-path"`
+	CertPath string `toml:"cert-path" json:"cert-path"`
+	KeyPath  string `toml:"key-path" json:"key-path"`
+	// CertAllowedCN is the common names allowed in the client certificates
+	// of the status server, all the names are allowed if it's empty
+	CertAllowedCN []string `toml:"cert-allowed-cn" json:"cert-allowed-cn"`
+}
+
+// IsTLSEnabled returns whether TLS is enabled, a nil Credential disables TLS.
+func (s *Credential) IsTLSEnabled() bool {
+	return s != nil && len(s.CAPath) != 0
+}
+
+// PDSecurityOption creates the security option of the PD client.
+func (s *Credential) PDSecurityOption() pd.SecurityOption {
+	if !s.IsTLSEnabled() {
+		return pd.SecurityOption{}
+	}
+	return pd.SecurityOption{
+		CAPath:   s.CAPath,
+		CertPath: s.CertPath,
+		KeyPath:  s.KeyPath,
+	}
+}
+
+// ToGRPCDialOption creates the transport credential dial option of gRPC,
+// the connection is insecure if TLS is disabled.
+func (s *Credential) ToGRPCDialOption() (grpc.DialOption, error) {
+	tlsCfg, err := s.ToTLSConfig()
+	if err != nil || tlsCfg == nil {
+		return grpc.WithInsecure(), errors.Trace(err)
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)), nil
+}
+
+// ToTLSConfig creates the client side TLS config, it returns nil if TLS is disabled.
+func (s *Credential) ToTLSConfig() (*tls.Config, error) {
+	if !s.IsTLSEnabled() {
+		return nil, nil
+	}
+	certPool, err := s.loadCA()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	certificates, err := s.loadCertificates()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &tls.Config{
+		Certificates: certificates,
+		RootCAs:      certPool,
+	}, nil
+}
+
+// ToTLSConfigWithVerify creates the server side TLS config which requires the client
+// certificates signed by the CA and checks their common names against CertAllowedCN,
+// it returns nil if TLS is disabled.
+func (s *Credential) ToTLSConfigWithVerify() (*tls.Config, error) {
+	if !s.IsTLSEnabled() {
+		return nil, nil
+	}
+	certPool, err := s.loadCA()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	certificates, err := s.loadCertificates()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(certificates) == 0 {
+		return nil, errors.New("the certificate and the key are required by the server side TLS")
+	}
+	tlsCfg := &tls.Config{
+		Certificates: certificates,
+		ClientCAs:    certPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	if len(s.CertAllowedCN) != 0 {
+		tlsCfg.VerifyPeerCertificate = s.verifyCommonName
+	}
+	return tlsCfg, nil
+}
+
+func (s *Credential) verifyCommonName(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	for _, chain := range verifiedChains {
+		if len(chain) == 0 {
+			continue
+		}
+		for _, cn := range s.CertAllowedCN {
+			if chain[0].Subject.CommonName == cn {
+				return nil
+			}
+		}
+	}
+	return errors.Errorf("client certificate authentication failed, the common name is not in %v", s.CertAllowedCN)
+}
+
+func (s *Credential) loadCA() (*x509.CertPool, error) {
+	ca, err := ioutil.ReadFile(s.CAPath)
+	if err != nil {
+		return nil, errors.Annotate(err, "could not read ca certificate")
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("failed to append ca certs")
+	}
+	return certPool, nil
+}
+
+func (s *Credential) loadCertificates() ([]tls.Certificate, error) {
+	if len(s.CertPath) == 0 && len(s.KeyPath) == 0 {
+		return nil, nil
+	}
+	if len(s.CertPath) == 0 || len(s.KeyPath) == 0 {
+		return nil, errors.New("the certificate and the key should be both set")
+	}
+	certificate, err := tls.LoadX509KeyPair(s.CertPath, s.KeyPath)
+	if err != nil {
+		return nil, errors.Annotate(err, "could not load key pair")
+	}
+	return []tls.Certificate{certificate}, nil
+}
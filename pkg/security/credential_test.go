@@ -0,0 +1,183 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pingcap/check"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type credentialSuite struct{}
+
+var _ = check.Suite(&credentialSuite{})
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// writeCert creates a certificate signed by parent, or a self-signed CA if parent is nil,
+// and writes the certificate and the key to dir.
+func writeCert(c *check.C, dir, name, cn string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, check.IsNil)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer := &testCert{cert: template, key: key}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer = parent
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer.cert, &key.PublicKey, signer.key)
+	c.Assert(err, check.IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, check.IsNil)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, check.IsNil)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, name+".pem"), certPEM, 0644), check.IsNil)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, name+"-key.pem"), keyPEM, 0600), check.IsNil)
+	return &testCert{cert: cert, key: key}
+}
+
+func newCredential(dir, name string) *Credential {
+	return &Credential{
+		CAPath:   filepath.Join(dir, "ca.pem"),
+		CertPath: filepath.Join(dir, name+".pem"),
+		KeyPath:  filepath.Join(dir, name+"-key.pem"),
+	}
+}
+
+// handshake connects the client to the server and returns the error of the server side handshake.
+func handshake(c *check.C, serverCfg, clientCfg *tls.Config) error {
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", serverCfg)
+	c.Assert(err, check.IsNil)
+	defer lis.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			errCh <- err
+			return
+		}
+		defer conn.Close()
+		errCh <- conn.(*tls.Conn).Handshake()
+	}()
+	conn, err := tls.Dial("tcp", lis.Addr().String(), clientCfg)
+	if err == nil {
+		// the client certificate is verified by the server after the client finishes the handshake
+		_, _ = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	return <-errCh
+}
+
+func (s *credentialSuite) TestTLSDisabled(c *check.C) {
+	var nilCredential *Credential
+	for _, credential := range []*Credential{nilCredential, {}} {
+		c.Assert(credential.IsTLSEnabled(), check.IsFalse)
+		c.Assert(credential.PDSecurityOption().CAPath, check.Equals, "")
+		tlsCfg, err := credential.ToTLSConfig()
+		c.Assert(err, check.IsNil)
+		c.Assert(tlsCfg, check.IsNil)
+		tlsCfg, err = credential.ToTLSConfigWithVerify()
+		c.Assert(err, check.IsNil)
+		c.Assert(tlsCfg, check.IsNil)
+		_, err = credential.ToGRPCDialOption()
+		c.Assert(err, check.IsNil)
+	}
+}
+
+func (s *credentialSuite) TestInvalidCredential(c *check.C) {
+	dir := c.MkDir()
+	writeCert(c, dir, "ca", "ca", nil)
+
+	credential := &Credential{CAPath: filepath.Join(dir, "missing.pem")}
+	_, err := credential.ToTLSConfig()
+	c.Assert(err, check.NotNil)
+
+	credential = &Credential{CAPath: filepath.Join(dir, "ca.pem"), CertPath: filepath.Join(dir, "ca.pem")}
+	_, err = credential.ToTLSConfig()
+	c.Assert(err, check.NotNil)
+
+	// the server side requires the certificate
+	credential = &Credential{CAPath: filepath.Join(dir, "ca.pem")}
+	_, err = credential.ToTLSConfig()
+	c.Assert(err, check.IsNil)
+	_, err = credential.ToTLSConfigWithVerify()
+	c.Assert(err, check.NotNil)
+}
+
+func (s *credentialSuite) TestVerifyCommonName(c *check.C) {
+	dir := c.MkDir()
+	ca := writeCert(c, dir, "ca", "ca", nil)
+	writeCert(c, dir, "server", "server", ca)
+	writeCert(c, dir, "client", "client", ca)
+	writeCert(c, dir, "other", "other", ca)
+
+	server := newCredential(dir, "server")
+	server.CertAllowedCN = []string{"client"}
+	serverCfg, err := server.ToTLSConfigWithVerify()
+	c.Assert(err, check.IsNil)
+	c.Assert(server.PDSecurityOption().CertPath, check.Equals, server.CertPath)
+
+	clientCfg, err := newCredential(dir, "client").ToTLSConfig()
+	c.Assert(err, check.IsNil)
+	clientCfg.ServerName = "127.0.0.1"
+	c.Assert(handshake(c, serverCfg, clientCfg), check.IsNil)
+
+	otherCfg, err := newCredential(dir, "other").ToTLSConfig()
+	c.Assert(err, check.IsNil)
+	otherCfg.ServerName = "127.0.0.1"
+	c.Assert(handshake(c, serverCfg, otherCfg), check.ErrorMatches, ".*common name is not in.*")
+
+	// the client without a certificate is rejected
+	noCertCfg, err := (&Credential{CAPath: filepath.Join(dir, "ca.pem")}).ToTLSConfig()
+	c.Assert(err, check.IsNil)
+	noCertCfg.ServerName = "127.0.0.1"
+	c.Assert(handshake(c, serverCfg, noCertCfg), check.NotNil)
+
+	// all the common names are allowed if the list is empty
+	server.CertAllowedCN = nil
+	serverCfg, err = server.ToTLSConfigWithVerify()
+	c.Assert(err, check.IsNil)
+	c.Assert(handshake(c, serverCfg, otherCfg), check.IsNil)
+}
@@ -3,12 +3,16 @@ package util
 import (
 	"strings"
 
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	bf "github.com/pingcap/tidb-tools/pkg/binlog-filter"
 	"github.com/pingcap/tidb-tools/pkg/filter"
 )
 
 // Filter is a event filter implementation
 type Filter struct {
 	filter            *filter.Filter
+	ddlFilter         *bf.BinlogEvent
 	ignoreTxnCommitTs []uint64
 }
 
@@ -17,6 +21,23 @@ type ReplicaConfig struct {
 	FilterCaseSensitive bool          `toml:"filter-case-sensitive" json:"filter-case-sensitive"`
 	FilterRules         *filter.Rules `toml:"filter-rules" json:"filter-rules"`
 	IgnoreTxnCommitTs   []uint64      `toml:"ignore-txn-commit-ts" json:"ignore-txn-commit-ts"`
+	// Placement constrains the captures which the tables are scheduled to
+	Placement *PlacementConfig `toml:"placement" json:"placement,omitempty"`
+	// SortEngine is the engine to sort the events of tables, "memory" or "file"
+	SortEngine string `toml:"sort-engine" json:"sort-engine,omitempty"`
+	// SortDir is the directory of the local files spilled by the "file" sort engine
+	SortDir string `toml:"sort-dir" json:"sort-dir,omitempty"`
+	// Recorder records the region feed events of the selected tables for debugging
+	Recorder *RecorderConfig `toml:"recorder" json:"recorder,omitempty"`
+	// ForceReplicate replicates the tables without a primary key or unique index, all the
+	// columns of the rows are used as their identity, so one of the duplicate rows is changed
+	// downstream when a row changes.
+	ForceReplicate bool `toml:"force-replicate" json:"force-replicate,omitempty"`
+	// SchemaSnapshot persists the schemas of the changefeed to speed up the start of the processors
+	SchemaSnapshot *SchemaSnapshotConfig `toml:"schema-snapshot" json:"schema-snapshot,omitempty"`
+	// DDLFilterRules discard the DDLs by their types or statements, the discarded DDLs are not
+	// executed downstream, but the rows after them are still replicated.
+	DDLFilterRules []*bf.BinlogEventRule `toml:"ddl-filter-rules" json:"ddl-filter-rules,omitempty"`
 }
 
 // NewFilter creates a filter
@@ -25,8 +46,16 @@ func NewFilter(config *ReplicaConfig) (*Filter, error) {
 	if err != nil {
 		return nil, err
 	}
+	var ddlFilter *bf.BinlogEvent
+	if len(config.DDLFilterRules) > 0 {
+		ddlFilter, err = bf.NewBinlogEvent(config.FilterCaseSensitive, config.DDLFilterRules)
+		if err != nil {
+			return nil, errors.Annotate(err, "invalid ddl filter rules")
+		}
+	}
 	return &Filter{
 		filter:            filter,
+		ddlFilter:         ddlFilter,
 		ignoreTxnCommitTs: config.IgnoreTxnCommitTs,
 	}, nil
 }
@@ -58,6 +87,45 @@ func (f *Filter) ShouldIgnoreEvent(ts uint64, schema, table string) bool {
 	return f.shouldIgnoreCommitTs(ts) || f.ShouldIgnoreTable(schema, table)
 }
 
+// ShouldDiscardDDL returns true if the DDL is discarded by the DDL filter rules
+func (f *Filter) ShouldDiscardDDL(tp timodel.ActionType, schema, table, query string) (bool, error) {
+	if f.ddlFilter == nil {
+		return false, nil
+	}
+	action, err := f.ddlFilter.Filter(schema, table, ddlEventType(tp, table), query)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return action == bf.Ignore, nil
+}
+
+// ddlEventType returns the event type of the DDL in the DDL filter rules, the DDLs of the tables
+// without a specific event type are "alter table", and the other DDLs can only be matched by statements.
+func ddlEventType(tp timodel.ActionType, table string) bf.EventType {
+	switch tp {
+	case timodel.ActionCreateSchema:
+		return bf.CreateDatabase
+	case timodel.ActionDropSchema:
+		return bf.DropDatabase
+	case timodel.ActionCreateTable:
+		return bf.CreateTable
+	case timodel.ActionDropTable:
+		return bf.DropTable
+	case timodel.ActionTruncateTable:
+		return bf.TruncateTable
+	case timodel.ActionRenameTable:
+		return bf.RenameTable
+	case timodel.ActionAddIndex, timodel.ActionAddPrimaryKey:
+		return bf.CreateIndex
+	case timodel.ActionDropIndex, timodel.ActionDropPrimaryKey:
+		return bf.DropIndex
+	}
+	if len(table) > 0 {
+		return bf.AlertTable
+	}
+	return bf.NullEvent
+}
+
 // IsSysSchema returns true if the given schema is a system schema
 func IsSysSchema(db string) bool {
 	db = strings.ToUpper(db)
@@ -15,6 +15,8 @@ package util
 
 import (
 	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	bf "github.com/pingcap/tidb-tools/pkg/binlog-filter"
 	"github.com/pingcap/tidb-tools/pkg/filter"
 )
 
@@ -85,3 +87,51 @@ func (s *filterSuite) TestShouldIgnoreTxn(c *check.C) {
 		c.Assert(filter.ShouldIgnoreEvent(tc.ts, tc.schema, tc.table), check.Equals, tc.ignore)
 	}
 }
+
+func (s *filterSuite) TestShouldDiscardDDL(c *check.C) {
+	_, err := NewFilter(&ReplicaConfig{
+		DDLFilterRules: []*bf.BinlogEventRule{{SchemaPattern: "*", Events: []bf.EventType{bf.DropTable}}},
+	})
+	c.Assert(err, check.ErrorMatches, ".*invalid ddl filter rules.*")
+
+	filter, err := NewFilter(&ReplicaConfig{})
+	c.Assert(err, check.IsNil)
+	discard, err := filter.ShouldDiscardDDL(timodel.ActionDropTable, "report", "t", "drop table t")
+	c.Assert(err, check.IsNil)
+	c.Assert(discard, check.IsFalse)
+
+	filter, err = NewFilter(&ReplicaConfig{
+		DDLFilterRules: []*bf.BinlogEventRule{{
+			SchemaPattern: "report*",
+			Events:        []bf.EventType{bf.DropTable, bf.TruncateTable, bf.DropDatabase},
+			Action:        bf.Ignore,
+		}, {
+			SchemaPattern: "sns",
+			TablePattern:  "user",
+			SQLPattern:    []string{"^ALTER\\s+TABLE\\s+`?user`?\\s+DROP\\s+COLUMN"},
+			Action:        bf.Ignore,
+		}},
+	})
+	c.Assert(err, check.IsNil)
+	testCases := []struct {
+		tp      timodel.ActionType
+		schema  string
+		table   string
+		query   string
+		discard bool
+	}{
+		{timodel.ActionDropTable, "report", "t", "DROP TABLE t", true},
+		{timodel.ActionTruncateTable, "report_2020", "t", "TRUNCATE TABLE t", true},
+		{timodel.ActionDropSchema, "report", "", "DROP DATABASE report", true},
+		{timodel.ActionCreateTable, "report", "t", "CREATE TABLE t (id int primary key)", false},
+		{timodel.ActionAddColumn, "report", "t", "ALTER TABLE t ADD COLUMN c int", false},
+		{timodel.ActionDropTable, "sns", "user", "DROP TABLE user", false},
+		{timodel.ActionDropColumn, "sns", "user", "alter table user drop column c", true},
+		{timodel.ActionDropColumn, "sns", "following", "alter table following drop column c", false},
+	}
+	for _, tc := range testCases {
+		discard, err := filter.ShouldDiscardDDL(tc.tp, tc.schema, tc.table, tc.query)
+		c.Assert(err, check.IsNil)
+		c.Assert(discard, check.Equals, tc.discard, check.Commentf("query: %s", tc.query))
+	}
+}
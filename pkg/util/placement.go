@@ -0,0 +1,73 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// PlacementConfig represents the placement constraints of the tables of a changefeed,
+// the constraints are matched with the labels of the captures.
+type PlacementConfig struct {
+	// Required labels must be all matched by a capture to replicate the tables
+	Required map[string]string `toml:"required" json:"required,omitempty"`
+	// Preferred labels are matched as many as possible when selecting a capture
+	Preferred map[string]string `toml:"preferred" json:"preferred,omitempty"`
+}
+
+// Satisfied returns true if the labels match all the required labels
+func (p *PlacementConfig) Satisfied(labels map[string]string) bool {
+	if p == nil {
+		return true
+	}
+	for k, v := range p.Required {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Score returns the number of the preferred labels matched by the labels
+func (p *PlacementConfig) Score(labels map[string]string) int {
+	if p == nil {
+		return 0
+	}
+	score := 0
+	for k, v := range p.Preferred {
+		if lv, ok := labels[k]; ok && lv == v {
+			score++
+		}
+	}
+	return score
+}
+
+// ParseLabels parses labels in the format of `key1=value1,key2=value2`
+func ParseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || len(strings.TrimSpace(kv[0])) == 0 {
+			return nil, errors.Errorf("invalid label %q, should be in the format of key=value", item)
+		}
+		labels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return labels, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"github.com/pingcap/check"
+)
+
+type placementSuite struct{}
+
+var _ = check.Suite(&placementSuite{})
+
+func (s *placementSuite) TestPlacement(c *check.C) {
+	var nilPlacement *PlacementConfig
+	c.Assert(nilPlacement.Satisfied(nil), check.IsTrue)
+	c.Assert(nilPlacement.Score(map[string]string{"zone": "az1"}), check.Equals, 0)
+
+	p := &PlacementConfig{
+		Required:  map[string]string{"zone": "az1"},
+		Preferred: map[string]string{"host": "h1", "disk": "ssd"},
+	}
+	c.Assert(p.Satisfied(map[string]string{"zone": "az1", "host": "h2"}), check.IsTrue)
+	c.Assert(p.Satisfied(map[string]string{"zone": "az2"}), check.IsFalse)
+	c.Assert(p.Satisfied(nil), check.IsFalse)
+	c.Assert(p.Score(map[string]string{"zone": "az1", "host": "h2"}), check.Equals, 0)
+	c.Assert(p.Score(map[string]string{"host": "h1", "disk": "ssd"}), check.Equals, 2)
+}
+
+func (s *placementSuite) TestParseLabels(c *check.C) {
+	labels, err := ParseLabels("")
+	c.Assert(err, check.IsNil)
+	c.Assert(labels, check.HasLen, 0)
+
+	labels, err = ParseLabels("zone=az1, host = h1,rack=")
+	c.Assert(err, check.IsNil)
+	c.Assert(labels, check.DeepEquals, map[string]string{"zone": "az1", "host": "h1", "rack": ""})
+
+	_, err = ParseLabels("zone")
+	c.Assert(err, check.ErrorMatches, ".*invalid label.*")
+	_, err = ParseLabels("=az1")
+	c.Assert(err, check.ErrorMatches, ".*invalid label.*")
+}
@@ -0,0 +1,46 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"strings"
+)
+
+// RecorderConfig represents the tables whose region feed events are recorded
+// to the local files for debugging, the recordings can be replayed by `cdc debug replay`.
+type RecorderConfig struct {
+	// Dir is the directory of the recording files
+	Dir string `toml:"dir" json:"dir"`
+	// Tables are the recorded tables in the format of "schema.table"
+	Tables []string `toml:"tables" json:"tables"`
+}
+
+// IsEnabled returns true if any table is recorded
+func (r *RecorderConfig) IsEnabled() bool {
+	return r != nil && len(r.Dir) != 0 && len(r.Tables) != 0
+}
+
+// ShouldRecord returns true if the table is recorded, the names are case insensitive.
+func (r *RecorderConfig) ShouldRecord(schema, table string) bool {
+	if !r.IsEnabled() {
+		return false
+	}
+	name := schema + "." + table
+	for _, t := range r.Tables {
+		if strings.EqualFold(t, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"github.com/pingcap/check"
+)
+
+type recorderConfigSuite struct{}
+
+var _ = check.Suite(&recorderConfigSuite{})
+
+func (s *recorderConfigSuite) TestShouldRecord(c *check.C) {
+	var nilConfig *RecorderConfig
+	c.Assert(nilConfig.IsEnabled(), check.IsFalse)
+	c.Assert(nilConfig.ShouldRecord("test", "t1"), check.IsFalse)
+
+	cfg := &RecorderConfig{Tables: []string{"test.t1"}}
+	c.Assert(cfg.IsEnabled(), check.IsFalse)
+	cfg.Dir = "/tmp/recording"
+	c.Assert(cfg.IsEnabled(), check.IsTrue)
+	c.Assert(cfg.ShouldRecord("test", "t1"), check.IsTrue)
+	c.Assert(cfg.ShouldRecord("TEST", "T1"), check.IsTrue)
+	c.Assert(cfg.ShouldRecord("test", "t2"), check.IsFalse)
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"time"
+)
+
+// The storages of the schema snapshots
+const (
+	SchemaSnapshotStorageFile = "file"
+	SchemaSnapshotStorageEtcd = "etcd"
+)
+
+const defaultSchemaSnapshotInterval = 10 * time.Minute
+
+// SchemaSnapshotConfig represents how the schemas of a changefeed are persisted, so the processors
+// load the latest snapshot and the newer DDL jobs instead of the whole DDL history at startup.
+type SchemaSnapshotConfig struct {
+	// Storage is where the snapshots are stored, "file" or "etcd", the snapshots are disabled if it's empty.
+	// The size of a value in etcd is limited, so "etcd" fits the changefeeds with few tables.
+	Storage string `toml:"storage" json:"storage"`
+	// Dir is the directory of the snapshots stored in the local files
+	Dir string `toml:"dir" json:"dir"`
+	// Interval is the minimal interval in seconds between two snapshots, it's 10 minutes by default
+	Interval int `toml:"interval" json:"interval"`
+}
+
+// IsEnabled returns true if the schema snapshots are persisted
+func (c *SchemaSnapshotConfig) IsEnabled() bool {
+	return c != nil && len(c.Storage) != 0
+}
+
+// GetInterval returns the minimal interval between two snapshots
+func (c *SchemaSnapshotConfig) GetInterval() time.Duration {
+	if c == nil || c.Interval <= 0 {
+		return defaultSchemaSnapshotInterval
+	}
+	return time.Duration(c.Interval) * time.Second
+}
@@ -39,7 +39,8 @@ func (s Span) Hack() Span {
 	return r
 }
 
-// GetTableSpan returns the span to watch for the specified table
+// GetTableSpan returns the span to watch for the specified table,
+// tableID is the physical table ID, which is the partition ID for the partitioned tables
 func GetTableSpan(tableID int64, needEncode bool) Span {
 	sep := byte('_')
 	tablePrefix := tablecodec.GenTablePrefix(tableID)